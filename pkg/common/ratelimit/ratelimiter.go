@@ -24,9 +24,20 @@ type RateLimiter struct {
 	RetryAfter     int           // Retry after time
 	TimeUntilReset time.Duration // Time until the rate limiter resets
 	UsesRetryAfter bool          // Flag to check if the rate limiter uses a retry after value
+	BlockedUntil   time.Time     // Set by Backoff; Wait blocks until this time passes
 	Log            *log.Logger   // Logger for the rate limiter
 }
 
+// State is a point-in-time snapshot of a RateLimiter's counters
+type State struct {
+	Limit          int       // Total requests allowed in the interval
+	Available      int       // Available requests remaining
+	Requests       int       // Requests made in the current interval
+	ResetTimestamp int64     // Unix time the limit resets
+	RetryAfter     int       // Last Retry-After value reported by the server, in seconds
+	BlockedUntil   time.Time // Time Wait blocks until after a Backoff, or the zero time
+}
+
 // NewRateLimiter creates a new RateLimiter instance with the given parameters
 func NewRateLimiter(args ...interface{}) *RateLimiter {
 	rl := &RateLimiter{
@@ -54,13 +65,20 @@ func NewRateLimiter(args ...interface{}) *RateLimiter {
 // Start begins the rate limiter's internal timer
 func (rl *RateLimiter) Start() {
 	rl.Log.Debug("Starting Rate Limiter")
+	tickerInterval := rl.Interval
+	if tickerInterval == 0 {
+		tickerInterval = 1 * time.Minute
+	}
+
+	// Set the first reset before returning, so it can't overwrite a reset reported by the server in the meantime
+	rl.mu.Lock()
+	if rl.ResetTimestamp == 0 {
+		rl.ResetTimestamp = time.Now().Add(tickerInterval).Unix()
+	}
+	rl.mu.Unlock()
+
 	go func() {
-		tickerInterval := rl.Interval
-		if tickerInterval == 0 {
-			tickerInterval = 1 * time.Minute
-		}
 		ticker := time.NewTicker(tickerInterval)
-		rl.ResetTimestamp = time.Now().Add(tickerInterval).Unix()
 		defer ticker.Stop()
 
 		for {
@@ -80,11 +98,45 @@ func (rl *RateLimiter) Start() {
 	}()
 }
 
+// State returns a snapshot of the rate limiter's current counters
+func (rl *RateLimiter) State() State {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return State{
+		Limit:          rl.Limit,
+		Available:      rl.Available,
+		Requests:       rl.Requests,
+		ResetTimestamp: rl.ResetTimestamp,
+		RetryAfter:     rl.RetryAfter,
+		BlockedUntil:   rl.BlockedUntil,
+	}
+}
+
+// Backoff makes Wait block for at least d, e.g. after the server answers 429 Too Many Requests
+func (rl *RateLimiter) Backoff(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if until := time.Now().Add(d); until.After(rl.BlockedUntil) {
+		rl.BlockedUntil = until
+	}
+	rl.Log.Debug("Rate limiter backing off until ", rl.BlockedUntil)
+}
+
 // Throttle requests based on the remaining available rate limit.
 func (rl *RateLimiter) Wait() {
 	for {
 		rl.mu.Lock()
 
+		// Honor any backoff requested after the server refused a request
+		if blocked := time.Until(rl.BlockedUntil); blocked > 0 {
+			rl.mu.Unlock()
+			rl.performWait(blocked)
+			continue
+		}
+
 		// Calculate the time until the next reset.
 		timeUntilReset := time.Until(time.Unix(rl.ResetTimestamp, 0))
 
@@ -50,7 +50,7 @@ func (c *Client) DownloadFile(url, directory, filename string, allowDuplicates b
 	// Use a HEAD request to fetch headers for filename extraction
 	// https://developer.mozilla.org/en-US/docs/web/http/methods/head
 	req, _ := c.CreateRequest("HEAD", url)
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.HTTPClient().Do(req)
 	if err != nil {
 		return fmt.Errorf("error performing HEAD request: %w", err)
 	}
@@ -106,7 +106,7 @@ func (c *Client) DownloadFile(url, directory, filename string, allowDuplicates b
 		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", bytesReceived))
 	}
 
-	resp, err = c.httpClient.Do(req)
+	resp, err = c.HTTPClient().Do(req)
 	if err != nil {
 		return fmt.Errorf("error performing request: %w", err)
 	}
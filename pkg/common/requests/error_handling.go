@@ -3,11 +3,19 @@ package requests
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 )
 
+// ErrResponseTooLarge is returned when a response body exceeds the client's MaxResponseBytes
+var ErrResponseTooLarge = errors.New("response body exceeds the size limit")
+
+// ErrAcceptedStatus is returned, along with the response and body, for a non-2xx status the caller listed as acceptable
+// (via Client.AcceptedStatus or WithAcceptedStatus), e.g. a 404 from an existence check
+var ErrAcceptedStatus = errors.New("accepted non-success status")
+
 // RequestError represents an API request error.
 type RequestError struct {
 	StatusCode  int    `json:"status_code"`
@@ -15,14 +23,27 @@ type RequestError struct {
 	URL         string `json:"url"`
 	Message     string `json:"message"`
 	RawResponse string `json:"raw_response"`
+	Err         error  `json:"-"` // Structured error returned by the client's ErrorParser, if any
 }
 
+/*
+ * ErrorParser extracts a structured error from an API's error response body
+ * - Register one with NewClient (or Client.ErrorParser) to surface API-specific messages
+ * - Returning nil falls back to the default JSON/plain-text handling
+ */
+type ErrorParser func(status int, body []byte) error
+
 // Error returns a string representation of the RequestError.
 func (e *RequestError) Error() string {
 	return fmt.Sprintf("Request Error: StatusCode=%d, Method=%s, URL=%s, Message=%s",
 		e.StatusCode, e.Method, e.URL, e.Message)
 }
 
+// Unwrap returns the structured error produced by the client's ErrorParser, so it can be matched with errors.As
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
 // handleErrorResponse processes the HTTP error response.
 func (c *Client) handleErrorResponse(resp *http.Response, body []byte) *RequestError {
 	reqError := &RequestError{
@@ -32,8 +53,20 @@ func (c *Client) handleErrorResponse(resp *http.Response, body []byte) *RequestE
 		RawResponse: string(body),
 	}
 
+	if c.ErrorParser != nil {
+		if err := c.ErrorParser(resp.StatusCode, body); err != nil {
+			reqError.Err = err
+			reqError.Message = err.Error()
+		}
+	}
+
 	contentType := resp.Header.Get("Content-Type")
 	switch {
+	case reqError.Err != nil:
+		// Message was set by the ErrorParser
+	case len(body) == 0:
+		// e.g. a HEAD response, which has no body to parse
+		reqError.Message = http.StatusText(resp.StatusCode)
 	case strings.Contains(contentType, JSON):
 		c.parseJSONError(body, reqError)
 	case strings.Contains(contentType, Plain):
@@ -0,0 +1,139 @@
+// pkg/common/requests/pagination.go
+package requests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+ * PageOptions
+ * @param ItemsKey string
+ * @param TokenKey string
+ * @param TokenParam string
+ */
+type PageOptions struct {
+	ItemsKey   string // JSON key holding each page's items, e.g. "items"; empty treats each page as a top-level array (or single object)
+	TokenKey   string // JSON key holding the next page token, e.g. "nextPageToken"; empty follows the `Link: <...>; rel="next"` header
+	TokenParam string // Query parameter the page token is sent back in; defaults to "pageToken"
+}
+
+/*
+ * # PaginatedRequest
+ * - Requests every page of a list endpoint, returning the items of all pages in order
+ * - By default each page is a top-level JSON array (or a single object) and the next page comes from the Link header
+ * - Set ItemsKey and TokenKey for APIs that wrap items, e.g. {"items": [...], "nextPageToken": "..."} as the Sheets/Drive list APIs do
+ */
+func (c *Client) PaginatedRequest(ctx context.Context, method string, url string, query interface{}, data interface{}, opts PageOptions) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	err := c.PaginatedRequestFunc(ctx, method, url, query, data, opts, func(page []json.RawMessage) error {
+		items = append(items, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+/*
+ * # PaginatedRequestFunc
+ * - Requests every page of a list endpoint like PaginatedRequest, but hands each page's items to each as it arrives
+ * - Only one page is held at a time, so huge pulls can be processed (e.g. written to a sheet) page by page
+ * - An error returned by each stops pagination and is returned as is
+ */
+func (c *Client) PaginatedRequestFunc(ctx context.Context, method string, url string, query interface{}, data interface{}, opts PageOptions, each func([]json.RawMessage) error) error {
+	for url != "" {
+		resp, body, err := c.DoRequest(ctx, method, url, query, data)
+		if err != nil {
+			return err
+		}
+
+		page, next, err := parsePage(resp, body, url, opts)
+		if err != nil {
+			return fmt.Errorf("decoding page %s: %w", url, err)
+		}
+		if err := each(page); err != nil {
+			return err
+		}
+
+		// A Link header's next URL already carries the query, so it must not be added again
+		if next != "" && opts.TokenKey == "" {
+			query = nil
+		}
+		url = next
+	}
+	return nil
+}
+
+// parsePage extracts a page's items and the URL of the next page, which is empty on the last page
+func parsePage(resp *http.Response, body []byte, pageURL string, opts PageOptions) ([]json.RawMessage, string, error) {
+	body = bytes.TrimSpace(body)
+
+	var fields map[string]json.RawMessage
+	if opts.ItemsKey != "" || opts.TokenKey != "" {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var items []json.RawMessage
+	switch {
+	case opts.ItemsKey != "":
+		if raw, ok := fields[opts.ItemsKey]; ok && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, "", fmt.Errorf("%s: %w", opts.ItemsKey, err)
+			}
+		}
+	case len(body) > 0 && body[0] == '[':
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, "", err
+		}
+	case len(body) > 0:
+		items = append(items, json.RawMessage(body))
+	}
+
+	if opts.TokenKey == "" {
+		return items, nextLink(resp.Header), nil
+	}
+
+	var token string
+	if raw, ok := fields[opts.TokenKey]; ok {
+		if err := json.Unmarshal(raw, &token); err != nil {
+			return nil, "", fmt.Errorf("%s: %w", opts.TokenKey, err)
+		}
+	}
+	if token == "" {
+		return items, "", nil
+	}
+
+	param := opts.TokenParam
+	if param == "" {
+		param = "pageToken"
+	}
+	next, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+	q := next.Query()
+	q.Set(param, token)
+	next.RawQuery = q.Encode()
+	return items, next.String(), nil
+}
+
+// nextLink returns the target of the `rel="next"` entry in a response's Link headers, if any
+func nextLink(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			if strings.Contains(link, `rel="next"`) {
+				return strings.Trim(strings.TrimSpace(strings.Split(link, ";")[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
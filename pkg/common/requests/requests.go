@@ -2,14 +2,23 @@
 package requests
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gemini-oss/rego/pkg/common/cache"
 	"github.com/gemini-oss/rego/pkg/common/config"
@@ -21,6 +30,40 @@ import (
 
 type Headers map[string]string
 
+// MergeHeaders returns a new Headers holding base overlaid with override. Keys are canonicalized with
+// textproto.CanonicalMIMEHeaderKey, so "content-type" and "Content-Type" collapse into one header rather than both being sent.
+func MergeHeaders(base, override Headers) Headers {
+	merged := make(Headers, len(base)+len(override))
+	for _, headers := range []Headers{base, override} {
+		// Within one map, a key already in canonical form wins over its case variants
+		keys := make([]string, 0, len(headers))
+		for key := range headers {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			aCanonical, bCanonical := a == textproto.CanonicalMIMEHeaderKey(a), b == textproto.CanonicalMIMEHeaderKey(b)
+			switch {
+			case aCanonical == bCanonical:
+				return strings.Compare(a, b)
+			case aCanonical:
+				return 1
+			default:
+				return -1
+			}
+		})
+		for _, key := range keys {
+			merged[textproto.CanonicalMIMEHeaderKey(key)] = headers[key]
+		}
+	}
+	return merged
+}
+
+// UserAgent and Accept are NewClient options setting the client's default User-Agent and Accept headers
+type (
+	UserAgent string
+	Accept    string
+)
+
 const (
 	All               = "*/*"                               // RFC-7231 (https://www.rfc-editor.org/rfc/rfc7231.html)
 	Atom              = "application/atom+xml"              // RFC-4287 (https://www.rfc-editor.org/rfc/rfc4287.html)
@@ -36,6 +79,7 @@ const (
 	MP4               = "video/mp4"                         // RFC-4337 (https://www.rfc-editor.org/rfc/rfc4337.html)
 	MPEG              = "video/mpeg"                        // RFC-4337 (https://www.rfc-editor.org/rfc/rfc4337.html)
 	MultipartFormData = "multipart/form-data"               // RFC-7578 (https://www.rfc-editor.org/rfc/rfc7578.html)
+	MultipartRelated  = "multipart/related"                 // RFC-2387 (https://www.rfc-editor.org/rfc/rfc2387.html)
 	OctetStream       = "application/octet-stream"          // RFC-2046 (https://www.rfc-editor.org/rfc/rfc2046.html)
 	PDF               = "application/pdf"                   // RFC-3778 (https://www.rfc-editor.org/rfc/rfc3778.html)
 	PNG               = "image/png"                         // RFC-2083 (https://www.rfc-editor.org/rfc/rfc2083.html)
@@ -49,20 +93,56 @@ const (
 
 var (
 	l = log.NewLogger("{requests}", log.DEBUG)
+
+	// DefaultUserAgent identifies rego, and its module version when built as a dependency, instead of Go's default
+	DefaultUserAgent = "rego/" + moduleVersion()
 )
 
+// moduleVersion returns rego's module version from the build info, or "devel" when it isn't known
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/gemini-oss/rego" && dep.Version != "" {
+			return dep.Version
+		}
+	}
+	if info.Main.Path == "github.com/gemini-oss/rego" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
 /*
  * Client
  * @param httpClient *http.Client
  * @param headers Headers
+ *
+ * A Client may serve concurrent DoRequest calls. Only its headers, body type, and HTTP client may change while
+ * requests are in flight, and only through UpdateContentType, UpdateBodyType, and SetHTTPClient. Set every other
+ * exported field (RateLimiter, DefaultQuery, ErrorParser, MaxResponseBytes, AcceptedStatus, ...) before sharing the
+ * client, or give each caller its own through Clone.
  */
 type Client struct {
-	httpClient  *http.Client
-	BodyType    string
-	Cache       *cache.Cache
-	Headers     Headers
-	Log         *log.Logger
-	RateLimiter *rl.RateLimiter
+	mu               sync.RWMutex // Guards Headers, BodyType and httpClient only; the other fields are fixed once the client is shared
+	httpClient       *http.Client
+	BodyType         string
+	Cache            *cache.Cache
+	Headers          Headers
+	Log              *log.Logger
+	RateLimiter      *rl.RateLimiter
+	ErrorParser      ErrorParser // Extracts a structured error from API-specific error bodies
+	DefaultQuery     url.Values  // Query parameters sent with every request; per-request parameters take precedence
+	UserAgent        string      // Default User-Agent header; Headers and per-request headers take precedence
+	Accept           string      // Default Accept header; Headers and per-request headers take precedence
+	MaxResponseBytes int64       // If positive, responses with larger bodies fail with ErrResponseTooLarge; zero is unlimited
+	AcceptedStatus   []int       // Non-2xx status codes returned with ErrAcceptedStatus instead of a RequestError
+
+	// EncodeByContentType opts in to encoding payloads per the request's Content-Type header when BodyType is empty;
+	// by default, a client without a BodyType sends no body
+	EncodeByContentType bool
 }
 
 /*
@@ -87,33 +167,103 @@ func NewClient(options ...interface{}) *Client {
 		Headers:     Headers{},
 		Log:         l,
 		RateLimiter: nil,
+		UserAgent:   DefaultUserAgent,
+	}
+
+	client.applyOptions(options)
+
+	if client.RateLimiter == nil {
+		client.RateLimiter = rl.NewRateLimiter(100)
 	}
 
+	return client
+}
+
+// applyOptions sets the client fields for each recognized NewClient option
+func (c *Client) applyOptions(options []interface{}) {
 	for _, option := range options {
 		switch opt := option.(type) {
 		case *http.Client:
-			client.httpClient = opt
+			c.httpClient = opt
 		case Headers:
-			client.Headers = opt
+			c.Headers = MergeHeaders(nil, opt)
 		case *rl.RateLimiter:
-			client.RateLimiter = opt
+			c.RateLimiter = opt
+		case ErrorParser:
+			c.ErrorParser = opt
+		case UserAgent:
+			c.UserAgent = string(opt)
+		case Accept:
+			c.Accept = string(opt)
 		}
 	}
+}
 
-	if client.RateLimiter == nil {
-		client.RateLimiter = rl.NewRateLimiter(100)
+/*
+ * # Clone
+ * - Returns a copy of the client whose Headers, DefaultQuery and AcceptedStatus can change without affecting the original
+ * - The HTTP client, cache, and rate limiter are shared, so both clients draw on the same API quota
+ * - Accepts the same options as NewClient, applied to the copy; pass a new *ratelimit.RateLimiter to give it its own quota
+ */
+func (c *Client) Clone(options ...interface{}) *Client {
+	c.mu.RLock()
+	clone := &Client{
+		httpClient:       c.httpClient,
+		BodyType:         c.BodyType,
+		Cache:            c.Cache,
+		Headers:          MergeHeaders(nil, c.Headers),
+		Log:              c.Log,
+		RateLimiter:      c.RateLimiter,
+		ErrorParser:      c.ErrorParser,
+		UserAgent:        c.UserAgent,
+		Accept:           c.Accept,
+		MaxResponseBytes: c.MaxResponseBytes,
+		AcceptedStatus:   slices.Clone(c.AcceptedStatus),
+
+		EncodeByContentType: c.EncodeByContentType,
+	}
+	if c.DefaultQuery != nil {
+		clone.DefaultQuery = make(url.Values, len(c.DefaultQuery))
+		for key, values := range c.DefaultQuery {
+			clone.DefaultQuery[key] = slices.Clone(values)
+		}
 	}
+	c.mu.RUnlock()
 
-	return client
+	clone.applyOptions(options)
+	return clone
 }
 
 // UpdateHeaders changes the headers for the HTTP client
 func (c *Client) UpdateContentType(contentType string) {
-	c.Headers["Content-Type"] = contentType
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Copy on write, so requests already reading the previous headers never see a concurrent map write
+	c.Headers = MergeHeaders(c.Headers, Headers{"Content-Type": contentType})
+}
+
+// HTTPClient returns the underlying *http.Client, e.g. to tune its transport or timeout after construction
+func (c *Client) HTTPClient() *http.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.httpClient
+}
+
+// SetHTTPClient replaces the underlying *http.Client used for subsequent requests; nil restores a default client
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.httpClient = httpClient
 }
 
 // UpdateHeaders changes the payload body for the HTTP client
 func (c *Client) UpdateBodyType(bodyType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.BodyType = bodyType
 }
 
@@ -140,20 +290,94 @@ func DecodeJSON(body []byte, result interface{}) error {
 	return json.Unmarshal(body, result)
 }
 
+/*
+ * DecodeJSONStream
+ * Decodes a top-level JSON array one element at a time, invoking each with the raw element
+ * - Only the current element is held in memory, so large array responses can be processed as they are read
+ * - Iteration stops at the first error returned by each
+ * @param r io.Reader
+ * @param each func(json.RawMessage) error
+ * @return error
+ */
+func DecodeJSONStream(r io.Reader, each func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array, got %v", tok)
+	}
+
+	for dec.More() {
+		var element json.RawMessage
+		if err := dec.Decode(&element); err != nil {
+			return err
+		}
+		if err := each(element); err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing bracket
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (c *Client) CreateRequest(method string, url string) (*http.Request, error) {
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Set headers
-	for key, value := range c.Headers {
+	// Set headers, letting explicit client headers override the User-Agent and Accept defaults
+	c.mu.RLock()
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.Accept != "" {
+		req.Header.Set("Accept", c.Accept)
+	}
+	for key, value := range MergeHeaders(nil, c.Headers) {
 		req.Header.Set(key, value)
 	}
+	c.mu.RUnlock()
 
 	return req, nil
 }
 
+type requestHeadersKey struct{}
+
+// WithRequestHeaders returns a context carrying headers for a single DoRequest call, overriding the client's headers
+func WithRequestHeaders(ctx context.Context, headers Headers) context.Context {
+	return context.WithValue(ctx, requestHeadersKey{}, headers)
+}
+
+type acceptedStatusKey struct{}
+
+// WithAcceptedStatus returns a context under which DoRequest returns the listed status codes with ErrAcceptedStatus
+// instead of a RequestError, in addition to the client's AcceptedStatus
+func WithAcceptedStatus(ctx context.Context, codes ...int) context.Context {
+	return context.WithValue(ctx, acceptedStatusKey{}, codes)
+}
+
+// isAcceptedStatus reports whether status is acceptable for this client or the request carried by ctx
+func (c *Client) isAcceptedStatus(ctx context.Context, status int) bool {
+	codes, _ := ctx.Value(acceptedStatusKey{}).([]int)
+	return slices.Contains(c.AcceptedStatus, status) || slices.Contains(codes, status)
+}
+
+// setRequestHeaders applies any per-request headers carried by ctx
+func setRequestHeaders(req *http.Request, ctx context.Context) {
+	headers, _ := ctx.Value(requestHeadersKey{}).(Headers)
+	for key, value := range MergeHeaders(nil, headers) {
+		req.Header.Set(key, value)
+	}
+}
+
 func SetQueryParams(req *http.Request, query interface{}) {
 	if query == nil {
 		return
@@ -179,6 +403,25 @@ func SetQueryParams(req *http.Request, query interface{}) {
 	req.URL.RawQuery = q.Encode()
 }
 
+// setDefaultQuery adds each default parameter that the request does not already carry
+func setDefaultQuery(req *http.Request, defaults url.Values) {
+	if len(defaults) == 0 {
+		return
+	}
+
+	q := req.URL.Query()
+	for key, values := range defaults {
+		if _, ok := q[key]; ok {
+			continue
+		}
+		for _, value := range values {
+			q.Add(key, value)
+		}
+	}
+
+	req.URL.RawQuery = q.Encode()
+}
+
 func SetJSONPayload(req *http.Request, data interface{}) error {
 	if data == nil {
 		return nil
@@ -193,8 +436,7 @@ func SetJSONPayload(req *http.Request, data interface{}) error {
 		return fmt.Errorf("marshaling request body: %w", err)
 	}
 
-	req.Body = io.NopCloser(strings.NewReader(string(payload)))
-	req.ContentLength = int64(len(payload))
+	setBody(req, payload)
 	return nil
 }
 
@@ -210,23 +452,38 @@ func SetFormURLEncodedPayload(req *http.Request, data interface{}) error {
 	}
 
 	for key, value := range parameters {
-		switch v := value.(type) {
-		case []interface{}:
-			arrayKey := fmt.Sprintf("%s[]", key)
-			for _, item := range v {
-				formData.Add(arrayKey, fmt.Sprintf("%v", item))
-			}
-		default:
-			formData.Add(key, fmt.Sprintf("%v", value))
-		}
+		addFormValue(formData, key, value)
 	}
 
-	req.Body = io.NopCloser(strings.NewReader(formData.Encode()))
+	setBody(req, []byte(formData.Encode()))
 	req.Header.Set("Content-Type", FormURLEncoded)
-	req.ContentLength = int64(len(formData.Encode()))
 	return nil
 }
 
+// addFormValue encodes a value under key using bracketed form conventions:
+// slices repeat `key[]`, nested maps become `key[child]`, and maps inside slices are indexed as `key[i][child]`
+func addFormValue(formData url.Values, key string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		for child, item := range v {
+			addFormValue(formData, fmt.Sprintf("%s[%s]", key, child), item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				addFormValue(formData, fmt.Sprintf("%s[%d]", key, i), item)
+			default:
+				addFormValue(formData, key+"[]", item)
+			}
+		}
+	default:
+		formData.Add(key, fmt.Sprintf("%v", value))
+	}
+}
+
 func SetXMLPayload(req *http.Request, data interface{}) error {
 	if data == nil {
 		return nil
@@ -237,11 +494,57 @@ func SetXMLPayload(req *http.Request, data interface{}) error {
 		return fmt.Errorf("marshaling request body: %w", err)
 	}
 
-	req.Body = io.NopCloser(strings.NewReader(string(payload)))
-	req.ContentLength = int64(len(payload))
+	setBody(req, payload)
+	return nil
+}
+
+// Part is a single body part of a multipart payload
+type Part struct {
+	ContentType string
+	Body        []byte
+}
+
+// Related is a payload sent as multipart/related, in order (e.g. JSON metadata followed by media)
+type Related []Part
+
+func SetMultipartRelatedPayload(req *http.Request, parts Related) error {
+	if len(parts) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, part := range parts {
+		contentType := part.ContentType
+		if contentType == "" {
+			contentType = OctetStream
+		}
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		if err != nil {
+			return fmt.Errorf("creating multipart part: %w", err)
+		}
+		if _, err := pw.Write(part.Body); err != nil {
+			return fmt.Errorf("writing multipart part: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("closing multipart body: %w", err)
+	}
+
+	setBody(req, buf.Bytes())
+	req.Header.Set("Content-Type", fmt.Sprintf("%s; boundary=%s", MultipartRelated, w.Boundary()))
 	return nil
 }
 
+// setBody attaches the payload to the request and sets GetBody, so redirects and retries can obtain a fresh reader
+func setBody(req *http.Request, payload []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.ContentLength = int64(len(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+}
+
 func (c *Client) DoRequest(ctx context.Context, method string, url string, query interface{}, data interface{}) (*http.Response, []byte, error) {
 	realTime := retry.RealTime{}
 	return c.doRetry(ctx, method, url, query, data, realTime)
@@ -257,7 +560,7 @@ func (c *Client) doRetry(ctx context.Context, method string, url string, query i
 			return reqErr
 		},
 		func(err error) bool {
-			return err != nil && (resp == nil || IsRetryableStatusCode(resp.StatusCode))
+			return err != nil && ctx.Err() == nil && !errors.Is(err, ErrAcceptedStatus) && (resp == nil || IsRetryableStatusCode(resp.StatusCode))
 		},
 		time,
 	)
@@ -279,15 +582,37 @@ func (c *Client) do(ctx context.Context, method string, url string, query interf
 	if err != nil {
 		return nil, nil, err
 	}
-	req = req.WithContext(ctx)
+	traceCtx, done := traceTiming(ctx)
+	defer done()
+	req = req.WithContext(traceCtx)
+	setRequestHeaders(req, ctx)
 
 	SetQueryParams(req, query)
+	setDefaultQuery(req, c.DefaultQuery)
 
-	if err := setPayload(req, data, c.BodyType); err != nil {
-		return nil, nil, err
+	// Snapshot per-request settings, so concurrent updates apply to later requests rather than this one midway
+	c.mu.RLock()
+	bodyType, httpClient := c.BodyType, c.httpClient
+	c.mu.RUnlock()
+
+	// Without an explicit body type, opted-in clients encode the payload per the request's Content-Type header
+	if bodyType == "" && c.EncodeByContentType {
+		bodyType = req.Header.Get("Content-Type")
+	}
+
+	// HEAD requests carry no body
+	if method != http.MethodHead {
+		if err := setPayload(req, data, bodyType); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	resp, err := c.httpClient.Do(req)
+	// Without a body, a HEAD or OPTIONS request has no content to describe
+	if (method == http.MethodHead || method == http.MethodOptions) && req.Body == nil {
+		req.Header.Del("Content-Type")
+	}
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, nil, &RequestError{
 			StatusCode: http.StatusInternalServerError,
@@ -298,13 +623,30 @@ func (c *Client) do(ctx context.Context, method string, url string, query interf
 	}
 	defer resp.Body.Close()
 
-	// Update rate limiter if headers are present
+	// A HEAD response has no body to read, whatever its Content-Length says
+	var body []byte
+	if method != http.MethodHead {
+		body, err = readBody(resp.Body, c.MaxResponseBytes)
+	}
+
+	// The request is over once its body is read, so any throttling below is left out of its timing
+	done()
+	if timing, ok := ctx.Value(timingKey{}).(*Timing); ok {
+		c.Log.Debugf("%s %s timing: %v", method, url, timing)
+	}
+
+	// Update rate limiter if headers are present, backing off for as long as the server asks on a 429
 	if c.RateLimiter != nil {
 		c.RateLimiter.UpdateFromHeaders(resp.Header)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			c.RateLimiter.Backoff(retryAfter(resp.Header))
+		}
 		c.RateLimiter.Wait()
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	if errors.Is(err, ErrResponseTooLarge) {
+		return resp, nil, err
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("reading response body: %w", err)
 	}
@@ -312,6 +654,8 @@ func (c *Client) do(ctx context.Context, method string, url string, query interf
 	switch {
 	case resp.StatusCode >= 200 && resp.StatusCode < 300:
 		return resp, body, nil
+	case c.isAcceptedStatus(ctx, resp.StatusCode):
+		return resp, body, fmt.Errorf("%w: %d", ErrAcceptedStatus, resp.StatusCode)
 	case IsRedirectCode(resp.StatusCode):
 		c.Log.Warning("Redirect status code encountered:", resp.StatusCode)
 		return resp, body, c.handleErrorResponse(resp, body)
@@ -330,7 +674,82 @@ func (c *Client) do(ctx context.Context, method string, url string, query interf
 	}
 }
 
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date, returning zero when it is absent
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// readBody reads r in full, failing with ErrResponseTooLarge instead of reading past limit bytes when limit is positive
+func readBody(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+	return body, nil
+}
+
+/*
+ * # Head
+ * - Issues a HEAD request, returning the status code and response headers without a body
+ * - Useful for existence and metadata checks, e.g. a file's Content-Length before downloading it
+ */
+func (c *Client) Head(ctx context.Context, url string, query interface{}) (int, http.Header, error) {
+	resp, _, err := c.DoRequest(ctx, http.MethodHead, url, query, nil)
+	if resp == nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, resp.Header, err
+}
+
+/*
+ * # AllowedMethods
+ * - Issues an OPTIONS request, returning the methods listed in the response's Allow header
+ * - Methods are upper-cased and de-duplicated, in the order the server lists them
+ */
+func (c *Client) AllowedMethods(ctx context.Context, url string) ([]string, error) {
+	resp, _, err := c.DoRequest(ctx, http.MethodOptions, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseAllow(resp.Header), nil
+}
+
+// parseAllow splits the comma-separated Allow header values into a list of methods
+func parseAllow(header http.Header) []string {
+	var methods []string
+	for _, value := range header.Values("Allow") {
+		for _, method := range strings.Split(value, ",") {
+			method = strings.ToUpper(strings.TrimSpace(method))
+			if method != "" && !slices.Contains(methods, method) {
+				methods = append(methods, method)
+			}
+		}
+	}
+	return methods
+}
+
 func setPayload(req *http.Request, data interface{}, bodyType string) error {
+	// Multipart payloads carry their own boundary, so they ignore the client's body type
+	if parts, ok := data.(Related); ok {
+		return SetMultipartRelatedPayload(req, parts)
+	}
+
 	switch bodyType {
 	case FormURLEncoded, fmt.Sprintf("%s; charset=utf-8", FormURLEncoded):
 		return SetFormURLEncodedPayload(req, data)
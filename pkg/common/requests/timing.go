@@ -0,0 +1,81 @@
+// pkg/common/requests/timing.go
+package requests
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+/*
+ * Timing records how long the phases of a request took, each measured from the start of the request
+ * - Phases that did not happen (e.g. DNS and Connect on a reused connection) stay zero
+ * - With retries, it describes the last attempt
+ */
+type Timing struct {
+	mu           sync.Mutex
+	DNS          time.Duration // Name resolution finished
+	Connect      time.Duration // TCP connection established
+	TLSHandshake time.Duration // TLS handshake finished
+	FirstByte    time.Duration // First response byte received (TTFB)
+	Total        time.Duration // Response body fully read
+	ReusedConn   bool          // Whether an idle connection was reused
+}
+
+type timingKey struct{}
+
+// WithTiming returns a context that makes DoRequest record its request timing into the returned Timing.
+// Timing is opt-in per request, so requests without it pay no tracing overhead.
+func WithTiming(ctx context.Context) (context.Context, *Timing) {
+	timing := &Timing{}
+	return context.WithValue(ctx, timingKey{}, timing), timing
+}
+
+// traceTiming returns ctx with an httptrace.ClientTrace recording into the Timing carried by ctx, if any, and a func
+// recording Total; only its first call counts, so it can also be deferred to cover early returns.
+func traceTiming(ctx context.Context) (context.Context, func()) {
+	timing, ok := ctx.Value(timingKey{}).(*Timing)
+	if !ok {
+		return ctx, func() {}
+	}
+
+	start := time.Now()
+	timing.reset()
+	since := func(d *time.Duration) {
+		timing.mu.Lock()
+		*d = time.Since(start)
+		timing.mu.Unlock()
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSDone:              func(httptrace.DNSDoneInfo) { since(&timing.DNS) },
+		ConnectDone:          func(_, _ string, err error) { since(&timing.Connect) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { since(&timing.TLSHandshake) },
+		GotFirstResponseByte: func() { since(&timing.FirstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			timing.mu.Lock()
+			timing.ReusedConn = info.Reused
+			timing.mu.Unlock()
+		},
+	}
+	var once sync.Once
+	return httptrace.WithClientTrace(ctx, trace), func() { once.Do(func() { since(&timing.Total) }) }
+}
+
+// String summarizes the recorded phases, reading them under the Timing's lock
+func (t *Timing) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fmt.Sprintf("dns=%v connect=%v tls=%v ttfb=%v total=%v reused=%v",
+		t.DNS, t.Connect, t.TLSHandshake, t.FirstByte, t.Total, t.ReusedConn)
+}
+
+// reset clears the timing left by a previous attempt
+func (t *Timing) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.DNS, t.Connect, t.TLSHandshake, t.FirstByte, t.Total, t.ReusedConn = 0, 0, 0, 0, 0, false
+}
@@ -0,0 +1,150 @@
+// pkg/common/starstruct/cache.go
+package starstruct
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// flatField is a scalar field of a flat struct type, resolved once per type
+type flatField struct {
+	index int    // Index of the field within the struct
+	key   string // Tag-resolved flattened key, before any prefix
+}
+
+// fieldInfo is the tag and shape metadata of one struct field, resolved once per type
+type fieldInfo struct {
+	index     int          // Index of the field within the struct
+	key       string       // Tag-resolved flattened key, before any prefix
+	name      string       // Name GenerateFieldNames gives the field, "-" when it is ignored
+	kind      reflect.Kind // Kind of the field's type
+	exported  bool         // Whether the field is exported
+	ignored   bool         // Whether the field is tagged "-"
+	inline    bool         // Whether the field's own fields are promoted to the parent
+	omitEmpty bool         // Whether the field is tagged omitempty
+	isTime    bool         // Whether the field is a non-embedded time.Time
+	text      bool         // Whether the field's type is a TextMarshaler
+	nested    bool         // Whether the field is a struct, or a pointer to one, that GenerateFieldNames recurses into
+	mapped    bool         // Whether the field is a map, or a pointer to one, whose keys become field names
+}
+
+// typeInfo is the resolved metadata of a struct type
+type typeInfo struct {
+	fields []fieldInfo
+	flat   []flatField // Fields of a flat struct, one whose exported fields are all scalars; nil for any other struct
+	static bool        // Whether GenerateFieldNames yields the same names for every value of the type
+}
+
+// fieldCacheKey identifies resolved fields: the same type names its fields differently under another tag name
+type fieldCacheKey struct {
+	typ     reflect.Type
+	tagName string
+}
+
+// typeInfoCache maps a fieldCacheKey to the *typeInfo of its struct type
+var typeInfoCache sync.Map
+
+// structInfo returns the resolved metadata of the struct type typ, computing it once per type and tag name unless the
+// cache is disabled.
+func structInfo(typ reflect.Type, cfg *pkgConfig) *typeInfo {
+	if cfg.NoFieldCache {
+		return resolveTypeInfo(typ, cfg)
+	}
+
+	key := fieldCacheKey{typ: typ, tagName: cfg.TagName}
+	if cached, found := typeInfoCache.Load(key); found {
+		return cached.(*typeInfo)
+	}
+
+	info, _ := typeInfoCache.LoadOrStore(key, resolveTypeInfo(typ, cfg))
+	return info.(*typeInfo)
+}
+
+// flatFields returns the resolved fields of typ when it is a flat struct, one whose exported fields are all scalars and so
+// flatten without recursion; ok is false for any other struct type, or when the cache is disabled.
+func flatFields(typ reflect.Type, cfg *pkgConfig) (fields []flatField, ok bool) {
+	if cfg.NoFieldCache {
+		return nil, false
+	}
+	fields = structInfo(typ, cfg).flat
+	return fields, fields != nil
+}
+
+// staticNames reports whether every value of typ, a struct type, generates the same field names under cfg, so a slice of
+// them needs its names generated only once.
+func staticNames(typ reflect.Type, cfg *pkgConfig) bool {
+	if cfg.NoFieldCache || cfg.ExcludeNil || cfg.OmitEmpty {
+		return false
+	}
+	return structInfo(typ, cfg).static
+}
+
+// resolveTypeInfo resolves the metadata of the struct type typ
+func resolveTypeInfo(typ reflect.Type, cfg *pkgConfig) *typeInfo {
+	info := &typeInfo{
+		fields: make([]fieldInfo, 0, typ.NumField()),
+		flat:   make([]flatField, 0, typ.NumField()),
+		static: true,
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := fieldTag(field, cfg)
+		f := fieldInfo{
+			index:     i,
+			key:       getMapKey(field, cfg),
+			name:      getFirstTag(tag),
+			kind:      field.Type.Kind(),
+			exported:  field.IsExported(),
+			inline:    shouldInline(field, cfg),
+			omitEmpty: strings.Contains(tag, ",omitempty"),
+			isTime:    field.Type == reflect.TypeOf(time.Time{}) && !field.Anonymous,
+			text:      isTextMarshaler(field.Type),
+		}
+		f.ignored = f.name == "-"
+		if cfg.TagName != "" && !f.ignored {
+			f.name = f.key
+		}
+
+		elem := field.Type
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		f.nested = elem.Kind() == reflect.Struct
+		f.mapped = elem.Kind() == reflect.Map
+		info.fields = append(info.fields, f)
+
+		if f.exported && !f.ignored && info.flat != nil {
+			if !isScalar(f.kind) || f.text {
+				info.flat = nil
+			} else {
+				info.flat = append(info.flat, flatField{index: i, key: f.key})
+			}
+		}
+
+		// Names stop depending on the type alone once a map's keys or a nil pointer's absence can change them
+		if info.static && !f.isTime && !f.ignored && !isUnsupported(f.kind) && !f.text {
+			switch {
+			case f.mapped, f.nested && f.kind == reflect.Ptr:
+				info.static = false
+			case f.nested:
+				info.static = structInfo(field.Type, cfg).static
+			}
+		}
+	}
+	return info
+}
+
+// isScalar reports whether values of kind k are flattened as a single leaf value
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
@@ -3,12 +3,16 @@ package starstruct
 
 import (
 	"bytes"
+	"context"
+	"encoding"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"golang.org/x/text/cases"
@@ -19,13 +23,51 @@ import (
 // ---------------------------------------------------------------------
 
 type pkgConfig struct {
-	Sort        bool
-	Generate    bool
-	Headers     *[]string
-	ExcludeNil  bool // If true, skip generating fields for nil pointer-structs
-	IncludeZero bool
+	Sort            bool
+	Generate        bool
+	Headers         *[]string
+	ExcludeNil      bool                // If true, skip generating fields for nil pointer-structs
+	OmitEmpty       bool                // If true, skip generating fields tagged `omitempty` whose value is zero
+	HeaderPrefix    string              // Namespace prepended to every generated header and flattened key
+	IncludeZero     bool                // If true, keep zero-valued fields in ToMapOpts
+	Strict          bool                // If true, provided headers that match no field are an error instead of an empty column
+	ColumnOrder     []string            // Columns moved to the front of the output, in this order; the rest follow in their natural order
+	DurationUnit    time.Duration       // Unit time.Duration values are counted in; zero renders them with Duration.String()
+	FloatFormat     byte                // strconv.FormatFloat format for float leaves; zero keeps fmt.Sprint's rendering
+	FloatPrecision  int                 // strconv.FormatFloat precision used with FloatFormat
+	BoolFormat      BoolFormat          // Rendering of bool leaves; the zero value keeps true/false
+	MaxKeys         int                 // If positive, flattening more keys than this fails with ErrTooManyKeys
+	SliceColumns    []string            // Indexed columns (e.g. "items.00.name") an empty slice fills with empty values
+	ZeroPadWidth    int                 // If positive, the fixed digit width of slice indexes; zero sizes it per slice
+	Unsupported     string              // Rendering of chan, func and unsafe.Pointer values; empty omits their columns
+	ExcludeFields   []string            // Fields removed, with everything nested under them, from headers and flattened output
+	TagName         string              // Struct tag naming fields (and carrying ",inline" and "-"); empty uses json, then url, then xml
+	NoFieldCache    bool                // If true, resolve every struct's fields on each call instead of caching them per type
+	HeaderTransform func(string) string // Applied to every header and flattened key's final form (e.g. to snake_case them)
+	Context         context.Context     // If set, flattening aborts with its error once it is cancelled
+	steps           int                 // Values flattened so far, counting towards the next Context check
+	allNames        bool                // If true, GenerateFieldNames names every field of the type, whatever the value holds
 }
 
+// contextCheckInterval is how many values are flattened between checks of the configured Context
+const contextCheckInterval = 1024
+
+// BoolFormat selects how bool leaf values are rendered when flattening
+type BoolFormat int
+
+const (
+	BoolTrueFalse BoolFormat = iota // true / false (default)
+	BoolUpper                       // TRUE / FALSE, as Sheets formulas write them
+	BoolYesNo                       // yes / no
+	BoolOneZero                     // 1 / 0
+)
+
+// ErrUnknownHeaders is returned under WithStrict when provided headers match no field of the struct
+var ErrUnknownHeaders = errors.New("headers match no struct field")
+
+// ErrTooManyKeys is returned under WithMaxKeys when flattening produces more keys than allowed
+var ErrTooManyKeys = errors.New("flattened key limit exceeded")
+
 type Option func(*pkgConfig)
 
 // WithSortFields tells the package to sort the fields of the struct
@@ -42,13 +84,150 @@ func WithGenerate() Option {
 	}
 }
 
-// WithHeaders tells the package to use the provided headers
+// WithHeaders tells the package to use the provided headers. The slice is only read, so it can be reused across calls.
 func WithHeaders(headers *[]string) Option {
 	return func(cfg *pkgConfig) {
 		cfg.Headers = headers
 	}
 }
 
+// WithHeaderPrefix prepends a namespace to every generated header and flattened key (e.g. "user" → "user.email"),
+// so multiple struct types can be combined without column collisions. Provided headers are matched with the prefix applied.
+func WithHeaderPrefix(prefix string) Option {
+	return func(cfg *pkgConfig) {
+		cfg.HeaderPrefix = prefix
+	}
+}
+
+// WithOmitEmpty instructs the package to skip generating fields tagged `omitempty` whose value is zero.
+// Across a slice, a field populated in any element still produces a header.
+func WithOmitEmpty() Option {
+	return func(cfg *pkgConfig) {
+		cfg.OmitEmpty = true
+	}
+}
+
+// WithIncludeZero instructs ToMapOpts to keep fields with a zero value.
+func WithIncludeZero() Option {
+	return func(cfg *pkgConfig) {
+		cfg.IncludeZero = true
+	}
+}
+
+// WithStrict makes FlattenStructFields return ErrUnknownHeaders, listing every provided header that matches no field,
+// instead of silently filling those columns with "".
+func WithStrict() Option {
+	return func(cfg *pkgConfig) {
+		cfg.Strict = true
+	}
+}
+
+// WithColumnOrder moves the listed columns (and any fields nested under them, e.g. "tags" → "tags.00") to the front of
+// FlattenStructFields output in the given order, without filtering: unlisted columns follow in their natural order.
+func WithColumnOrder(columns []string) Option {
+	return func(cfg *pkgConfig) {
+		cfg.ColumnOrder = columns
+	}
+}
+
+// WithDurationUnit renders time.Duration values as a count of unit (e.g. time.Minute renders an hour as "60")
+// instead of the default Duration.String() form ("1h0m0s").
+func WithDurationUnit(unit time.Duration) Option {
+	return func(cfg *pkgConfig) {
+		cfg.DurationUnit = unit
+	}
+}
+
+// WithFloatFormat renders float leaf values with strconv.FormatFloat(v, format, prec, bitSize) instead of fmt.Sprint,
+// e.g. WithFloatFormat('f', 2) renders 1e+06 as "1000000.00". float32 fields are formatted at 32-bit precision.
+func WithFloatFormat(format byte, prec int) Option {
+	return func(cfg *pkgConfig) {
+		cfg.FloatFormat = format
+		cfg.FloatPrecision = prec
+	}
+}
+
+// WithBoolFormat renders bool leaf values in the given style instead of Go's true/false.
+func WithBoolFormat(format BoolFormat) Option {
+	return func(cfg *pkgConfig) {
+		cfg.BoolFormat = format
+	}
+}
+
+// WithMaxKeys caps the number of keys FlattenStructFields (and FlattenNestedStructs) may produce, failing with
+// ErrTooManyKeys as soon as the cap is exceeded, so an unexpectedly huge nested map can't exhaust memory.
+func WithMaxKeys(n int) Option {
+	return func(cfg *pkgConfig) {
+		cfg.MaxKeys = n
+	}
+}
+
+// WithSliceColumns aligns empty slices with columns flattened from other rows: an empty slice whose indexed columns
+// (e.g. "items.00.name" for "items") appear in columns emits those columns empty instead of a single "items" key.
+func WithSliceColumns(columns []string) Option {
+	return func(cfg *pkgConfig) {
+		cfg.SliceColumns = columns
+	}
+}
+
+// WithZeroPadWidth pads every slice index to n digits (e.g. n=3 gives "items.009"), so rows whose slices differ in
+// length produce the same keys. Without it the width follows each slice's length, with a minimum of 2.
+func WithZeroPadWidth(n int) Option {
+	return func(cfg *pkgConfig) {
+		cfg.ZeroPadWidth = n
+	}
+}
+
+// WithUnsupportedPlaceholder renders chan, func and unsafe.Pointer values, which have no meaningful text form, as
+// placeholder (e.g. "<func>") instead of omitting their columns.
+func WithUnsupportedPlaceholder(placeholder string) Option {
+	return func(cfg *pkgConfig) {
+		cfg.Unsupported = placeholder
+	}
+}
+
+// WithExcludeFields removes the listed fields, and every field nested under them (e.g. "address" removes "address.city"),
+// from FlattenStructFields headers and output. Without WithHeaders, all fields are generated before the exclusions apply.
+func WithExcludeFields(fields []string) Option {
+	return func(cfg *pkgConfig) {
+		cfg.ExcludeFields = fields
+	}
+}
+
+// WithHeaderTransform applies fn to the final form of every header and flattened key, e.g. strings.ToLower, so
+// downstream systems get normalized column names. Provided headers and excluded fields are transformed too, so
+// filtering matches whether they are given in the original or the transformed form.
+func WithHeaderTransform(fn func(string) string) Option {
+	return func(cfg *pkgConfig) {
+		cfg.HeaderTransform = fn
+	}
+}
+
+// WithContext aborts flattening with ctx.Err() once ctx is cancelled, checking it periodically while recursing so a huge
+// structure (e.g. flattened for an HTTP handler whose client went away) stops promptly.
+func WithContext(ctx context.Context) Option {
+	return func(cfg *pkgConfig) {
+		cfg.Context = ctx
+	}
+}
+
+// WithTagName names fields from the given struct tag (e.g. `rego:"name"`) instead of their json tags, so sheet columns
+// can be named independently of JSON encoding. The tag's ",inline" option and "-" are honored as they are for json;
+// fields without the tag fall back to their camelCased Go name.
+func WithTagName(name string) Option {
+	return func(cfg *pkgConfig) {
+		cfg.TagName = name
+	}
+}
+
+// WithoutFieldCache resolves struct field metadata on every call instead of caching it per type, for callers flattening many
+// one-off types (e.g. built with reflect.StructOf) that would otherwise stay in the cache.
+func WithoutFieldCache() Option {
+	return func(cfg *pkgConfig) {
+		cfg.NoFieldCache = true
+	}
+}
+
 // WithExcludeNilStructs instructs the package to skip expanding fields in nil pointer-structs.
 func WithExcludeNil() Option {
 	return func(cfg *pkgConfig) {
@@ -56,6 +235,16 @@ func WithExcludeNil() Option {
 	}
 }
 
+// withAllFieldNames makes GenerateFieldNames name every field any value of the type could produce: nil pointer-structs
+// are expanded, and nothing is left out for being nil or empty
+func withAllFieldNames() Option {
+	return func(cfg *pkgConfig) {
+		cfg.allNames = true
+		cfg.ExcludeNil = false
+		cfg.OmitEmpty = false
+	}
+}
+
 // ---------------------------------------------------------------------
 // Utility Functions
 // ---------------------------------------------------------------------
@@ -64,20 +253,70 @@ func WithExcludeNil() Option {
  * Print a struct as a JSON string
  */
 func PrettyJSON(data interface{}) (string, error) {
+	return PrettyJSONWithOptions(data, "  ", true)
+}
+
+/*
+ * Print a struct as a JSON string using the given indent
+ * - When escapeHTML is false, characters such as <, >, and & are written literally instead of as \u003c etc.
+ */
+func PrettyJSONWithOptions(data interface{}, indent string, escapeHTML bool) (string, error) {
+	buffer, err := encodeJSON(data, indent, escapeHTML)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+/*
+ * Print a struct as a single-line JSON string, without indentation or a trailing newline
+ */
+func CompactJSON(data interface{}) (string, error) {
+	buffer, err := encodeJSON(data, "", true)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// encodeJSON is the shared encoder configuration behind PrettyJSON and CompactJSON
+func encodeJSON(data interface{}, indent string, escapeHTML bool) (*bytes.Buffer, error) {
 	buffer := new(bytes.Buffer)
 	encoder := json.NewEncoder(buffer)
-	encoder.SetIndent("", "  ")
+	if indent != "" {
+		encoder.SetIndent("", indent)
+	}
+	encoder.SetEscapeHTML(escapeHTML)
 
 	err := encoder.Encode(data)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return buffer.String(), nil
+	return buffer, nil
 }
 
 // ToMap converts a struct (or map) to a map[string]interface{}.
 // If includeZeroValues is false then any field with a zero value is skipped.
 func ToMap(item interface{}, includeZeroValues bool) (map[string]interface{}, error) {
+	if includeZeroValues {
+		return ToMapOpts(item, WithIncludeZero())
+	}
+	return ToMapOpts(item)
+}
+
+// ToMapOpts converts a struct (or map) to a map[string]interface{}, configured by options.
+// Zero-valued fields are skipped unless WithIncludeZero is set; nil pointers, interfaces, maps and slices are skipped when
+// WithExcludeNil is set, so WithIncludeZero and WithExcludeNil together keep zero scalars but drop nils.
+func ToMapOpts(item interface{}, opts ...Option) (map[string]interface{}, error) {
+	cfg := &pkgConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return toMap(item, cfg)
+}
+
+// toMap implements ToMapOpts for an already-resolved configuration.
+func toMap(item interface{}, cfg *pkgConfig) (map[string]interface{}, error) {
 	out := make(map[string]interface{})
 
 	v := reflect.ValueOf(item)
@@ -103,31 +342,28 @@ func ToMap(item interface{}, includeZeroValues bool) (map[string]interface{}, er
 			continue
 		}
 
-		if !includeZeroValues && field.IsZero() {
+		// Skip ignored fields, matching flattening
+		if getFirstTag(fieldTag(typeOfItem.Field(i), cfg)) == "-" {
+			continue
+		}
+
+		if !cfg.IncludeZero && field.IsZero() {
+			continue
+		}
+
+		// Exclude nil pointers, interfaces, maps and slices, if set
+		if cfg.ExcludeNil && isNilable(field.Kind()) && field.IsNil() {
 			continue
 		}
 
-		key := getMapKey(typeOfItem.Field(i))
+		key := getMapKey(typeOfItem.Field(i), cfg)
 		if key == "" {
 			key = camelKey(typeOfItem.Field(i).Name)
 		}
 
-		var value interface{}
-		switch field.Kind() {
-		case reflect.Struct:
-			nestedMap, err := ToMap(field.Interface(), includeZeroValues)
-			if err != nil {
-				return nil, err
-			}
-			value = nestedMap
-		case reflect.Slice, reflect.Array:
-			sliceValues, err := sliceToInterface(field, includeZeroValues)
-			if err != nil {
-				return nil, err
-			}
-			value = sliceValues
-		default:
-			value = field.Interface()
+		value, err := toMapValue(field, cfg)
+		if err != nil {
+			return nil, err
 		}
 
 		out[key] = value
@@ -136,13 +372,142 @@ func ToMap(item interface{}, includeZeroValues bool) (map[string]interface{}, er
 	return out, nil
 }
 
+// MapEntry is a key and its value in an OrderedMap
+type MapEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// OrderedMap is a map's entries sorted by key. It marshals to a JSON object with the keys in that order.
+type OrderedMap []MapEntry
+
+// ToOrderedMap converts a struct (or map) like ToMapOpts, but returns its keys, and those of every nested map, in sorted
+// order, so ranging over the result (or serializing it by hand) is deterministic.
+func ToOrderedMap(item interface{}, opts ...Option) (OrderedMap, error) {
+	m, err := ToMapOpts(item, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return orderMap(m), nil
+}
+
+// Get returns the value for key, if present
+func (om OrderedMap) Get(key string) (interface{}, bool) {
+	for _, entry := range om {
+		if entry.Key == key {
+			return entry.Value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON writes om as a JSON object, keeping its key order
+func (om OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range om {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Key, err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// orderMap converts m, and the maps nested in it, into OrderedMaps
+func orderMap(m map[string]interface{}) OrderedMap {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	om := make(OrderedMap, 0, len(keys))
+	for _, key := range keys {
+		om = append(om, MapEntry{Key: key, Value: orderValue(m[key])})
+	}
+	return om
+}
+
+// orderValue orders the maps within a ToMap value, descending into slices
+func orderValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return orderMap(v)
+	case []interface{}:
+		ordered := make([]interface{}, len(v))
+		for i, elem := range v {
+			ordered[i] = orderValue(elem)
+		}
+		return ordered
+	}
+	return v
+}
+
+// isNilable reports whether values of kind k can be nil for the purposes of WithExcludeNil
+func isNilable(k reflect.Kind) bool {
+	switch k {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return true
+	}
+	return false
+}
+
+// toMapValue normalizes a field value for ToMap, dereferencing pointers and converting
+// structs and maps to map[string]interface{} and slices to []interface{}.
+func toMapValue(v reflect.Value, cfg *pkgConfig) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toMapValue(v.Elem(), cfg)
+	case reflect.Struct:
+		// time.Time has no exported fields, so keep it as-is rather than producing an empty map
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			return v.Interface(), nil
+		}
+		return toMap(v.Interface(), cfg)
+	case reflect.Map:
+		return mapToInterface(v, cfg)
+	case reflect.Slice, reflect.Array:
+		return sliceToInterface(v, cfg)
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// mapToInterface converts a map to a map[string]interface{}, normalizing each value.
+func mapToInterface(v reflect.Value, cfg *pkgConfig) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, v.Len())
+	for _, key := range v.MapKeys() {
+		value, err := toMapValue(v.MapIndex(key), cfg)
+		if err != nil {
+			return nil, err
+		}
+		out[fmt.Sprint(key.Interface())] = value
+	}
+	return out, nil
+}
+
 // sliceToInterface converts a slice/array to a []interface{}.
-func sliceToInterface(v reflect.Value, includeZeroValues bool) ([]interface{}, error) {
+func sliceToInterface(v reflect.Value, cfg *pkgConfig) ([]interface{}, error) {
 	var result []interface{}
 	for i := 0; i < v.Len(); i++ {
 		elem := v.Index(i)
 		if elem.Kind() == reflect.Struct {
-			nestedMap, err := ToMap(elem.Interface(), includeZeroValues)
+			nestedMap, err := toMap(elem.Interface(), cfg)
 			if err != nil {
 				return nil, err
 			}
@@ -192,6 +557,287 @@ func TableToStructs(data [][]string) ([]interface{}, error) {
 	return results, nil
 }
 
+// TaggedFieldNames returns the flattened keys (as produced by FlattenNestedStructs) of the fields of t whose tagKey tag
+// lists value among its comma-separated options, e.g. TaggedFieldNames(t, "rego", "link") for `rego:"link"` fields.
+// Nested structs and pointers to structs are walked; t may itself be a pointer to a struct.
+func TaggedFieldNames(t reflect.Type, tagKey, value string, opts ...Option) []string {
+	cfg := &pkgConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return taggedFieldNames(t, "", tagKey, value, cfg)
+}
+
+func taggedFieldNames(t reflect.Type, prefix, tagKey, value string, cfg *pkgConfig) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || getFirstTag(fieldTag(field, cfg)) == "-" {
+			continue
+		}
+		key := joinPrefixKey(prefix, getMapKey(field, cfg))
+		if shouldInline(field, cfg) {
+			key = prefix
+		}
+
+		for _, option := range strings.Split(field.Tag.Get(tagKey), ",") {
+			if option == value {
+				names = append(names, key)
+				break
+			}
+		}
+		names = append(names, taggedFieldNames(field.Type, key, tagKey, value, cfg)...)
+	}
+	return names
+}
+
+// MapToStruct assigns the values of a flattened map (as produced by FlattenNestedStructs) to out, which must be a pointer to a struct.
+// Keys are matched to the same tag-resolved paths used when flattening, and scalars are parsed into the destination field's type.
+// Slice elements are placed by their numeric index keys, whatever their zero-padding, so slices (including slices of slices,
+// of maps, and of pointers) come back in their original order.
+func MapToStruct(m map[string]string, out interface{}, opts ...Option) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("MapToStruct: expected a non-nil pointer to a struct, got %T", out)
+	}
+	cfg := &pkgConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return unflattenValue(m, "", v.Elem(), cfg)
+}
+
+// unflattenValue populates v from the keys of m at or beneath prefix.
+func unflattenValue(m map[string]string, prefix string, v reflect.Value, cfg *pkgConfig) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if raw, ok := m[prefix]; (ok && raw == "<nil>") || !hasKeyPrefix(m, prefix) {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unflattenValue(m, prefix, v.Elem(), cfg)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			return setScalarKey(m, prefix, v, cfg)
+		}
+		typ := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() || getFirstTag(fieldTag(field, cfg)) == "-" {
+				continue
+			}
+			key := joinPrefixKey(prefix, getMapKey(field, cfg))
+			if shouldInline(field, cfg) {
+				key = prefix
+			}
+			if err := unflattenValue(m, key, v.Field(i), cfg); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		type index struct {
+			segment string
+			num     int
+		}
+		var indices []index
+		for _, segment := range childSegments(m, prefix) {
+			if num, err := strconv.Atoi(segment); err == nil && num >= 0 {
+				indices = append(indices, index{segment, num})
+			}
+		}
+		if len(indices) == 0 {
+			return nil
+		}
+		sort.Slice(indices, func(i, j int) bool { return indices[i].num < indices[j].num })
+
+		// Elements go back to the position their index names, so ordering survives any padding width
+		target := v
+		if v.Kind() == reflect.Slice {
+			target = reflect.MakeSlice(v.Type(), indices[len(indices)-1].num+1, indices[len(indices)-1].num+1)
+		}
+		for _, idx := range indices {
+			if idx.num >= target.Len() {
+				return fmt.Errorf("index %s out of range for %v", idx.segment, v.Type())
+			}
+			if err := unflattenValue(m, joinPrefixKey(prefix, idx.segment), target.Index(idx.num), cfg); err != nil {
+				return err
+			}
+		}
+		v.Set(target)
+		return nil
+	case reflect.Map:
+		keyType := v.Type().Key()
+		switch keyType.Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return fmt.Errorf("unsupported map key type: %v", keyType)
+		}
+		segments := childSegments(m, prefix)
+		if len(segments) == 0 {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for _, segment := range segments {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := unflattenValue(m, joinPrefixKey(prefix, segment), elem, cfg); err != nil {
+				return err
+			}
+			key, err := parseMapKey(unescapeKey(segment), keyType)
+			if err != nil {
+				return fmt.Errorf("map key %q: %w", segment, err)
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return nil
+	default:
+		return setScalarKey(m, prefix, v, cfg)
+	}
+}
+
+// parseMapKey converts a flattened key segment to a map key of type t (string or integer).
+func parseMapKey(segment string, t reflect.Type) (reflect.Value, error) {
+	key := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		key.SetString(segment)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(segment, 10, t.Bits())
+		if err != nil {
+			return key, err
+		}
+		key.SetInt(n)
+	default:
+		n, err := strconv.ParseUint(segment, 10, t.Bits())
+		if err != nil {
+			return key, err
+		}
+		key.SetUint(n)
+	}
+	return key, nil
+}
+
+// hasKeyPrefix reports whether m has the key prefix, or any key nested beneath it.
+func hasKeyPrefix(m map[string]string, prefix string) bool {
+	if _, ok := m[prefix]; ok {
+		return true
+	}
+	return len(childSegments(m, prefix)) > 0
+}
+
+// childSegments returns the sorted, unique path segments directly beneath prefix.
+func childSegments(m map[string]string, prefix string) []string {
+	seen := make(map[string]struct{})
+	for key := range m {
+		rest := key
+		if prefix != "" {
+			if !strings.HasPrefix(key, prefix+".") {
+				continue
+			}
+			rest = key[len(prefix)+1:]
+		}
+		seen[firstSegment(rest)] = struct{}{}
+	}
+
+	segments := make([]string, 0, len(seen))
+	for segment := range seen {
+		segments = append(segments, segment)
+	}
+	sort.Strings(segments)
+	return segments
+}
+
+// setScalarKey parses the value of key in m into v, leaving v untouched when the key is missing, empty, or "<nil>".
+// Bools are read in cfg's BoolFormat as well as Go's true/false.
+func setScalarKey(m map[string]string, key string, v reflect.Value, cfg *pkgConfig) error {
+	raw, ok := m[key]
+	if !ok || raw == "" || raw == "<nil>" {
+		return nil
+	}
+
+	switch {
+	case v.Type() == reflect.TypeOf(time.Time{}):
+		// fmt.Sprint appends the monotonic clock reading, which time.Parse does not accept
+		if i := strings.Index(raw, " m="); i >= 0 {
+			raw = raw[:i]
+		}
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			t, err = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", raw)
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(reflect.ValueOf(t))
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	default:
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(raw)
+		case reflect.Bool:
+			b, err := parseBool(raw, cfg.BoolFormat)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetUint(n)
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(raw, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetFloat(f)
+		case reflect.Interface:
+			v.Set(reflect.ValueOf(raw))
+		default:
+			return fmt.Errorf("unsupported field type: %v", v.Type())
+		}
+	}
+	return nil
+}
+
+// parseBool parses a bool leaf written in format; strconv.ParseBool already covers TRUE/FALSE and 1/0, leaving yes/no.
+func parseBool(raw string, format BoolFormat) (bool, error) {
+	if format == BoolYesNo {
+		switch strings.ToLower(raw) {
+		case "yes":
+			return true, nil
+		case "no":
+			return false, nil
+		}
+	}
+	return strconv.ParseBool(raw)
+}
+
 // ensureValidIdentifier makes sure the string is a valid Go identifier.
 func ensureValidIdentifier(name string) string {
 	if name == "" || !isLetter(rune(name[0])) {
@@ -236,8 +882,94 @@ func mapFromMap(v reflect.Value) map[string]interface{} {
 // Flattening and Field-Generation Functions
 // ---------------------------------------------------------------------
 
+// FieldPair is a flattened field name and its value
+type FieldPair struct {
+	Key   string
+	Value string
+}
+
+// GetByPath returns the value at the dotted key path in a flattened map (as produced by FlattenNestedStructs).
+// Segments containing a literal "." must be escaped the way flattening escapes them, e.g. "domains.example\.com".
+func GetByPath(flat map[string]string, path string) (string, bool) {
+	value, ok := flat[trimPathDelimiter(path)]
+	return value, ok
+}
+
+// FilterByPrefix returns the entries of a flattened map at or beneath the dotted key path prefix, matching whole
+// segments only: "address" selects "address" and "address.city", but not "addresses.00". An empty prefix selects all.
+func FilterByPrefix(flat map[string]string, prefix string) map[string]string {
+	prefix = trimPathDelimiter(prefix)
+	filtered := make(map[string]string)
+	for key, value := range flat {
+		if prefix == "" || hasPathPrefix(key, prefix) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
+
+// hasPathPrefix reports whether key is prefix itself or a key nested beneath it.
+func hasPathPrefix(key, prefix string) bool {
+	return key == prefix || strings.HasPrefix(key, prefix+".")
+}
+
+// matchesAnyPath reports whether key is at or beneath any of the dotted key paths
+func matchesAnyPath(key string, paths []string) bool {
+	for _, path := range paths {
+		if hasPathPrefix(key, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimPathDelimiter removes a trailing "." delimiter from path, leaving an escaped "\." in place.
+func trimPathDelimiter(path string) string {
+	if strings.HasSuffix(path, ".") && !strings.HasSuffix(path, `\.`) {
+		return strings.TrimSuffix(path, ".")
+	}
+	return path
+}
+
+// FlattenToOrderedMap flattens a struct like FlattenStructFields, returning the fields as key/value pairs in header
+// order (or generated order).
+func FlattenToOrderedMap(item interface{}, opts ...Option) ([]FieldPair, error) {
+	fields, err := FlattenStructFields(item, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]FieldPair, 0, len(fields))
+	for _, field := range fields {
+		pairs = append(pairs, FieldPair{Key: field[0], Value: field[1]})
+	}
+	return pairs, nil
+}
+
+// FlattenStructFieldsContext is FlattenStructFields aborting with ctx.Err() (e.g. context.Canceled) once ctx is cancelled.
+func FlattenStructFieldsContext(ctx context.Context, item interface{}, opts ...Option) ([][]string, error) {
+	return FlattenStructFields(item, append(opts, WithContext(ctx))...)
+}
+
 // FlattenStructFields recursively flattens a struct and its nested fields into a two-dimensional slice.
+// The expanded field list (e.g. "tags" → "tags.00", "tags.01") is the first column of the result.
 func FlattenStructFields(item interface{}, opts ...Option) ([][]string, error) {
+	return flattenStructFields(item, nil, opts...)
+}
+
+// FlattenStructFieldsKinds is FlattenStructFields also returning the kinds FlattenStructKinds would, keyed like the
+// flattened fields, from the same pass over item.
+func FlattenStructFieldsKinds(item interface{}, opts ...Option) ([][]string, map[string]reflect.Kind, error) {
+	kinds := make(map[string]reflect.Kind)
+	fields, err := flattenStructFields(item, kinds, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fields, kinds, nil
+}
+
+// flattenStructFields implements FlattenStructFields, recording leaf kinds into kinds when it is non-nil
+func flattenStructFields(item interface{}, kinds map[string]reflect.Kind, opts ...Option) ([][]string, error) {
 	// Default config
 	cfg := &pkgConfig{
 		Sort:     false,
@@ -259,22 +991,33 @@ func FlattenStructFields(item interface{}, opts ...Option) ([][]string, error) {
 		return nil, fmt.Errorf("expected a struct or pointer to a struct, got %v", val.Kind())
 	}
 
+	// Excluding fields is relative to the full field list, unless headers narrow it down first
+	if len(cfg.ExcludeFields) > 0 && (cfg.Headers == nil || len(*cfg.Headers) == 0) {
+		cfg.Generate = true
+	}
+
 	// Dynamically generate headers (if requested)
 	if cfg.Generate && (cfg.Headers == nil || len(*cfg.Headers) == 0) {
 		cfg.Headers = &[]string{}
-		generatedFields, err := GenerateFieldNames("", val)
+		// The caller's options name the same fields flattening emits; GenerateFieldNames applies the prefix itself
+		generatedFields, err := GenerateFieldNames("", val, opts...)
 		if err != nil {
 			return nil, err
 		}
 		*cfg.Headers = append(*cfg.Headers, *generatedFields...)
+	} else if cfg.HeaderPrefix != "" {
+		cfg.Headers = prefixHeaders(cfg.HeaderPrefix, cfg.Headers)
 	}
+	cfg.Headers = transformHeaders(cfg.Headers, cfg)
 
 	// Build a map to hold flattened field names and their values
 	fieldMap := make(map[string]string)
-	err = FlattenNestedStructs(item, "", &fieldMap)
+	err = flattenNested(item, cfg.HeaderPrefix, &fieldMap, kinds, cfg)
 	if err != nil {
 		return nil, err
 	}
+	fieldMap = transformKeys(fieldMap, cfg)
+	transformKinds(kinds, cfg)
 
 	// If not generating, limit the output to only the provided headers.
 	if !cfg.Generate {
@@ -286,31 +1029,139 @@ func FlattenStructFields(item interface{}, opts ...Option) ([][]string, error) {
 
 		for key, value := range fieldMap {
 			for field := range headerSet {
-				if key == field || strings.HasPrefix(key, field+".") {
+				if hasPathPrefix(key, field) {
 					newMap[key] = value
 					break
 				}
 			}
 		}
 
-		// If any header is missing, add it with an empty string.
-		if len(newMap) < len(*cfg.Headers) {
-			for _, header := range *cfg.Headers {
-				if _, ok := newMap[header]; !ok {
-					newMap[header] = ""
-				}
+		if cfg.Strict {
+			if err := checkUnknownHeaders(val, cfg, fieldMap, opts); err != nil {
+				return nil, err
+			}
+		}
+
+		// If any header is missing, add it with an empty string.
+		if len(newMap) < len(*cfg.Headers) {
+			for _, header := range *cfg.Headers {
+				if _, ok := newMap[header]; !ok {
+					newMap[header] = ""
+				}
+			}
+		}
+		fieldMap = newMap
+	}
+
+	if len(cfg.ExcludeFields) > 0 {
+		excluded := *transformHeaders(prefixHeaders(cfg.HeaderPrefix, &cfg.ExcludeFields), cfg)
+		headers := make([]string, 0, len(*cfg.Headers))
+		for _, header := range *cfg.Headers {
+			if !matchesAnyPath(header, excluded) {
+				headers = append(headers, header)
+			}
+		}
+		cfg.Headers = &headers
+		for key := range fieldMap {
+			if matchesAnyPath(key, excluded) {
+				delete(fieldMap, key)
+			}
+		}
+	}
+
+	// Convert the fieldMap into a 2D slice (field and value) while updating headers.
+	return orderColumns(mapToSlice(fieldMap, *cfg.Headers), cfg.ColumnOrder), nil
+}
+
+// checkUnknownHeaders returns ErrUnknownHeaders listing the provided headers that match neither a generated field name
+// of val's type nor a flattened key (or a parent of one)
+func checkUnknownHeaders(val reflect.Value, cfg *pkgConfig, fieldMap map[string]string, opts []Option) error {
+	generated, err := GenerateFieldNames("", val, append(opts[:len(opts):len(opts)], withAllFieldNames())...)
+	if err != nil {
+		return err
+	}
+	generated = transformHeaders(generated, cfg)
+	known := make([]string, 0, len(*generated)+len(fieldMap))
+	known = append(known, *generated...)
+	for key := range fieldMap {
+		known = append(known, key)
+	}
+
+	var unknown []string
+	for _, header := range *cfg.Headers {
+		matched := false
+		for _, field := range known {
+			if field == header || strings.HasPrefix(field, header+".") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unknown = append(unknown, header)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownHeaders, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// StructDiff flattens two structs and returns the keys whose values differ, mapped to their [old, new] values.
+// Keys present in only one of the structs use "<nil>" for the missing side.
+// If WithHeaders is set, only keys matching those headers (or nested beneath them) are compared.
+func StructDiff(a, b interface{}, opts ...Option) (map[string][2]string, error) {
+	cfg := &pkgConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.HeaderPrefix != "" {
+		cfg.Headers = prefixHeaders(cfg.HeaderPrefix, cfg.Headers)
+	}
+
+	oldMap := make(map[string]string)
+	if err := flattenNested(a, cfg.HeaderPrefix, &oldMap, nil, cfg); err != nil {
+		return nil, err
+	}
+	newMap := make(map[string]string)
+	if err := flattenNested(b, cfg.HeaderPrefix, &newMap, nil, cfg); err != nil {
+		return nil, err
+	}
+
+	include := func(key string) bool {
+		if cfg.Headers == nil || len(*cfg.Headers) == 0 {
+			return true
+		}
+		for _, header := range *cfg.Headers {
+			if key == header || strings.HasPrefix(key, header+".") {
+				return true
 			}
 		}
-		fieldMap = newMap
+		return false
 	}
 
-	// Convert the fieldMap into a 2D slice (field and value) while updating headers.
-	fieldSlice, err := mapToSliceAndUpdateFields(&fieldMap, cfg.Headers)
-	if err != nil {
-		return nil, err
+	diff := make(map[string][2]string)
+	for key, oldValue := range oldMap {
+		if !include(key) {
+			continue
+		}
+		newValue, ok := newMap[key]
+		if !ok {
+			newValue = "<nil>"
+		}
+		if oldValue != newValue {
+			diff[key] = [2]string{oldValue, newValue}
+		}
+	}
+	for key, newValue := range newMap {
+		if _, ok := oldMap[key]; ok || !include(key) {
+			continue
+		}
+		diff[key] = [2]string{"<nil>", newValue}
 	}
 
-	return fieldSlice, nil
+	return diff, nil
 }
 
 // GenerateFieldNames recursively generates field names from a struct (or slice/map thereof), dereferencing pointers as needed.
@@ -325,6 +1176,12 @@ func GenerateFieldNames(prefix string, val reflect.Value, opts ...Option) (*[]st
 		opt(cfg)
 	}
 
+	// Apply the header prefix once at the top level, so nested calls don't repeat it
+	if cfg.HeaderPrefix != "" {
+		nestedOpts := append(append([]Option{}, opts...), WithHeaderPrefix(""))
+		return GenerateFieldNames(joinPrefixKey(cfg.HeaderPrefix, prefix), val, nestedOpts...)
+	}
+
 	var err error
 	val, err = DerefPointers(val)
 	if err != nil {
@@ -336,7 +1193,7 @@ func GenerateFieldNames(prefix string, val reflect.Value, opts ...Option) (*[]st
 
 	switch val.Kind() {
 	case reflect.Map:
-		mapFields, err := generateMapFieldNames(prefix, val)
+		mapFields, err := generateMapFieldNames(prefix, val, opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -366,8 +1223,19 @@ func GenerateFieldNames(prefix string, val reflect.Value, opts ...Option) (*[]st
 			mergedFields = *subFieldsPtr
 			break
 		}
+		// Elements of a struct type whose names never vary all share the baseline, so there is nothing to merge
+		elemType := val.Type().Elem()
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		if len(mergedFields) > 0 && elemType.Kind() == reflect.Struct && staticNames(elemType, cfg) {
+			return &mergedFields, nil
+		}
 		// Now, for every candidate, merge in its field names into the baseline.
 		for i := 0; i < val.Len(); i++ {
+			if err := checkContext(cfg); err != nil {
+				return nil, err
+			}
 			mergeCandidate := val.Index(i)
 			if (mergeCandidate.Kind() == reflect.Ptr || mergeCandidate.Kind() == reflect.Interface) && mergeCandidate.IsNil() {
 				continue
@@ -394,23 +1262,18 @@ func GenerateFieldNames(prefix string, val reflect.Value, opts ...Option) (*[]st
 		return &mergedFields, nil
 
 	case reflect.Struct:
-		typ := val.Type()
-
 		// Handle struct fields
-		for i := 0; i < val.NumField(); i++ {
-			field := typ.Field(i)
-			fieldVal := val.Field(i)
-			jsonTag := getFirstTag(field.Tag.Get("json"))
+		for _, field := range structInfo(val.Type(), cfg).fields {
+			fieldVal := val.Field(field.index)
 
 			// If the type of the struct itself is time.Time and it's not an embedded field, add it to the fields
-			switch {
-			case field.Type.String() == "time.Time" && !field.Anonymous:
-				fields = append(fields, jsonTag)
+			if field.isTime {
+				fields = append(fields, field.name)
 				continue
 			}
 
 			// Skip ignored field
-			if jsonTag == "-" {
+			if field.ignored {
 				continue
 			}
 
@@ -421,26 +1284,38 @@ func GenerateFieldNames(prefix string, val reflect.Value, opts ...Option) (*[]st
 				}
 			}
 
-			fieldKey := joinPrefixKey(prefix, jsonTag)
+			// Exclude zero-valued omitempty fields, if set
+			if cfg.OmitEmpty && field.omitEmpty && fieldVal.IsZero() {
+				continue
+			}
+
+			fieldKey := joinPrefixKey(prefix, field.name)
 
 			// Recursively handle nested structs and inline structs if specified
-			if shouldInline(field) {
-				subFields, err := GenerateFieldNames(prefix, val.Field(i), opts...)
+			if isUnsupported(field.kind) {
+				if cfg.Unsupported != "" {
+					fields = append(fields, fieldKey)
+				}
+			} else if field.text {
+				fields = append(fields, fieldKey)
+			} else if (field.nested || field.mapped) && !field.inline && fieldVal.Kind() == reflect.Ptr && fieldVal.IsNil() && !cfg.allNames {
+				// A nil pointer flattens to a single "<nil>" value, so it gets a single column
+				fields = append(fields, fieldKey)
+			} else if field.inline {
+				subFields, err := GenerateFieldNames(prefix, fieldVal, opts...)
 				if err != nil {
 					return nil, err
 				}
 				fields = append(fields, *subFields...)
-			} else if field.Type.Kind() == reflect.Struct ||
-				(field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) {
+			} else if field.nested {
 				subPrefix := fieldKey
-				subFields, err := GenerateFieldNames(subPrefix, val.Field(i), opts...)
+				subFields, err := GenerateFieldNames(subPrefix, fieldVal, opts...)
 				if err != nil {
 					return nil, err
 				}
 				fields = append(fields, *subFields...)
-			} else if field.Type.Kind() == reflect.Map ||
-				(field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Map) {
-				subFields, err := generateMapFieldNames(fieldKey, val.Field(i))
+			} else if field.mapped {
+				subFields, err := generateMapFieldNames(fieldKey, fieldVal, opts...)
 				if err != nil {
 					return nil, err
 				}
@@ -463,6 +1338,11 @@ func GenerateFieldNames(prefix string, val reflect.Value, opts ...Option) (*[]st
 		return GenerateFieldNames(prefix, val.Elem(), opts...)
 	case reflect.Invalid:
 		return &[]string{prefix}, nil
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if cfg.Unsupported != "" {
+			return &[]string{prefix}, nil
+		}
+		return &fields, nil
 	default:
 		return nil, fmt.Errorf("GenerateFieldNames: unsupported input type: %v", val.Kind())
 	}
@@ -470,7 +1350,7 @@ func GenerateFieldNames(prefix string, val reflect.Value, opts ...Option) (*[]st
 
 // generateMapFieldNames generates field names from a map value.
 // The keys are sorted to ensure deterministic ordering.
-func generateMapFieldNames(prefix string, val reflect.Value) (*[]string, error) {
+func generateMapFieldNames(prefix string, val reflect.Value, opts ...Option) (*[]string, error) {
 	var err error
 	val, err = DerefPointers(val)
 	if err != nil {
@@ -484,18 +1364,18 @@ func generateMapFieldNames(prefix string, val reflect.Value) (*[]string, error)
 	var fields []string
 
 	// Sort map keys for consistent ordering.
-	keys := val.MapKeys()
-	sort.Slice(keys, func(i, j int) bool {
-		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
-	})
+	keys := sortedMapKeys(val)
 
 	for _, key := range keys {
-		keyStr := fmt.Sprint(key.Interface())
-		fieldKey := joinPrefixKey(prefix, keyStr)
-		value := val.MapIndex(key)
+		fieldKey := joinPrefixKey(prefix, mapKeyString(key))
+		// Pointer (and interface) values expand like the values they point to, e.g. map[string]*Inner
+		value, err := DerefPointers(val.MapIndex(key))
+		if err != nil {
+			return nil, err
+		}
 		switch value.Kind() {
 		case reflect.Map, reflect.Struct:
-			subFields, err := GenerateFieldNames(fieldKey, value)
+			subFields, err := GenerateFieldNames(fieldKey, value, opts...)
 			if err != nil {
 				return nil, err
 			}
@@ -511,21 +1391,16 @@ func generateMapFieldNames(prefix string, val reflect.Value) (*[]string, error)
 func DerefPointers(val reflect.Value) (reflect.Value, error) {
 	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
 		if val.IsNil() {
-			// Return the relevant nil value for the current type, "" for string, 0 for int, etc.
-			switch val.Kind() {
-			case reflect.String:
-				return reflect.ValueOf(""), nil
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				return reflect.ValueOf(0), nil
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				return reflect.ValueOf(uint(0)), nil
-			case reflect.Float32, reflect.Float64:
-				return reflect.ValueOf(float64(0)), nil
-			case reflect.Bool:
-				return reflect.ValueOf(false), nil
-			default:
-				//return reflect.Value{}, fmt.Errorf("DerefPointers: nil pointer/interface element")
+			// A nil interface carries no type information to build a zero value from
+			if val.Kind() == reflect.Interface {
+				return reflect.Value{}, nil
 			}
+			// Return the zero value of the pointed-to type, "" for *string, 0 for **int, etc.
+			typ := val.Type().Elem()
+			for typ.Kind() == reflect.Pointer {
+				typ = typ.Elem()
+			}
+			return reflect.Zero(typ), nil
 		}
 		if val.Kind() == reflect.Pointer {
 			val = val.Elem()
@@ -539,7 +1414,150 @@ func DerefPointers(val reflect.Value) (reflect.Value, error) {
 
 // FlattenNestedStructs recursively flattens a struct (and its nested fields) into a map.
 // The keys are generated using the provided prefix.
-func FlattenNestedStructs(item interface{}, prefix string, fieldMap *map[string]string) error {
+func FlattenNestedStructs(item interface{}, prefix string, fieldMap *map[string]string, opts ...Option) error {
+	cfg := &pkgConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.HeaderTransform == nil {
+		return flattenNested(item, prefix, fieldMap, nil, cfg)
+	}
+
+	// Keys already in fieldMap are the caller's, so only the newly flattened ones are transformed
+	flat := make(map[string]string)
+	if err := flattenNested(item, prefix, &flat, nil, cfg); err != nil {
+		return err
+	}
+	for key, value := range transformKeys(flat, cfg) {
+		(*fieldMap)[key] = value
+	}
+	return nil
+}
+
+// FlattenStructKinds returns the reflect.Kind of every numeric or boolean leaf FlattenNestedStructs would emit,
+// keyed the same way, so callers can restore native types from the flattened strings.
+func FlattenStructKinds(item interface{}, prefix string, opts ...Option) (map[string]reflect.Kind, error) {
+	cfg := &pkgConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	fieldMap := make(map[string]string)
+	kinds := make(map[string]reflect.Kind)
+	if err := flattenNested(item, prefix, &fieldMap, kinds, cfg); err != nil {
+		return nil, err
+	}
+	transformKinds(kinds, cfg)
+	return kinds, nil
+}
+
+// transformKinds re-keys kinds in place with the configured HeaderTransform, matching transformKeys
+func transformKinds(kinds map[string]reflect.Kind, cfg *pkgConfig) {
+	if cfg.HeaderTransform == nil || len(kinds) == 0 {
+		return
+	}
+	transformed := make(map[string]reflect.Kind, len(kinds))
+	for key, kind := range kinds {
+		transformed[cfg.HeaderTransform(key)] = kind
+	}
+	clear(kinds)
+	for key, kind := range transformed {
+		kinds[key] = kind
+	}
+}
+
+// recordKind notes the kind of a numeric or boolean leaf value when kinds are being collected
+func recordKind(kinds map[string]reflect.Kind, key string, v reflect.Value) {
+	if kinds == nil || !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		kinds[key] = v.Kind()
+	}
+}
+
+// checkContext counts a flattened value and, every contextCheckInterval values, returns the error of a cancelled Context
+func checkContext(cfg *pkgConfig) error {
+	if cfg.Context == nil {
+		return nil
+	}
+	cfg.steps++
+	if cfg.steps%contextCheckInterval != 0 {
+		return nil
+	}
+	return cfg.Context.Err()
+}
+
+// checkKeyLimit returns ErrTooManyKeys, with the key count reached, once fieldMap holds more than cfg.MaxKeys keys
+func checkKeyLimit(fieldMap map[string]string, cfg *pkgConfig) error {
+	if cfg.MaxKeys > 0 && len(fieldMap) > cfg.MaxKeys {
+		return fmt.Errorf("%w: %d keys, limit %d", ErrTooManyKeys, len(fieldMap), cfg.MaxKeys)
+	}
+	return nil
+}
+
+// flattenLeaf stores the string form of a scalar leaf value under key, recording its kind when kinds are being collected
+func flattenLeaf(key string, v reflect.Value, fieldMap *map[string]string, kinds map[string]reflect.Kind, cfg *pkgConfig) {
+	// A channel or function would only print its address, so it is omitted or rendered as the placeholder
+	if isUnsupported(v.Kind()) {
+		if cfg.Unsupported != "" {
+			(*fieldMap)[key] = cfg.Unsupported
+		}
+		return
+	}
+
+	// time.Duration is an int64, but renders as "1h0m0s" rather than a number unless a unit is set
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d := time.Duration(v.Int())
+		if cfg.DurationUnit <= 0 {
+			(*fieldMap)[key] = d.String()
+			return
+		}
+		(*fieldMap)[key] = strconv.FormatFloat(float64(d)/float64(cfg.DurationUnit), 'f', -1, 64)
+		recordKind(kinds, key, reflect.ValueOf(float64(0)))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		switch cfg.BoolFormat {
+		case BoolUpper:
+			(*fieldMap)[key] = strings.ToUpper(strconv.FormatBool(v.Bool()))
+			recordKind(kinds, key, v)
+			return
+		case BoolYesNo:
+			(*fieldMap)[key] = map[bool]string{true: "yes", false: "no"}[v.Bool()]
+			return
+		case BoolOneZero:
+			(*fieldMap)[key] = map[bool]string{true: "1", false: "0"}[v.Bool()]
+			recordKind(kinds, key, reflect.ValueOf(0))
+			return
+		}
+	case reflect.Float32, reflect.Float64:
+		if cfg.FloatFormat != 0 {
+			(*fieldMap)[key] = strconv.FormatFloat(v.Float(), cfg.FloatFormat, cfg.FloatPrecision, v.Type().Bits())
+			recordKind(kinds, key, v)
+			return
+		}
+	}
+	(*fieldMap)[key] = fmt.Sprint(v.Interface())
+	recordKind(kinds, key, v)
+}
+
+// isUnsupported reports whether values of kind k have no meaningful flattened form
+func isUnsupported(k reflect.Kind) bool {
+	return k == reflect.Chan || k == reflect.Func || k == reflect.UnsafePointer
+}
+
+// flattenNested implements FlattenNestedStructs, recording leaf kinds into kinds when it is non-nil
+func flattenNested(item interface{}, prefix string, fieldMap *map[string]string, kinds map[string]reflect.Kind, cfg *pkgConfig) error {
+	if err := checkContext(cfg); err != nil {
+		return err
+	}
+
 	val, err := DerefPointers(reflect.ValueOf(item))
 	if err != nil {
 		return err
@@ -547,81 +1565,134 @@ func FlattenNestedStructs(item interface{}, prefix string, fieldMap *map[string]
 
 	typ := val.Type()
 
-	// For non-struct types, handle maps or slices separately.
+	// For non-struct types, handle maps, slices, or arrays separately.
 	if val.Kind() != reflect.Struct {
 		if val.Kind() == reflect.Map {
-			return flattenMap(val, prefix, fieldMap)
+			return flattenMap(val, prefix, fieldMap, kinds, cfg)
 		}
-		if val.Kind() == reflect.Slice {
-			return flattenSlice(val, prefix, fieldMap)
+		if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+			return flattenSlice(val, prefix, fieldMap, kinds, cfg)
 		}
 		return fmt.Errorf("expected a struct or pointer to a struct, got %v", val.Kind())
 	}
 
+	// Flat structs need no recursion, so their fields are flattened straight from the keys cached for their type
+	if fields, ok := flatFields(typ, cfg); ok && !cfg.OmitEmpty {
+		for _, f := range fields {
+			flattenLeaf(joinPrefixKey(prefix, f.key), val.Field(f.index), fieldMap, kinds, cfg)
+		}
+		return checkKeyLimit(*fieldMap, cfg)
+	}
+
 	// Iterate over struct fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
+	for _, field := range structInfo(typ, cfg).fields {
+		fieldVal := val.Field(field.index)
 
 		// Skip unexported fields
-		if !field.IsExported() {
+		if !field.exported {
+			continue
+		}
+
+		// Skip ignored fields, matching GenerateFieldNames
+		if field.ignored {
+			continue
+		}
+
+		// Leave out what GenerateFieldNames does under WithExcludeNil and WithOmitEmpty, so keys match its headers
+		if cfg.ExcludeNil && (fieldVal.Kind() == reflect.Ptr || fieldVal.Kind() == reflect.Interface) && fieldVal.IsNil() {
+			continue
+		}
+		if cfg.OmitEmpty && field.omitEmpty && fieldVal.IsZero() {
 			continue
 		}
 
-		keyPrefix := joinPrefixKey(prefix, getMapKey(field))
+		keyPrefix := joinPrefixKey(prefix, field.key)
+
+		// Types such as net.IP or UUIDs are one value, not the slice or array they are made of
+		if text, ok, err := marshalText(fieldVal); err != nil {
+			return fmt.Errorf("%s: %w", keyPrefix, err)
+		} else if ok {
+			(*fieldMap)[keyPrefix] = text
+			continue
+		}
 
 		switch fieldVal.Kind() {
-		case reflect.Slice:
+		case reflect.Slice, reflect.Array:
 			if fieldVal.Len() == 0 {
-				(*fieldMap)[keyPrefix] = "" // Handle empty slice
+				emptySlice(keyPrefix, fieldMap, cfg) // Handle empty slice or zero-length array
 			} else {
-				err := flattenSlice(fieldVal, keyPrefix, fieldMap)
+				err := flattenSlice(fieldVal, keyPrefix, fieldMap, kinds, cfg)
 				if err != nil {
 					return err
 				}
 			}
 		case reflect.Struct:
 			// If the type of the struct itself is time.Time and it's not an embedded field, add it to the map
-			switch {
-			case field.Type.String() == "time.Time" && !field.Anonymous:
+			if field.isTime {
 				(*fieldMap)[keyPrefix] = fmt.Sprint(fieldVal.Interface())
 				continue
 			}
 
 			// Check if the struct should be inlined
-			if shouldInline(field) {
-				err := FlattenNestedStructs(fieldVal.Interface(), prefix, fieldMap)
+			if field.inline {
+				err := flattenNested(fieldVal.Interface(), prefix, fieldMap, kinds, cfg)
 				if err != nil {
 					return err
 				}
 			} else {
 				// Recursively handle nested structs
-				err := FlattenNestedStructs(fieldVal.Interface(), keyPrefix, fieldMap)
+				err := flattenNested(fieldVal.Interface(), keyPrefix, fieldMap, kinds, cfg)
 				if err != nil {
 					return err
 				}
 			}
 		case reflect.Interface:
-			if !fieldVal.IsNil() {
-				elem := fieldVal.Elem()
-				if elem.Kind() == reflect.Struct {
-					err := FlattenNestedStructs(elem.Interface(), prefix, fieldMap)
-					if err != nil {
-						return err
-					}
+			// A nil interface has nothing to flatten, so no column is emitted for it
+			if fieldVal.IsNil() {
+				continue
+			}
+			elem, err := DerefPointers(fieldVal)
+			if err != nil {
+				return err
+			}
+			if text, ok, err := marshalText(elem); err != nil {
+				return fmt.Errorf("%s: %w", keyPrefix, err)
+			} else if ok {
+				(*fieldMap)[keyPrefix] = text
+				continue
+			}
+			switch elem.Kind() {
+			case reflect.Struct:
+				err = flattenNested(elem.Interface(), prefix, fieldMap, kinds, cfg)
+			case reflect.Map, reflect.Slice, reflect.Array:
+				if elem.Len() == 0 && elem.Kind() != reflect.Map {
+					emptySlice(keyPrefix, fieldMap, cfg)
+				} else if elem.Len() == 0 {
+					(*fieldMap)[keyPrefix] = ""
+				} else if elem.Kind() == reflect.Map {
+					err = flattenMap(elem, keyPrefix, fieldMap, kinds, cfg)
+				} else {
+					err = flattenSlice(elem, keyPrefix, fieldMap, kinds, cfg)
 				}
+			case reflect.Invalid:
+				(*fieldMap)[keyPrefix] = "<nil>"
+			default:
+				flattenLeaf(keyPrefix, elem, fieldMap, kinds, cfg)
+			}
+			if err != nil {
+				return err
 			}
 		case reflect.Map:
 			if fieldVal.Len() == 0 {
 				(*fieldMap)[keyPrefix] = ""
 			} else {
-				if shouldInline(field) {
-					err := flattenMap(fieldVal, prefix, fieldMap)
+				if field.inline {
+					err := flattenMap(fieldVal, prefix, fieldMap, kinds, cfg)
 					if err != nil {
 						return err
 					}
 				} else {
-					err := flattenMap(fieldVal, keyPrefix, fieldMap)
+					err := flattenMap(fieldVal, keyPrefix, fieldMap, kinds, cfg)
 					if err != nil {
 						return err
 					}
@@ -629,20 +1700,23 @@ func FlattenNestedStructs(item interface{}, prefix string, fieldMap *map[string]
 			}
 		case reflect.Ptr:
 			if fieldVal.IsNil() {
-				(*fieldMap)[keyPrefix] = "<nil>"
+				// A nil inlined embed has no key of its own; its promoted fields are simply absent, as in encoding/json
+				if !field.inline {
+					(*fieldMap)[keyPrefix] = "<nil>"
+				}
 			} else {
 				underlying := fieldVal.Elem()
 				switch underlying.Kind() {
 				case reflect.Struct:
-					if shouldInline(field) {
-						err = FlattenNestedStructs(underlying.Interface(), prefix, fieldMap)
+					if field.inline {
+						err = flattenNested(underlying.Interface(), prefix, fieldMap, kinds, cfg)
 					} else {
-						err = FlattenNestedStructs(underlying.Interface(), keyPrefix, fieldMap)
+						err = flattenNested(underlying.Interface(), keyPrefix, fieldMap, kinds, cfg)
 					}
 				case reflect.Map, reflect.Slice, reflect.Array:
-					err = FlattenNestedStructs(underlying.Interface(), keyPrefix, fieldMap)
+					err = flattenNested(underlying.Interface(), keyPrefix, fieldMap, kinds, cfg)
 				default:
-					(*fieldMap)[keyPrefix] = fmt.Sprint(underlying.Interface())
+					flattenLeaf(keyPrefix, underlying, fieldMap, kinds, cfg)
 				}
 				if err != nil {
 					return err
@@ -650,14 +1724,53 @@ func FlattenNestedStructs(item interface{}, prefix string, fieldMap *map[string]
 			}
 		default:
 			if fieldVal.IsValid() {
-				(*fieldMap)[keyPrefix] = fmt.Sprint(fieldVal.Interface())
+				flattenLeaf(keyPrefix, fieldVal, fieldMap, kinds, cfg)
 			} else {
 				(*fieldMap)[keyPrefix] = "<nil>"
 			}
 		}
 	}
 
-	return nil
+	return checkKeyLimit(*fieldMap, cfg)
+}
+
+// transformHeaders returns a copy of headers with the configured HeaderTransform applied, or headers itself without one
+func transformHeaders(headers *[]string, cfg *pkgConfig) *[]string {
+	if cfg.HeaderTransform == nil || headers == nil {
+		return headers
+	}
+	out := make([]string, len(*headers))
+	for i, header := range *headers {
+		out[i] = cfg.HeaderTransform(header)
+	}
+	return &out
+}
+
+// transformKeys returns fieldMap re-keyed with the configured HeaderTransform, or fieldMap itself without one
+func transformKeys(fieldMap map[string]string, cfg *pkgConfig) map[string]string {
+	if cfg.HeaderTransform == nil {
+		return fieldMap
+	}
+	out := make(map[string]string, len(fieldMap))
+	for key, value := range fieldMap {
+		out[cfg.HeaderTransform(key)] = value
+	}
+	return out
+}
+
+// prefixHeaders returns a copy of headers with prefix applied to any header not already under it.
+func prefixHeaders(prefix string, headers *[]string) *[]string {
+	if headers == nil {
+		return nil
+	}
+	prefixed := make([]string, 0, len(*headers))
+	for _, header := range *headers {
+		if header != prefix && !strings.HasPrefix(header, prefix+".") {
+			header = joinPrefixKey(prefix, header)
+		}
+		prefixed = append(prefixed, header)
+	}
+	return &prefixed
 }
 
 // joinPrefixKey joins a prefix and key, helping to avoid extra trailing dots.
@@ -676,7 +1789,8 @@ func joinPrefixKey(prefix, key string) string {
 
 /*
 * shouldInline reports whether the field should be embedded, making it appear as if it belongs to the parent struct.
-* It returns true if the field has the "inline" tag.
+* It returns true if the field has the "inline" tag, or is an anonymous embedded struct (or pointer to one) without an
+* explicit JSON name (matching how encoding/json promotes embedded fields).
 
 * Example:
 * Field: profile.customAttributes `json:",inline"`
@@ -687,45 +1801,157 @@ func joinPrefixKey(prefix, key string) string {
 * Field: profile.customAttributes `json:"customAttributes,omitempty"`
 * profile.customAttributes.key1 ==> profile.customAttributes.key1
  */
-func shouldInline(field reflect.StructField) bool {
-	tag := field.Tag.Get("json")
-	return strings.Contains(tag, ",inline")
+func shouldInline(field reflect.StructField, cfg *pkgConfig) bool {
+	tag := fieldTag(field, cfg)
+	if strings.Contains(tag, ",inline") {
+		return true
+	}
+	if !field.Anonymous || getFirstTag(tag) != "" {
+		return false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// isTextMarshaler reports whether t (or a pointer to it) implements encoding.TextMarshaler, and so flattens to a single
+// value. time.Time is excluded, as it keeps its own fmt.Sprint rendering.
+func isTextMarshaler(t reflect.Type) bool {
+	if t == reflect.TypeOf(time.Time{}) || t == reflect.TypeOf(&time.Time{}) {
+		return false
+	}
+	return t.Implements(textMarshalerType) || (t.Kind() != reflect.Ptr && reflect.PointerTo(t).Implements(textMarshalerType))
+}
+
+// marshalText returns the MarshalText form of v when its type is a TextMarshaler; ok is false for other types and nil pointers.
+func marshalText(v reflect.Value) (text string, ok bool, err error) {
+	if !v.IsValid() || !isTextMarshaler(v.Type()) || (v.Kind() == reflect.Ptr && v.IsNil()) || !v.CanInterface() {
+		return "", false, nil
+	}
+	if !v.Type().Implements(textMarshalerType) {
+		// Pointer receiver: marshal from an addressable copy
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return "", false, err
+	}
+	return string(b), true, nil
+}
+
+// emptySlice records an empty slice at key: as the empty indexed columns cfg.SliceColumns expects under it, if any,
+// otherwise as a single empty key
+func emptySlice(key string, fieldMap *map[string]string, cfg *pkgConfig) {
+	filled := false
+	for _, column := range cfg.SliceColumns {
+		if isIndexedColumn(column, key) {
+			(*fieldMap)[column] = ""
+			filled = true
+		}
+	}
+	if !filled {
+		(*fieldMap)[key] = ""
+	}
+}
+
+// isIndexedColumn reports whether column is an element of the slice at key, e.g. "items.00.name" of "items"
+func isIndexedColumn(column, key string) bool {
+	rest, ok := strings.CutPrefix(column, key+".")
+	if !ok {
+		return false
+	}
+	index, _, _ := strings.Cut(rest, ".")
+	if index == "" {
+		return false
+	}
+	for _, ch := range index {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
 }
 
-// flattenSlice flattens a slice field.
-// It computes the index format (with a minimum width of 2 digits) for consistent ordering.
-func flattenSlice(slice reflect.Value, keyPrefix string, fieldMap *map[string]string) error {
-	width := len(strconv.Itoa(slice.Len() - 1))
-	if width < 2 {
-		width = 2
+// flattenSlice flattens a slice or array field.
+// It computes the index format (with a minimum width of 2 digits, or cfg.ZeroPadWidth when set) for consistent ordering.
+func flattenSlice(slice reflect.Value, keyPrefix string, fieldMap *map[string]string, kinds map[string]reflect.Kind, cfg *pkgConfig) error {
+	width := cfg.ZeroPadWidth
+	if width <= 0 {
+		width = len(strconv.Itoa(slice.Len() - 1))
+		if width < 2 {
+			width = 2
+		}
 	}
 	indexFormat := fmt.Sprintf("%%0%dd", width)
 
 	for j := 0; j < slice.Len(); j++ {
 		elem := slice.Index(j)
 		elemKey := joinPrefixKey(keyPrefix, fmt.Sprintf(indexFormat, j))
-		if elem.Kind() == reflect.Struct {
-			// Recursively handle struct elements in a slice
-			err := FlattenNestedStructs(elem.Interface(), elemKey, fieldMap)
-			if err != nil {
-				return err
-			}
-		} else {
-			(*fieldMap)[elemKey] = fmt.Sprint(elem.Interface())
+		if text, ok, err := marshalText(elem); err != nil {
+			return fmt.Errorf("%s: %w", elemKey, err)
+		} else if ok {
+			(*fieldMap)[elemKey] = text
+		} else if err := flattenElem(elem, elemKey, fieldMap, kinds, cfg); err != nil {
+			return err
+		}
+		if err := checkContext(cfg); err != nil {
+			return err
+		}
+		if err := checkKeyLimit(*fieldMap, cfg); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// flattenMap flattens a map field. The keys are sorted to guarantee a deterministic order.
-func flattenMap(m reflect.Value, prefix string, fieldMap *map[string]string) error {
-	keys := m.MapKeys()
-	sort.Slice(keys, func(i, j int) bool {
-		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
-	})
+// flattenElem flattens one slice element under its index key. Structs, maps and nested slices (behind any non-nil
+// pointer) are expanded beneath the index, so every element keeps its position when the map is unflattened.
+func flattenElem(elem reflect.Value, elemKey string, fieldMap *map[string]string, kinds map[string]reflect.Kind, cfg *pkgConfig) error {
+	value := elem
+	for (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && !value.IsNil() {
+		value = value.Elem()
+	}
+	if text, ok, err := marshalText(value); err != nil {
+		return fmt.Errorf("%s: %w", elemKey, err)
+	} else if ok {
+		(*fieldMap)[elemKey] = text
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.Type() == reflect.TypeOf(time.Time{}) {
+			break
+		}
+		return flattenNested(value.Interface(), elemKey, fieldMap, kinds, cfg)
+	case reflect.Map:
+		if value.Len() == 0 {
+			(*fieldMap)[elemKey] = ""
+			return nil
+		}
+		return flattenMap(value, elemKey, fieldMap, kinds, cfg)
+	case reflect.Slice, reflect.Array:
+		if value.Len() == 0 {
+			emptySlice(elemKey, fieldMap, cfg)
+			return nil
+		}
+		return flattenSlice(value, elemKey, fieldMap, kinds, cfg)
+	}
+	flattenLeaf(elemKey, elem, fieldMap, kinds, cfg)
+	return nil
+}
+
+// flattenMap flattens a map field. The keys are sorted (numerically, when they are all integers) to guarantee a deterministic order.
+func flattenMap(m reflect.Value, prefix string, fieldMap *map[string]string, kinds map[string]reflect.Kind, cfg *pkgConfig) error {
+	keys := sortedMapKeys(m)
 	for _, key := range keys {
-		keyStr := fmt.Sprint(key.Interface())
-		newKey := joinPrefixKey(prefix, keyStr)
+		newKey := joinPrefixKey(prefix, mapKeyString(key))
 
 		value := m.MapIndex(key)
 		value, err := DerefPointers(value)
@@ -733,27 +1959,155 @@ func flattenMap(m reflect.Value, prefix string, fieldMap *map[string]string) err
 			return err
 		}
 
+		if text, ok, err := marshalText(value); err != nil {
+			return fmt.Errorf("%s: %w", newKey, err)
+		} else if ok {
+			(*fieldMap)[newKey] = text
+			continue
+		}
+
 		switch value.Kind() {
 		case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
-			err := FlattenNestedStructs(value.Interface(), newKey, fieldMap)
+			err := flattenNested(value.Interface(), newKey, fieldMap, kinds, cfg)
 			if err != nil {
 				return err
 			}
 		default:
-			(*fieldMap)[newKey] = fmt.Sprint(value.Interface())
+			flattenLeaf(newKey, value, fieldMap, kinds, cfg)
+		}
+		if err := checkContext(cfg); err != nil {
+			return err
+		}
+		if err := checkKeyLimit(*fieldMap, cfg); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// mapKeyString formats a map key as a flattened key segment. Integer keys are formatted canonically in base 10
+// (ignoring any String method), and the "." delimiter and "\" escape are backslash-escaped, so a key such as
+// "example.com" stays one segment instead of colliding with a nested "example" → "com" path.
+func mapKeyString(key reflect.Value) string {
+	for key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+
+	var s string
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(key.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s = strconv.FormatUint(key.Uint(), 10)
+	case reflect.String:
+		s = key.String()
+	default:
+		s = fmt.Sprint(key.Interface())
+	}
+	return escapeKey(s)
+}
+
+// sortedMapKeys returns the keys of m in a deterministic order: numerically when every key is an integer
+// (so "2" sorts before "10"), and lexicographically by flattened key otherwise.
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	names := make([]string, len(keys))
+	numbers := make([]int64, len(keys))
+	numeric := true
+	for i, key := range keys {
+		names[i] = mapKeyString(key)
+		if numeric {
+			n, err := strconv.ParseInt(names[i], 10, 64)
+			numbers[i], numeric = n, err == nil
+		}
+	}
+
+	sort.Sort(mapKeySorter{keys: keys, names: names, numbers: numbers, numeric: numeric})
+	return keys
+}
+
+// mapKeySorter sorts map keys alongside their precomputed names and numeric values.
+type mapKeySorter struct {
+	keys    []reflect.Value
+	names   []string
+	numbers []int64
+	numeric bool
+}
+
+func (s mapKeySorter) Len() int { return len(s.keys) }
+
+func (s mapKeySorter) Less(i, j int) bool {
+	if s.numeric {
+		return s.numbers[i] < s.numbers[j]
+	}
+	return s.names[i] < s.names[j]
+}
+
+func (s mapKeySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+	s.numbers[i], s.numbers[j] = s.numbers[j], s.numbers[i]
+}
+
+// escapeKey escapes the "\" escape character and the "." delimiter within a single key segment.
+func escapeKey(s string) string {
+	if !strings.ContainsAny(s, `.\`) {
+		return s
+	}
+	return strings.NewReplacer(`\`, `\\`, ".", `\.`).Replace(s)
+}
+
+// unescapeKey reverses escapeKey.
+func unescapeKey(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// firstSegment returns a flattened key up to its first unescaped ".".
+func firstSegment(key string) string {
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '\\':
+			i++
+		case '.':
+			return key[:i]
+		}
+	}
+	return key
+}
+
 // getFirstTag extracts the first comma-separated part of a tag.
 func getFirstTag(tag string) string {
 	return strings.Split(tag, ",")[0]
 }
 
+// fieldTag returns the tag naming field: cfg.TagName's when set, otherwise json's
+func fieldTag(field reflect.StructField, cfg *pkgConfig) string {
+	if cfg != nil && cfg.TagName != "" {
+		return field.Tag.Get(cfg.TagName)
+	}
+	return field.Tag.Get("json")
+}
+
 // getMapKey determines the key to use based on the field’s tags.
-func getMapKey(field reflect.StructField) string {
+func getMapKey(field reflect.StructField, cfg *pkgConfig) string {
+	if cfg != nil && cfg.TagName != "" {
+		if name := getFirstTag(field.Tag.Get(cfg.TagName)); name != "" && name != "-" {
+			return name
+		}
+		return camelKey(field.Name)
+	}
+
 	jsonTag := getFirstTag(field.Tag.Get("json"))
 	urlTag := getFirstTag(field.Tag.Get("url"))
 	xmlTag := getFirstTag(field.Tag.Get("xml"))
@@ -772,11 +2126,33 @@ func getMapKey(field reflect.StructField) string {
 	return mapKey
 }
 
-// mapToSliceAndUpdateFields converts the internal field map into a 2D slice
-// and updates the headers. It groups keys under each header and sorts them
-// using a custom comparator that is numeric-aware.
-func mapToSliceAndUpdateFields(fieldMap *map[string]string, headers *[]string) ([][]string, error) {
-	var newFields []string
+// orderColumns moves the pairs under each of the order columns to the front, keeping the remaining pairs' relative order.
+func orderColumns(fieldSlice [][]string, order []string) [][]string {
+	if len(order) == 0 {
+		return fieldSlice
+	}
+
+	ordered := make([][]string, 0, len(fieldSlice))
+	used := make([]bool, len(fieldSlice))
+	for _, column := range order {
+		for i, pair := range fieldSlice {
+			if !used[i] && (pair[0] == column || strings.HasPrefix(pair[0], column+".")) {
+				ordered = append(ordered, pair)
+				used[i] = true
+			}
+		}
+	}
+	for i, pair := range fieldSlice {
+		if !used[i] {
+			ordered = append(ordered, pair)
+		}
+	}
+	return ordered
+}
+
+// mapToSlice converts the internal field map into a 2D slice ordered by headers, without modifying them.
+// It groups keys under each header and sorts them using a custom comparator that is numeric-aware.
+func mapToSlice(fieldMap map[string]string, headers []string) [][]string {
 	var fieldSlice [][]string
 
 	// custom sort function for keys within a header group.
@@ -815,9 +2191,9 @@ func mapToSliceAndUpdateFields(fieldMap *map[string]string, headers *[]string) (
 	}
 
 	// Process each header in the order provided.
-	for _, header := range *headers {
+	for _, header := range headers {
 		var group []string
-		for key := range *fieldMap {
+		for key := range fieldMap {
 			if key == header || strings.HasPrefix(key, header+".") {
 				group = append(group, key)
 			}
@@ -825,17 +2201,16 @@ func mapToSliceAndUpdateFields(fieldMap *map[string]string, headers *[]string) (
 		if len(group) > 0 {
 			sortGroupKeys(header, group)
 			for _, key := range group {
-				newFields = append(newFields, key)
-				fieldSlice = append(fieldSlice, []string{key, (*fieldMap)[key]})
+				fieldSlice = append(fieldSlice, []string{key, fieldMap[key]})
 			}
 		}
 	}
 
 	// Process any keys not matched by the provided headers.
 	var leftovers []string
-	for key := range *fieldMap {
+	for key := range fieldMap {
 		found := false
-		for _, header := range *headers {
+		for _, header := range headers {
 			if key == header || strings.HasPrefix(key, header+".") {
 				found = true
 				break
@@ -848,10 +2223,8 @@ func mapToSliceAndUpdateFields(fieldMap *map[string]string, headers *[]string) (
 	}
 	sort.Slice(leftovers, func(i, j int) bool { return leftovers[i] < leftovers[j] })
 	for _, key := range leftovers {
-		newFields = append(newFields, key)
-		fieldSlice = append(fieldSlice, []string{key, (*fieldMap)[key]})
+		fieldSlice = append(fieldSlice, []string{key, fieldMap[key]})
 	}
 
-	*headers = newFields
-	return fieldSlice, nil
+	return fieldSlice
 }
@@ -13,21 +13,26 @@ https://developers.google.com/drive/api/v3/reference/
 package google
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/gemini-oss/rego/pkg/common/requests"
+	ss "github.com/gemini-oss/rego/pkg/common/starstruct"
 )
 
 var (
-	DriveBaseURL     = fmt.Sprintf("%s/drive/v3", BaseURL)         // https://developers.google.com/drive/api/v3/reference/
-	DriveAbout       = fmt.Sprintf("%s/about", DriveBaseURL)       // https://developers.google.com/drive/api/v3/reference/about
-	DriveChanges     = fmt.Sprintf("%s/changes", DriveBaseURL)     // https://developers.google.com/drive/api/v3/reference/changes
-	DriveChannels    = fmt.Sprintf("%s/channels", DriveBaseURL)    // https://developers.google.com/drive/api/v3/reference/channels
-	DriveComments    = fmt.Sprintf("%s/comments", DriveBaseURL)    // https://developers.google.com/drive/api/v3/reference/comments
-	DriveFiles       = fmt.Sprintf("%s/files", DriveBaseURL)       // https://developers.google.com/drive/api/v3/reference/files
-	DrivePermissions = fmt.Sprintf("%s/permissions", DriveBaseURL) // https://developers.google.com/drive/api/v3/reference/permissions
-	DriveReplies     = fmt.Sprintf("%s/replies", DriveBaseURL)     // https://developers.google.com/drive/api/v3/reference/replies
-	DriveRevisions   = fmt.Sprintf("%s/revisions", DriveBaseURL)   // https://developers.google.com/drive/api/v3/reference/revisions
+	DriveBaseURL     = fmt.Sprintf("%s/drive/v3", BaseURL)              // https://developers.google.com/drive/api/v3/reference/
+	DriveAbout       = fmt.Sprintf("%s/about", DriveBaseURL)            // https://developers.google.com/drive/api/v3/reference/about
+	DriveChanges     = fmt.Sprintf("%s/changes", DriveBaseURL)          // https://developers.google.com/drive/api/v3/reference/changes
+	DriveChannels    = fmt.Sprintf("%s/channels", DriveBaseURL)         // https://developers.google.com/drive/api/v3/reference/channels
+	DriveComments    = fmt.Sprintf("%s/comments", DriveBaseURL)         // https://developers.google.com/drive/api/v3/reference/comments
+	DriveFiles       = fmt.Sprintf("%s/files", DriveBaseURL)            // https://developers.google.com/drive/api/v3/reference/files
+	DrivePermissions = fmt.Sprintf("%s/permissions", DriveBaseURL)      // https://developers.google.com/drive/api/v3/reference/permissions
+	DriveReplies     = fmt.Sprintf("%s/replies", DriveBaseURL)          // https://developers.google.com/drive/api/v3/reference/replies
+	DriveRevisions   = fmt.Sprintf("%s/revisions", DriveBaseURL)        // https://developers.google.com/drive/api/v3/reference/revisions
+	DriveUploadFiles = fmt.Sprintf("%s/upload/drive/v3/files", BaseURL) // https://developers.google.com/drive/api/guides/manage-uploads
 )
 
 // DriveClient for chaining methods
@@ -238,6 +243,52 @@ func (c *DriveClient) CopyFileToFolder(file *File, folder *File) error {
 	return nil
 }
 
+/*
+ * # Upload Google Drive File
+ * Creates a file with its metadata and content in a single multipart/related request
+ * upload/drive/v3/files?uploadType=multipart
+ * @param {File} file - The metadata of the file to create (name, parents, mimeType, ...)
+ * @param {string} contentType - The media type of content; defaults to file.MimeType, then application/octet-stream
+ * @param {[]byte} content - The file content
+ * - Set file.MimeType to a Google type (e.g. application/vnd.google-apps.spreadsheet) with a CSV contentType to convert on upload
+ * https://developers.google.com/drive/api/guides/manage-uploads#multipart
+ */
+func (c *DriveClient) UploadFile(file *File, contentType string, content []byte) (*File, error) {
+	if file == nil {
+		file = &File{}
+	}
+	if contentType == "" {
+		contentType = file.MimeType
+	}
+
+	metadata, err := ss.ToMap(file, false)
+	if err != nil {
+		return nil, err
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling file metadata: %w", err)
+	}
+
+	q := DriveFileQuery{
+		UploadType:        "multipart",
+		Fields:            "*",
+		SupportsAllDrives: true,
+	}
+
+	body := requests.Related{
+		{ContentType: fmt.Sprintf("%s; charset=UTF-8", requests.JSON), Body: metadataJSON},
+		{ContentType: contentType, Body: content},
+	}
+
+	uploaded, err := do[*File](c.Client, "POST", DriveUploadFiles, q, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return uploaded, nil
+}
+
 /*
  * # Get File List ("My Drive")
  * drive/v3/files
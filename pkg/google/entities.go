@@ -13,6 +13,7 @@ package google
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/gemini-oss/rego/pkg/common/auth"
 	"github.com/gemini-oss/rego/pkg/common/cache"
@@ -42,6 +43,7 @@ type Client struct {
 	OAuth    *auth.OAuthConfig // OAuth Config
 	JWT      *jwt.Config       // JWT Config
 	HTTP     *requests.Client  // HTTP Client
+	Doer     Doer              // Optional override for HTTP when issuing requests (e.g. a mock in tests)
 	Error    *ErrorResponse    // Error
 	Log      *log.Logger       // Logger
 	Cache    *cache.Cache      // Cache
@@ -538,6 +540,47 @@ type ValueRange struct {
 	Range          string     `json:"range"`          // The range the values cover, in A1 notation
 	MajorDimension string     `json:"majorDimension"` // The major dimension of the values
 	Values         [][]string `json:"values"`         // The data that was read or to be written
+
+	kinds [][]reflect.Kind // Source kind of each cell in Values, set by GenerateValueRange so writes send native numbers and booleans
+}
+
+// UpdateValuesResponse represents the response when updating a range of values in a spreadsheet.
+type UpdateValuesResponse struct {
+	SpreadsheetID  string      `json:"spreadsheetId,omitempty"`  // The spreadsheet the updates were applied to
+	UpdatedRange   string      `json:"updatedRange,omitempty"`   // The range (in A1 notation) that updates were applied to
+	UpdatedRows    int         `json:"updatedRows,omitempty"`    // The number of rows where at least one cell in the row was updated
+	UpdatedColumns int         `json:"updatedColumns,omitempty"` // The number of columns where at least one cell in the column was updated
+	UpdatedCells   int         `json:"updatedCells,omitempty"`   // The number of cells updated
+	UpdatedData    *ValueRange `json:"updatedData,omitempty"`    // The values of the cells after updates were applied, only included if includeValuesInResponse was true
+}
+
+// BatchGetValuesResponse represents the response when retrieving more than one range of values in a spreadsheet.
+type BatchGetValuesResponse struct {
+	SpreadsheetID string        `json:"spreadsheetId,omitempty"` // The ID of the spreadsheet the data was retrieved from
+	ValueRanges   []*ValueRange `json:"valueRanges,omitempty"`   // The requested values, in the same order as the requested ranges
+}
+
+// BatchUpdateValuesRequest is the body of a values:batchUpdate call, writing several ranges at once.
+type BatchUpdateValuesRequest struct {
+	ValueInputOption string        `json:"valueInputOption"` // How the input data should be interpreted: RAW or USER_ENTERED
+	Data             []interface{} `json:"data"`             // The ranges and values to write, each a ValueRange
+}
+
+// BatchUpdateValuesResponse represents the response when updating more than one range of values in a spreadsheet.
+type BatchUpdateValuesResponse struct {
+	SpreadsheetID       string                  `json:"spreadsheetId,omitempty"`       // The spreadsheet the updates were applied to
+	TotalUpdatedRows    int                     `json:"totalUpdatedRows,omitempty"`    // The total number of rows where at least one cell in the row was updated
+	TotalUpdatedColumns int                     `json:"totalUpdatedColumns,omitempty"` // The total number of columns where at least one cell in the column was updated
+	TotalUpdatedCells   int                     `json:"totalUpdatedCells,omitempty"`   // The total number of cells updated
+	TotalUpdatedSheets  int                     `json:"totalUpdatedSheets,omitempty"`  // The total number of sheets where at least one cell in the sheet was updated
+	Responses           []*UpdateValuesResponse `json:"responses,omitempty"`           // One UpdateValuesResponse per requested range, in the same order
+}
+
+// AppendValuesResponse represents the response when appending values to a spreadsheet.
+type AppendValuesResponse struct {
+	SpreadsheetID string                `json:"spreadsheetId,omitempty"` // The spreadsheet the updates were applied to
+	TableRange    string                `json:"tableRange,omitempty"`    // The range (in A1 notation) of the table that values are being appended to
+	Updates       *UpdateValuesResponse `json:"updates,omitempty"`       // Information about the updates that were applied
 }
 
 // DataSource represents a data source in a spreadsheet.
@@ -847,9 +890,15 @@ type CellFormat struct {
 }
 
 type Borders interface{}
-type NumberFormat interface{}
 type Padding interface{}
 
+// NumberFormat represents the number format of a cell.
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/cells#NumberFormat
+type NumberFormat struct {
+	Type    string `json:"type,omitempty"`    // TEXT, NUMBER, PERCENT, CURRENCY, DATE, TIME, DATE_TIME or SCIENTIFIC
+	Pattern string `json:"pattern,omitempty"` // Pattern used for formatting; the locale's default for Type is used when empty
+}
+
 // TextFormat represents the text format
 // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/other#textformat
 type TextFormat struct {
@@ -1065,7 +1114,7 @@ type SheetRequest struct {
 	AddFilterView                interface{}                       `json:"addFilterView,omitempty"`                // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#addfilterviewrequest
 	AddNamedRange                interface{}                       `json:"addNamedRange,omitempty"`                // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#addnamedrangerequest
 	AddProtectedRange            interface{}                       `json:"addProtectedRange,omitempty"`            // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#addprotectedrangerequest
-	AddSheet                     interface{}                       `json:"addSheet,omitempty"`                     // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#addsheetrequest
+	AddSheet                     *AddSheetRequest                  `json:"addSheet,omitempty"`                     // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#addsheetrequest
 	AddSlicer                    interface{}                       `json:"addSlicer,omitempty"`                    // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#addslicerrequest
 	AppendCells                  interface{}                       `json:"appendCells,omitempty"`                  // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#appendcellsrequest
 	AppendDimension              interface{}                       `json:"appendDimension,omitempty"`              // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#appenddimensionrequest
@@ -1106,7 +1155,7 @@ type SheetRequest struct {
 	TrimWhitespace               interface{}                       `json:"trimWhitespace,omitempty"`               // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#trimwhitespacerequest
 	UpdateBanding                interface{}                       `json:"updateBanding,omitempty"`                // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatebandingrequest
 	UpdateBorders                interface{}                       `json:"updateBorders,omitempty"`                // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatebordersrequest
-	UpdateCells                  interface{}                       `json:"updateCells,omitempty"`                  // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatecellsrequest
+	UpdateCells                  *UpdateCellsRequest               `json:"updateCells,omitempty"`                  // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatecellsrequest
 	UpdateChartSpec              interface{}                       `json:"updateChartSpec,omitempty"`              // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatechartspecrequest
 	UpdateConditionalFormatRule  interface{}                       `json:"updateConditionalFormatRule,omitempty"`  // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updateconditionalformatrulerequest
 	UpdateDataSource             interface{}                       `json:"updateDataSource,omitempty"`             // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatedatasourcerequest
@@ -1118,10 +1167,61 @@ type SheetRequest struct {
 	UpdateFilterView             interface{}                       `json:"updateFilterView,omitempty"`             // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatefilterviewrequest
 	UpdateNamedRange             interface{}                       `json:"updateNamedRange,omitempty"`             // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatenamedrangerequest
 	UpdateProtectedRange         interface{}                       `json:"updateProtectedRange,omitempty"`         // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updateprotectedrangerequest
-	UpdateSheetProperties        interface{}                       `json:"updateSheetProperties,omitempty"`        // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatesheetpropertiesrequest
+	UpdateSheetProperties        *UpdateSheetPropertiesRequest     `json:"updateSheetProperties,omitempty"`        // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatesheetpropertiesrequest
 	UpdateSlicerSpec             interface{}                       `json:"updateSlicerSpec,omitempty"`             // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updateslicerspecrequest
 }
 
+// BatchUpdateSpreadsheetResponse is the reply to a batch of updates, holding one reply per request in request order.
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/batchUpdate#response-body
+type BatchUpdateSpreadsheetResponse struct {
+	SpreadsheetID      string           `json:"spreadsheetId,omitempty"`      // The spreadsheet the updates were applied to
+	Replies            []*SheetResponse `json:"replies,omitempty"`            // The reply to each request, in order; requests with nothing to report have an empty reply
+	UpdatedSpreadsheet *Spreadsheet     `json:"updatedSpreadsheet,omitempty"` // The spreadsheet after the updates, if includeSpreadsheetInResponse was true
+}
+
+// SheetResponse represents the reply to a single SheetRequest; only the field matching the request's kind is set.
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#Response
+type SheetResponse struct {
+	AddBanding              interface{}                `json:"addBanding,omitempty"`              // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addbandingresponse
+	AddChart                interface{}                `json:"addChart,omitempty"`                // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addchartresponse
+	AddDimensionGroup       interface{}                `json:"addDimensionGroup,omitempty"`       // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#adddimensiongroupresponse
+	AddFilterView           *AddFilterViewResponse     `json:"addFilterView,omitempty"`           // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addfilterviewresponse
+	AddNamedRange           *AddNamedRangeResponse     `json:"addNamedRange,omitempty"`           // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addnamedrangeresponse
+	AddProtectedRange       *AddProtectedRangeResponse `json:"addProtectedRange,omitempty"`       // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addprotectedrangeresponse
+	AddSheet                *AddSheetResponse          `json:"addSheet,omitempty"`                // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addsheetresponse
+	AddSlicer               interface{}                `json:"addSlicer,omitempty"`               // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addslicerresponse
+	CreateDeveloperMetadata interface{}                `json:"createDeveloperMetadata,omitempty"` // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#createdevelopermetadataresponse
+	DeleteDuplicates        interface{}                `json:"deleteDuplicates,omitempty"`        // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#deleteduplicatesresponse
+	DuplicateFilterView     *AddFilterViewResponse     `json:"duplicateFilterView,omitempty"`     // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#duplicatefilterviewresponse
+	DuplicateSheet          *AddSheetResponse          `json:"duplicateSheet,omitempty"`          // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#duplicatesheetresponse
+	FindReplace             interface{}                `json:"findReplace,omitempty"`             // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#findreplaceresponse
+	TrimWhitespace          interface{}                `json:"trimWhitespace,omitempty"`          // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#trimwhitespaceresponse
+}
+
+// AddSheetResponse holds the properties of a newly added (or duplicated) sheet, including its sheetId
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addsheetresponse
+type AddSheetResponse struct {
+	Properties *SheetProperties `json:"properties,omitempty"` // Properties of the new sheet
+}
+
+// AddNamedRangeResponse holds a newly added named range, including its namedRangeId
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addnamedrangeresponse
+type AddNamedRangeResponse struct {
+	NamedRange *NamedRange `json:"namedRange,omitempty"` // The named range to add
+}
+
+// AddFilterViewResponse holds a newly added (or duplicated) filter view, including its filterViewId
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addfilterviewresponse
+type AddFilterViewResponse struct {
+	Filter *FilterView `json:"filter,omitempty"` // The newly added filter view
+}
+
+// AddProtectedRangeResponse holds a newly added protected range, including its protectedRangeId
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/response#addprotectedrangeresponse
+type AddProtectedRangeResponse struct {
+	ProtectedRange *ProtectedRange `json:"protectedRange,omitempty"` // The newly added protected range
+}
+
 // AutoResizeDimensionsRequest represents a request to auto resize dimensions.
 // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#autoresizedimensionsrequest
 type AutoResizeDimensionsRequest struct {
@@ -1136,6 +1236,12 @@ type DataSourceSheetDimensionRange struct {
 	SheetID          int                         `json:"sheetId,omitempty"`          // SheetID represents the ID of the sheet
 }
 
+// AddSheetRequest adds a new sheet; a client-chosen properties.sheetId must be unique within the spreadsheet
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#addsheetrequest
+type AddSheetRequest struct {
+	Properties *SheetProperties `json:"properties,omitempty"` // The properties the new sheet should have
+}
+
 // SetBasicFilterRequest represents the request to set a basic filter
 // Source: https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#setbasicfilterrequest
 type SetBasicFilterRequest struct {
@@ -1159,6 +1265,21 @@ type UpdateDimensionPropertiesRequest struct {
 	DataSourceSheetRange *DataSourceSheetDimensionRange `json:"dataSourceSheetRange,omitempty"` // Range of the dataSource sheet dimension to update
 }
 
+// UpdateCellsRequest updates all cells in a range with new data; only the fields in the mask are written
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatecellsrequest
+type UpdateCellsRequest struct {
+	Rows   []RowData  `json:"rows,omitempty"`   // The data to write
+	Fields string     `json:"fields,omitempty"` // The fields of CellData that should be updated
+	Range  *GridRange `json:"range,omitempty"`  // The range to write data to
+}
+
+// UpdateSheetPropertiesRequest updates the properties of the sheet matching properties.sheetId
+// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#updatesheetpropertiesrequest
+type UpdateSheetPropertiesRequest struct {
+	Properties *SheetProperties `json:"properties,omitempty"` // The properties to update
+	Fields     string           `json:"fields,omitempty"`     // The fields that should be updated
+}
+
 // END OF SPREADSHEET STRUCTS
 //---------------------------------------------------------------------------------------
 
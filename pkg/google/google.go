@@ -17,6 +17,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -151,7 +152,7 @@ func (c *Client) GenerateJWT(data []byte) (*requests.Client, error) {
 		"Authorization": "Bearer " + t.AccessToken,
 	}
 
-	return requests.NewClient(jwtClient, headers, c.HTTP.RateLimiter), nil
+	return requests.NewClient(jwtClient, headers, c.HTTP.RateLimiter, requests.ErrorParser(ParseError)), nil
 }
 
 func (c *Client) ImpersonateUser(email string) error {
@@ -177,7 +178,7 @@ func (c *Client) ImpersonateUser(email string) error {
 	}
 
 	// Update the HTTP client of the client object
-	c.HTTP = requests.NewClient(jwtClient, headers, nil)
+	c.HTTP = requests.NewClient(jwtClient, headers, nil, requests.ErrorParser(ParseError))
 	c.HTTP.BodyType = requests.JSON
 
 	return nil
@@ -284,7 +285,7 @@ func NewClient(ac AuthCredentials, verbosity int) (*Client, error) {
 		BaseURL: BaseURL,
 		Log:     log,
 		Cache:   cache,
-		HTTP:    requests.NewClient(nil, nil, rl),
+		HTTP:    requests.NewClient(nil, nil, rl, requests.ErrorParser(ParseError)),
 	}
 
 	log.Println("Initializing Google Client")
@@ -410,16 +411,61 @@ type GoogleQuery interface {
 	SetPageToken(string)
 }
 
+/*
+ * # Doer
+ * - Issues a request and returns the response along with its body
+ * - Satisfied by *requests.Client; set `Client.Doer` to substitute canned responses in tests
+ */
+type Doer interface {
+	DoRequest(ctx context.Context, method string, url string, query interface{}, data interface{}) (*http.Response, []byte, error)
+}
+
+// doer returns the injected Doer if one is set, otherwise the HTTP client
+func (c *Client) doer() Doer {
+	if c.Doer != nil {
+		return c.Doer
+	}
+	return c.HTTP
+}
+
+// ParseError is the requests.ErrorParser for Google APIs, surfacing `error.message` from `{"error":{"code","message"}}` bodies.
+// It returns nil for bodies without a message so the default handling applies.
+func ParseError(status int, body []byte) error {
+	var res ErrorResponse
+	if err := json.Unmarshal(body, &res); err != nil || res.Error == nil || res.Error.Message == "" {
+		return nil
+	}
+	if res.Error.Code == 0 {
+		res.Error.Code = status
+	}
+	return res.Error
+}
+
 /*
  * Perform a generic request to the Google API
  */
 func do[T any](c *Client, method string, url string, query any, data any) (T, error) {
+	return doContext[T](context.Background(), c, method, url, query, data)
+}
+
+/*
+ * Perform a generic request to the Google API, bounded by ctx
+ * - No request is issued once ctx is done; ctx.Err() is returned instead
+ */
+func doContext[T any](ctx context.Context, c *Client, method string, url string, query any, data any) (T, error) {
 	var result T
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := ctx.Err(); err != nil {
+		return *new(T), err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	res, body, err := c.HTTP.DoRequest(ctx, method, url, query, data)
+	res, body, err := c.doer().DoRequest(ctx, method, url, query, data)
 	if err != nil {
+		if res == nil {
+			return *new(T), err
+		}
 		if requests.IsNonRetryableCode(res.StatusCode) {
 			var googleError ErrorResponse
 			err = json.Unmarshal(body, &googleError)
@@ -13,10 +13,20 @@ https://developers.google.com/sheets/api/reference/rest
 package google
 
 import (
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gemini-oss/rego/pkg/common/crypt"
 	ss "github.com/gemini-oss/rego/pkg/common/starstruct"
 )
 
@@ -30,9 +40,17 @@ var (
 	SheetValuesAppend      = fmt.Sprintf("%s/%s/values/%s:append", Sheets, "%s", "%s") // https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/append
 )
 
+// Defaults used when a range or sheet name is not provided; override them to change every method's default
+var (
+	DefaultSheetName       = "Sheet1" // Sheet (tab) name used when none is given
+	DefaultColumnSpan      = "A:ZZ"   // Column span read or written when no range is given
+	DefaultStreamBatchSize = 500      // Rows appended per request by SaveChannelToSheet
+)
+
 // SheetsClient for chaining methods
 type SheetsClient struct {
 	*Client
+	ctx context.Context // Bounds every request issued by this client; set with WithContext
 }
 
 // Entry point for sheets-related operations
@@ -50,6 +68,22 @@ func (c *Client) Sheets() *SheetsClient {
 	return sc
 }
 
+// WithContext returns a copy of the client whose requests are bound to ctx, so cancelling ctx aborts any in-flight or pending Sheets calls
+func (c *SheetsClient) WithContext(ctx context.Context) *SheetsClient {
+	return &SheetsClient{
+		Client: c.Client,
+		ctx:    ctx,
+	}
+}
+
+// context returns the client's context, defaulting to context.Background()
+func (c *SheetsClient) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
 /*
  * Query Parameters for Sheet Values
  */
@@ -60,40 +94,535 @@ type SheetValueQuery struct {
 	ValueRenderOption            string   `url:"valueRenderOption,omitempty"`            // https://developers.google.com/sheets/api/reference/rest/v4/ValueRenderOption
 	DateTimeRenderOption         string   `url:"dateTimeRenderOption,omitempty"`         // https://developers.google.com/sheets/api/reference/rest/v4/DateTimeRenderOption
 	ValueInputOption             string   `url:"valueInputOption,omitempty"`             // How the input data should be interpreted. Accepted values are: RAW or USER_ENTERED. The default is USER_ENTERED.
+	InsertDataOption             string   `url:"insertDataOption,omitempty"`             // How the input data should be inserted when appending. Accepted values are: OVERWRITE or INSERT_ROWS. The default is OVERWRITE.
 	IncludeValuesInResponse      bool     `url:"includeValuesInResponse,omitempty"`      // Determines if the update response should include the values of the cells that were updated. By default, responses do not include the updated values. If the range to write was larger than the range actually written, the response includes all values in the requested range (excluding trailing empty rows and columns).
 	ResponseValueRenderOption    string   `url:"responseValueRenderOption,omitempty"`    // Determines how values in the response should be rendered. The default render option is FORMATTED_VALUE.
 	ResponseDateTimeRenderOption string   `url:"responseDateTimeRenderOption,omitempty"` // Determines how dates, times, and durations in the response should be rendered. This is ignored if responseValueRenderOption is FORMATTED_VALUE. The default dateTime render option is SERIAL_NUMBER.
 }
 
+// ### Sheet Save Options
+// ---------------------------------------------------------------------
+var (
+	ErrHeaderMismatch = errors.New("existing header row does not match the generated headers")
+	ErrNoData         = errors.New("no data to save")
+)
+
+type saveConfig struct {
+	Append        bool              // Append rows below existing data instead of replacing the range
+	RewriteHeader bool              // Replace a mismatched header row when appending instead of returning an error
+	DryRun        *SavePlan         // Populated with the planned requests instead of sending them
+	HeaderFormat  *HeaderFormat     // Styling applied to the header row
+	LinkColumns   []string          // Columns whose values are written as HYPERLINK formulas
+	Metadata      map[string]string // Key/value pairs written as a metadata row above the header, or into MetadataTab
+	MetadataTab   string            // Tab that receives Metadata instead of the row above the header
+	Saved         *Spreadsheet      // Populated with the spreadsheet written to, including its ID and URL
+}
+
+// SavePlan describes the requests SaveToSheet would send, as populated by WithDryRun
+type SavePlan struct {
+	SpreadsheetID string             // Target spreadsheet; empty when a new spreadsheet would be created
+	Append        bool               // The values would be appended rather than replacing the range
+	ValueRange    *ValueRange        // Values that would be written
+	Format        *SheetBatchRequest // Header formatting that would be applied (sheet ID 0 is assumed, as the sheet is not looked up)
+	NumberFormat  *SheetBatchRequest // Number formats from `rego:"format=..."` tags that would be applied, if any
+	Metadata      *ValueRange        // Metadata that would be written, when WithMetadata or WithMetadataTab is set
+}
+
+type SaveOption func(*saveConfig)
+
+// WithAppend appends rows below the existing data in the sheet, skipping the header row if the sheet already has a matching one
+func WithAppend() SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.Append = true
+	}
+}
+
+// WithDryRun fills plan with the value range and format requests SaveToSheet would send, without issuing any HTTP calls
+func WithDryRun(plan *SavePlan) SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.DryRun = plan
+	}
+}
+
+// WithSavedSpreadsheet fills dst with the spreadsheet SaveToSheet writes to, so callers get the ID and spreadsheetUrl of one
+// it creates. dst is filled as soon as the spreadsheet is created or looked up, even if a later write fails.
+func WithSavedSpreadsheet(dst *Spreadsheet) SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.Saved = dst
+	}
+}
+
+// WithHeaderFormat styles the header row with format instead of the default green, bold header
+func WithHeaderFormat(format *HeaderFormat) SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.HeaderFormat = format
+	}
+}
+
+// WithLinkColumns writes the values in the named columns as clickable =HYPERLINK() formulas.
+// Fields tagged `rego:"link"` are treated as link columns without this option.
+func WithLinkColumns(columns ...string) SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.LinkColumns = append(cfg.LinkColumns, columns...)
+	}
+}
+
+// WithMetadata writes meta (e.g. export time, source, row count) as a row of key/value cells above the header;
+// the data block, and its header formatting and filter, start on the second row
+func WithMetadata(meta map[string]string) SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.Metadata = meta
+		cfg.MetadataTab = ""
+	}
+}
+
+// WithMetadataTab writes meta as key/value rows into the named tab, creating it if needed, leaving the data tab untouched
+func WithMetadataTab(tab string, meta map[string]string) SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.Metadata = meta
+		cfg.MetadataTab = tab
+	}
+}
+
+// metadataAbove reports whether the metadata row is written above the data block
+func (cfg *saveConfig) metadataAbove() bool {
+	return len(cfg.Metadata) > 0 && cfg.MetadataTab == ""
+}
+
+// metadataRange builds the value range for cfg.Metadata: one row of key/value cells above the header,
+// or one key/value row per entry in the metadata tab. Keys are sorted for a stable layout.
+func (cfg *saveConfig) metadataRange(sheetName string) *ValueRange {
+	keys := make([]string, 0, len(cfg.Metadata))
+	for key := range cfg.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if cfg.MetadataTab == "" {
+		row := make([]string, 0, 2*len(keys))
+		for _, key := range keys {
+			row = append(row, key, cfg.Metadata[key])
+		}
+		return &ValueRange{Range: fmt.Sprintf("%s!1:1", sheetName), MajorDimension: "ROWS", Values: [][]string{row}}
+	}
+
+	vr := &ValueRange{Range: fmt.Sprintf("%s!%s", cfg.MetadataTab, DefaultColumnSpan), MajorDimension: "ROWS"}
+	for _, key := range keys {
+		vr.Values = append(vr.Values, []string{key, cfg.Metadata[key]})
+	}
+	return vr
+}
+
+// rowRange starts a column span such as "A:ZZ" at the given 1-based row, e.g. "A2:ZZ"
+func rowRange(span string, row int) string {
+	start, end, ok := strings.Cut(span, ":")
+	if !ok {
+		return fmt.Sprintf("%s%d", span, row)
+	}
+	return fmt.Sprintf("%s%d:%s", start, row, end)
+}
+
+// WithRewriteHeader replaces the existing header row when appending and it does not match the generated headers
+func WithRewriteHeader() SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.RewriteHeader = true
+	}
+}
+
+// ### Sheet Write Options
+// ---------------------------------------------------------------------
+type writeConfig struct {
+	ResponseValues   *ValueRange // Populated with the values the API stored, via includeValuesInResponse
+	ValueInputOption string      // How input data is interpreted: RAW (default) or USER_ENTERED
+	R1C1             bool        // The ValueRange's Range is in R1C1 notation and is converted to A1 before writing
+	HeaderRow        bool        // The first row is a header, so no row may be wider than it
+	InsertRows       bool        // Appended rows are inserted as new rows rather than overwriting any below the table
+}
+
+type WriteOption func(*writeConfig)
+
+// WithResponseValues sets includeValuesInResponse on the write and fills dst with the returned updatedData, so callers can verify what the API stored
+func WithResponseValues(dst *ValueRange) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.ResponseValues = dst
+	}
+}
+
+// WithValueInputOption sets how the API interprets written values: RAW (the default) stores them as-is,
+// USER_ENTERED parses them as if typed into the UI, so formulas, numbers and dates are converted
+func WithValueInputOption(option string) WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.ValueInputOption = option
+	}
+}
+
+// WithInsertRows makes an append insert new rows for its data (insertDataOption INSERT_ROWS), shifting down anything below the table.
+// Without it, the API's default OVERWRITE writes into the rows after the table.
+func WithInsertRows() WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.InsertRows = true
+	}
+}
+
+// WithHeaderRow marks the ValueRange's first row as its header, so a row wider than the header is rejected before writing.
+// Shorter rows are always allowed, as the API omits trailing empty cells from the values it returns.
+func WithHeaderRow() WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.HeaderRow = true
+	}
+}
+
+// WithR1C1Write takes the ValueRange's Range in absolute R1C1 notation (e.g. "Sheet1!R1C1:R10C4"), converting it to A1 before writing
+func WithR1C1Write() WriteOption {
+	return func(cfg *writeConfig) {
+		cfg.R1C1 = true
+	}
+}
+
+// ### Value Range Options
+// ---------------------------------------------------------------------
+type valueRangeConfig struct {
+	SkipHeader bool // Leave the header row out, so the ValueRange holds only data rows
+}
+
+type ValueRangeOption func(*valueRangeConfig)
+
+// WithoutHeaderRow leaves the header row out of the generated ValueRange, for appending below a sheet that already has one.
+// Headers are still generated (or taken from headers) to order the columns.
+func WithoutHeaderRow() ValueRangeOption {
+	return func(cfg *valueRangeConfig) {
+		cfg.SkipHeader = true
+	}
+}
+
+// ### Sheet Read Options
+// ---------------------------------------------------------------------
+var (
+	ErrDuplicateKey  = errors.New("duplicate key in key column")
+	ErrNotSingleCell = errors.New("range is not a single cell")
+)
+
+type readConfig struct {
+	ErrorOnDuplicate bool // Return an error when a key appears more than once instead of keeping the last row
+	R1C1             bool // The range is in R1C1 notation and is converted to A1 before reading
+}
+
+type ReadOption func(*readConfig)
+
+// WithDuplicateKeyError makes ReadSheetAsMap return ErrDuplicateKey when a key appears more than once, instead of keeping the last row
+func WithDuplicateKeyError() ReadOption {
+	return func(cfg *readConfig) {
+		cfg.ErrorOnDuplicate = true
+	}
+}
+
+// WithR1C1Read takes the range to read in absolute R1C1 notation (e.g. "Sheet1!R1C1:R10C4"), converting it to A1 before reading
+func WithR1C1Read() ReadOption {
+	return func(cfg *readConfig) {
+		cfg.R1C1 = true
+	}
+}
+
+// ### Sheet Notation
+// ---------------------------------------------------------------------
+var (
+	ErrInvalidReference = errors.New("invalid cell reference")
+)
+
+/*
+ * # R1C1 to A1
+ * Converts an R1C1 reference or range to A1 notation, keeping any sheet name: "Sheet1!R1C1:R10C4" -> "Sheet1!A1:D10"
+ * - R2C3 is absolute; R[-1]C[2] is relative, and a bare R or C is the origin's own row or column
+ * - Relative parts are resolved against the 1-based originRow and originCol; pass 0, 0 when only absolute references are expected
+ * - Whole rows and columns convert too: "R2" -> "2:2", "C3:C4" -> "C:D"
+ */
+func R1C1ToA1(ref string, originRow, originCol int) (string, error) {
+	sheet, cells := splitSheet(ref)
+	parts := strings.Split(cells, ":")
+	if len(parts) > 2 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidReference, ref)
+	}
+
+	a1 := make([]string, len(parts))
+	whole := false
+	for i, part := range parts {
+		row, col, err := parseR1C1(part, originRow, originCol)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q: %v", ErrInvalidReference, ref, err)
+		}
+		whole = row == 0 || col == 0
+		if col > 0 {
+			a1[i] = columnName(col - 1)
+		}
+		if row > 0 {
+			a1[i] += strconv.Itoa(row)
+		}
+	}
+
+	// A lone whole row or column is written as a range of itself, as A1 has no single-part form for it
+	if len(a1) == 1 && whole {
+		a1 = append(a1, a1[0])
+	}
+	return sheet + strings.Join(a1, ":"), nil
+}
+
+/*
+ * # A1 to R1C1
+ * Converts an A1 reference or range to absolute R1C1 notation, keeping any sheet name: "Sheet1!A1:D10" -> "Sheet1!R1C1:R10C4"
+ * - `$` anchors are accepted and dropped, as every R1C1 reference produced is absolute
+ * - Whole rows and columns convert too: "2:2" -> "R2:R2", "C:D" -> "C3:C4"
+ */
+func A1ToR1C1(ref string) (string, error) {
+	sheet, cells := splitSheet(ref)
+	parts := strings.Split(cells, ":")
+	if len(parts) > 2 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidReference, ref)
+	}
+
+	r1c1 := make([]string, len(parts))
+	for i, part := range parts {
+		part = strings.ToUpper(strings.ReplaceAll(part, "$", ""))
+		digits := strings.TrimLeft(part, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+		letters := part[:len(part)-len(digits)]
+		if part == "" || strings.Trim(digits, "0123456789") != "" || strings.HasPrefix(digits, "0") {
+			return "", fmt.Errorf("%w: %q", ErrInvalidReference, ref)
+		}
+		if digits != "" {
+			r1c1[i] = "R" + digits
+		}
+		if letters != "" {
+			col := 0
+			for _, ch := range letters {
+				col = col*26 + int(ch-'A') + 1
+			}
+			r1c1[i] += "C" + strconv.Itoa(col)
+		}
+	}
+	return sheet + strings.Join(r1c1, ":"), nil
+}
+
+// splitSheet splits a range into its "Sheet!" prefix (empty when there is none) and its cell references
+func splitSheet(ref string) (string, string) {
+	if i := strings.LastIndex(ref, "!"); i >= 0 {
+		return ref[:i+1], ref[i+1:]
+	}
+	return "", ref
+}
+
+// columnName converts a zero-based column index to its A1 letter name (0 -> A, 26 -> AA)
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// parseR1C1 resolves one R1C1 reference to 1-based row and column numbers, either of which is 0 when absent (a whole column or row)
+func parseR1C1(ref string, originRow, originCol int) (row, col int, err error) {
+	rest := strings.ToUpper(ref)
+	if rest == "" {
+		return 0, 0, fmt.Errorf("empty reference")
+	}
+	if strings.HasPrefix(rest, "R") {
+		if row, rest, err = parseR1C1Index(rest[1:], originRow); err != nil {
+			return 0, 0, err
+		}
+	}
+	if strings.HasPrefix(rest, "C") {
+		if col, rest, err = parseR1C1Index(rest[1:], originCol); err != nil {
+			return 0, 0, err
+		}
+	}
+	if rest != "" || (row == 0 && col == 0) {
+		return 0, 0, fmt.Errorf("unexpected %q", ref)
+	}
+	return row, col, nil
+}
+
+// parseR1C1Index parses the index after an R or C: absolute ("3"), relative to origin ("[-1]"), or the origin itself (nothing)
+func parseR1C1Index(s string, origin int) (int, string, error) {
+	var index int
+	switch {
+	case strings.HasPrefix(s, "["):
+		end := strings.Index(s, "]")
+		if end < 0 {
+			return 0, "", fmt.Errorf("unterminated %q", s)
+		}
+		offset, err := strconv.Atoi(s[1:end])
+		if err != nil {
+			return 0, "", err
+		}
+		index, s = origin+offset, s[end+1:]
+	case s != "" && s[0] >= '0' && s[0] <= '9':
+		digits := strings.TrimLeft(s, "0123456789")
+		n, err := strconv.Atoi(s[:len(s)-len(digits)])
+		if err != nil {
+			return 0, "", err
+		}
+		return n, digits, checkIndex(n)
+	default:
+		index = origin
+	}
+	if origin < 1 {
+		return 0, "", fmt.Errorf("relative reference without an origin")
+	}
+	return index, s, checkIndex(index)
+}
+
+// checkIndex rejects row and column numbers below 1
+func checkIndex(n int) error {
+	if n < 1 {
+		return fmt.Errorf("index %d is out of range", n)
+	}
+	return nil
+}
+
+/*
+ * ValueRangeError describes why a ValueRange failed verification
+ * - Row is the index within ValueRange.Values of the offending row, or -1 when the error is not tied to a row
+ */
+type ValueRangeError struct {
+	Row      int    // Index of the offending row
+	Columns  int    // Number of columns in the offending row
+	Expected int    // Number of columns in the header row
+	Message  string // Description of the problem
+}
+
+func (e *ValueRangeError) Error() string {
+	if e.Row < 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("row %d: %s (got %d columns, header has %d)", e.Row, e.Message, e.Columns, e.Expected)
+}
+
 /*
  * # Set Sheet Value Defaults
  * - Sets default values for ValueRange if they are not defined
+ * - With WithHeaderRow(), verifies no row has more columns than the header row (the first row); shorter rows are valid,
+ *   since the API drops trailing empty cells
  */
-func (c *SheetsClient) VerifySheetValueRange(vr *ValueRange) error {
+func (c *SheetsClient) VerifySheetValueRange(vr *ValueRange, opts ...WriteOption) error {
+	cfg := &writeConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	if vr.Range == "" {
-		vr.Range = "A:Z"
+		vr.Range = DefaultColumnSpan
 	}
 	if vr.MajorDimension == "" {
 		vr.MajorDimension = "ROWS"
 	}
 	if vr.Values == nil {
-		return fmt.Errorf("ValueRange.Values cannot be empty")
+		return &ValueRangeError{Row: -1, Message: "ValueRange.Values cannot be empty"}
+	}
+	if !cfg.HeaderRow || len(vr.Values) < 2 {
+		return nil
+	}
+
+	expected := len(vr.Values[0])
+	for i, row := range vr.Values[1:] {
+		if len(row) > expected {
+			return &ValueRangeError{
+				Row:      i + 1,
+				Columns:  len(row),
+				Expected: expected,
+				Message:  "row is wider than the header row",
+			}
+		}
 	}
 	return nil
 }
 
+/*
+ * # Merge Value Ranges
+ * - Concatenates the rows of each ValueRange in order, e.g. a header block followed by data chunks or batchGet results
+ * - Every range must target the same sheet and major dimension, and no row may be wider than the first row
+ * - Shorter rows, as batchGet returns when trailing cells are empty, are padded with empty cells to the first row's width
+ * - The merged Range is kept when all inputs share it, otherwise it is widened to the sheet name
+ * - nil ValueRanges are skipped
+ */
+func MergeValueRanges(vrs ...*ValueRange) (*ValueRange, error) {
+	merged := &ValueRange{Values: [][]string{}}
+	first := true
+	for _, vr := range vrs {
+		if vr == nil {
+			continue
+		}
+
+		dimension := vr.MajorDimension
+		if dimension == "" {
+			dimension = "ROWS"
+		}
+		if first {
+			merged.Range = vr.Range
+			merged.MajorDimension = dimension
+			first = false
+		} else {
+			if dimension != merged.MajorDimension {
+				return nil, &ValueRangeError{Row: -1, Message: fmt.Sprintf("cannot merge major dimension %s into %s", dimension, merged.MajorDimension)}
+			}
+			if sheetOfRange(vr.Range) != sheetOfRange(merged.Range) {
+				return nil, &ValueRangeError{Row: -1, Message: fmt.Sprintf("cannot merge range %q into %q", vr.Range, merged.Range)}
+			}
+			if vr.Range != merged.Range {
+				merged.Range = sheetOfRange(merged.Range)
+			}
+		}
+
+		for _, row := range vr.Values {
+			if len(merged.Values) > 0 {
+				width := len(merged.Values[0])
+				if len(row) > width {
+					return nil, &ValueRangeError{
+						Row:      len(merged.Values),
+						Columns:  len(row),
+						Expected: width,
+						Message:  "row is wider than the header row",
+					}
+				}
+				if len(row) < width {
+					row = append(row[:len(row):len(row)], make([]string, width-len(row))...)
+				}
+			}
+			merged.Values = append(merged.Values, row)
+		}
+	}
+
+	if first {
+		return nil, ErrNoData
+	}
+	return merged, nil
+}
+
+// sheetOfRange returns the sheet name portion of an A1 range, or the whole range when it has no sheet qualifier
+func sheetOfRange(a1 string) string {
+	if i := strings.LastIndex(a1, "!"); i >= 0 {
+		return a1[:i]
+	}
+	return a1
+}
+
 /*
  * Generate Google Sheets ValueRange from a slice of any structs
+ * - Elements may be structs or pointers to structs; nil elements are skipped rather than written as zero-valued rows
+ * - Use WithoutHeaderRow() to leave the header row out when appending below an existing one
  */
-func (c *SheetsClient) GenerateValueRange(data []any, sheetName string, headers *[]string) *ValueRange {
+func (c *SheetsClient) GenerateValueRange(data []any, sheetName string, headers *[]string, opts ...ValueRangeOption) *ValueRange {
+	cfg := &valueRangeConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	vr := &ValueRange{
 		MajorDimension: "ROWS",
 	}
 
 	if sheetName != "" {
-		vr.Range = fmt.Sprintf("%s!A:ZZ", sheetName)
+		vr.Range = fmt.Sprintf("%s!%s", sheetName, DefaultColumnSpan)
 	} else {
-		vr.Range = "A:ZZ"
+		vr.Range = DefaultColumnSpan
 	}
 
 	// Ensure headers as the first row
@@ -106,6 +635,9 @@ func (c *SheetsClient) GenerateValueRange(data []any, sheetName string, headers
 		genHeaders, err := ss.GenerateFieldNames("", reflect.ValueOf(data))
 		if err != nil {
 			c.Log.Tracef("Failed to generate headers: %v", err)
+			if cfg.SkipHeader {
+				vr.Values = [][]string{}
+			}
 			return vr
 		}
 		headers = genHeaders
@@ -115,17 +647,24 @@ func (c *SheetsClient) GenerateValueRange(data []any, sheetName string, headers
 	finalHeaders := make([]string, len(*headers))
 	copy(finalHeaders, *headers)
 
-	// allRows will hold the flattened data for each row as a map of header->value.
+	// allRows will hold the flattened data for each row as a map of header->value, and allKinds the source kind of each value.
 	allRows := make([]map[string]string, 0, len(data))
+	allKinds := make([]map[string]reflect.Kind, 0, len(data))
 
 	// Process each row in the data slice.
 	for _, d := range data {
+		if v := reflect.ValueOf(d); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+			c.Log.Trace("Skipping nil row")
+			continue
+		}
+
 		var orderedData [][]string
+		var kinds map[string]reflect.Kind
 		var err error
 		if generate {
-			orderedData, err = ss.FlattenStructFields(d, ss.WithGenerate())
+			orderedData, kinds, err = ss.FlattenStructFieldsKinds(d, ss.WithGenerate())
 		} else {
-			orderedData, err = ss.FlattenStructFields(d, ss.WithHeaders(headers))
+			orderedData, kinds, err = ss.FlattenStructFieldsKinds(d, ss.WithHeaders(headers))
 		}
 		if err != nil {
 			c.Log.Tracef("Failed to flatten struct fields: %v", err)
@@ -140,6 +679,7 @@ func (c *SheetsClient) GenerateValueRange(data []any, sheetName string, headers
 			}
 		}
 		allRows = append(allRows, rowMap)
+		allKinds = append(allKinds, kinds)
 
 		// Create a candidate header list from the keys of this row.
 		candidate := make([]string, 0, len(rowMap))
@@ -157,17 +697,93 @@ func (c *SheetsClient) GenerateValueRange(data []any, sheetName string, headers
 	vr.Values[0] = headerRow
 
 	// Each subsequent row: look up each header value (empty string if missing).
-	for _, rowMap := range allRows {
+	vr.kinds = [][]reflect.Kind{make([]reflect.Kind, len(finalHeaders))}
+	for r, rowMap := range allRows {
 		row := make([]string, len(finalHeaders))
+		kinds := make([]reflect.Kind, len(finalHeaders))
 		for i, header := range finalHeaders {
 			row[i] = rowMap[header]
+			kinds[i] = allKinds[r][header]
 		}
 		vr.Values = append(vr.Values, row)
+		vr.kinds = append(vr.kinds, kinds)
+	}
+
+	if cfg.SkipHeader {
+		vr.Values, vr.kinds = vr.Values[1:], vr.kinds[1:]
 	}
 
 	return vr
 }
 
+// typedValueRange is the request body for a ValueRange whose cells carry their native JSON types
+type typedValueRange struct {
+	Range          string          `json:"range"`
+	MajorDimension string          `json:"majorDimension"`
+	Values         [][]interface{} `json:"values"`
+}
+
+// payload returns the body to send for vr: numeric and boolean cells from GenerateValueRange become JSON numbers and
+// booleans, so they sort and calculate in Sheets; any cell whose text no longer parses as its kind stays a string.
+// vr itself is returned when no cell has a native type.
+func (vr *ValueRange) payload() interface{} {
+	if len(vr.kinds) != len(vr.Values) {
+		return vr
+	}
+
+	typed := &typedValueRange{
+		Range:          vr.Range,
+		MajorDimension: vr.MajorDimension,
+		Values:         make([][]interface{}, len(vr.Values)),
+	}
+	converted := false
+	for r, row := range vr.Values {
+		typed.Values[r] = make([]interface{}, len(row))
+		for i, cell := range row {
+			typed.Values[r][i] = cell
+			if i < len(vr.kinds[r]) {
+				if v, ok := typedCell(cell, vr.kinds[r][i]); ok {
+					typed.Values[r][i] = v
+					converted = true
+				}
+			}
+		}
+	}
+
+	if !converted {
+		return vr
+	}
+	return typed
+}
+
+// maxExactInt is the largest integer magnitude Sheets, which stores numbers as float64, holds without rounding
+const maxExactInt = 1 << 53
+
+// typedCell parses a flattened cell back to its source kind
+func typedCell(cell string, kind reflect.Kind) (interface{}, bool) {
+	switch kind {
+	case reflect.Bool:
+		if v, err := strconv.ParseBool(cell); err == nil {
+			return v, true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Larger integers would be rounded as numbers, so they stay strings
+		if v, err := strconv.ParseInt(cell, 10, 64); err == nil && v >= -maxExactInt && v <= maxExactInt {
+			return v, true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, err := strconv.ParseUint(cell, 10, 64); err == nil && v <= maxExactInt {
+			return v, true
+		}
+	case reflect.Float32, reflect.Float64:
+		// NaN and ±Inf have no JSON representation, so they stay strings
+		if v, err := strconv.ParseFloat(cell, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
 /*
  * # Spreadsheet: Create
  * - Creates a new spreadsheet, with basic properties.
@@ -176,7 +792,7 @@ func (c *SheetsClient) GenerateValueRange(data []any, sheetName string, headers
 func (c *SheetsClient) CreateSpreadsheet(s *Spreadsheet) (*Spreadsheet, error) {
 	url := Sheets
 
-	spreadsheet, err := do[Spreadsheet](c.Client, "POST", url, nil, s)
+	spreadsheet, err := doContext[Spreadsheet](c.context(), c.Client, "POST", url, nil, s)
 	if err != nil {
 		return nil, err
 	}
@@ -185,206 +801,1426 @@ func (c *SheetsClient) CreateSpreadsheet(s *Spreadsheet) (*Spreadsheet, error) {
 }
 
 /*
- * # Spreadsheet Values: Update
- * Sets/Replaces values in a range of a spreadsheet. The caller must specify the spreadsheet ID, range, and a valueInputOption
- * spreadsheets/{spreadsheetId}/values/{range}
- * https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/update
+ * # Spreadsheet: Batch Update
+ * Applies a batch of requests to a spreadsheet atomically, returning the API's reply to each request in order
+ * - Replies carry the IDs of created objects, e.g. Replies[i].AddSheet.Properties.SheetID for an addSheet request
+ * spreadsheets/{spreadsheetId}:batchUpdate
+ * https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/batchUpdate
  */
-func (c *SheetsClient) UpdateSpreadsheet(spreadsheetID string, vr *ValueRange) error {
-
-	q := SheetValueQuery{
-		ValueInputOption: "RAW",
-	}
-
-	// Check Value paramters
-	err := c.VerifySheetValueRange(vr)
-	if err != nil {
-		return err
-	}
-
-	url := fmt.Sprintf("%s/%s/values/%s", Sheets, spreadsheetID, vr.Range)
+func (c *SheetsClient) BatchUpdateSpreadsheet(spreadsheetID string, req *SheetBatchRequest) (*BatchUpdateSpreadsheetResponse, error) {
+	url := fmt.Sprintf("%s/%s:batchUpdate", Sheets, spreadsheetID)
 
-	_, err = do[any](c.Client, "PUT", url, q, &vr)
+	res, err := doContext[BatchUpdateSpreadsheetResponse](c.context(), c.Client, "POST", url, nil, req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &res, nil
 }
 
 /*
- * # Spreadsheet Values: Append
- * - Appends values within the range of a spreadsheet. The caller must specify the spreadsheet ID, range, and a valueInputOption
- *   - https://sheets.googleapis.com/v4/spreadsheets/{spreadsheetId}/values/{range}
- *   - https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/update
+ * # Sheet: Add
+ * Adds a sheet (tab) to a spreadsheet, and is safe to retry
+ * - Skips the request if a sheet with the same title already exists
+ * - Sends a client-generated sheetId, so a retry of a request that was already applied is rejected instead of adding a
+ *   duplicate tab; the sheet is then read back by that ID
+ *   - https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets/request#addsheetrequest
+ *
+ * The Sheets API has no idempotency keys:
+ * - Naturally idempotent: values.update, and formatting requests (repeatCell, setBasicFilter, autoResizeDimensions, updateSheetProperties)
+ * - Not idempotent: values.append, addSheet, insertDimension, appendCells, duplicateSheet; these need a client-chosen ID or a read-back check
  */
-func (c *SheetsClient) AppendSpreadsheet(spreadsheetID string, vr *ValueRange) error {
-
-	q := SheetValueQuery{
-		ValueInputOption: "RAW",
+func (c *SheetsClient) AddSheet(spreadsheetID, title string) (*SheetProperties, error) {
+	existing, err := c.findSheet(spreadsheetID, func(p *SheetProperties) bool { return p.Title == title })
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		c.Log.Debugf("Sheet %q already exists, skipping add.", title)
+		return existing, nil
 	}
 
-	// Check Value paramters
-	err := c.VerifySheetValueRange(vr)
+	id, err := crypt.SecureRandomInt(math.MaxInt32)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	properties := &SheetProperties{
+		SheetID: id + 1, // Avoid 0, which is omitted from the request and lets the API choose
+		Title:   title,
 	}
 
-	url := fmt.Sprintf("%s/%s/values/%s:append", Sheets, spreadsheetID, vr.Range)
+	add := &SheetBatchRequest{
+		Requests: []*SheetRequest{
+			{AddSheet: &AddSheetRequest{Properties: properties}},
+		},
+	}
 
-	_, err = do[any](c.Client, "POST", url, q, &vr)
+	res, err := c.BatchUpdateSpreadsheet(spreadsheetID, add)
 	if err != nil {
-		return err
+		// A retried request may have been applied before it failed; the client-chosen ID tells us whether it was
+		added, findErr := c.findSheet(spreadsheetID, func(p *SheetProperties) bool { return p.SheetID == properties.SheetID })
+		if findErr == nil && added != nil {
+			return added, nil
+		}
+		return nil, err
 	}
 
-	return nil
+	// The reply carries the properties the API filled in, such as the grid size
+	if len(res.Replies) > 0 && res.Replies[0].AddSheet != nil && res.Replies[0].AddSheet.Properties != nil {
+		return res.Replies[0].AddSheet.Properties, nil
+	}
+	return properties, nil
 }
 
 /*
- * # Format Header and AutoSize
- * - Sets the header row to bold and green, and auto-sizes all columns
+ * # Sheet: List
+ * Returns a map of sheet (tab) title -> sheetId for a spreadsheet, for building batchUpdate ranges
+ * - Titles are unique within a spreadsheet; should the API ever return duplicates, the first sheet wins
  */
-func (c *SheetsClient) FormatHeaderAndAutoSize(spreadsheetID string, sheet *Sheet, rows, columns int) error {
-	url := fmt.Sprintf("%s/%s:batchUpdate", Sheets, spreadsheetID)
+func (c *SheetsClient) ListSheets(spreadsheetID string) (map[string]int64, error) {
+	spreadsheet, err := c.GetSpreadsheet(spreadsheetID)
+	if err != nil {
+		return nil, err
+	}
 
-	format := &SheetBatchRequest{}
+	sheets := make(map[string]int64, len(spreadsheet.Sheets))
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties == nil {
+			continue
+		}
+		if _, ok := sheets[sheet.Properties.Title]; ok {
+			continue
+		}
+		sheets[sheet.Properties.Title] = int64(sheet.Properties.SheetID)
+	}
+	return sheets, nil
+}
 
-	// Set the header row to bold and green
-	format.Requests = append(format.Requests, &SheetRequest{
-		RepeatCell: &RepeatCellRequest{
-			Range: &GridRange{
-				SheetID:          sheet.Properties.SheetID,
-				StartRowIndex:    0,
-				EndRowIndex:      1,
-				StartColumnIndex: 0,
-				EndColumnIndex:   columns,
-			},
-			Cell: &CellData{
-				UserEnteredFormat: &CellFormat{
-					BackgroundColor: &Color{
-						Alpha: 1.0,
-						Red:   (182.0 / 255.0),
-						Green: (215.0 / 255.0),
-						Blue:  (168.0 / 255.0),
-					},
-					TextFormat: &TextFormat{
-						FontSize: 10,
-						Bold:     true,
+// findSheet returns the properties of the first sheet in the spreadsheet matching match, or nil if there is none
+func (c *SheetsClient) findSheet(spreadsheetID string, match func(*SheetProperties) bool) (*SheetProperties, error) {
+	spreadsheet, err := c.GetSpreadsheet(spreadsheetID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties != nil && match(sheet.Properties) {
+			return sheet.Properties, nil
+		}
+	}
+	return nil, nil
+}
+
+/*
+ * # Spreadsheet Values: Update
+ * Sets/Replaces values in a range of a spreadsheet. The caller must specify the spreadsheet ID, range, and a valueInputOption
+ * spreadsheets/{spreadsheetId}/values/{range}
+ * https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/update
+ */
+func (c *SheetsClient) UpdateSpreadsheet(spreadsheetID string, vr *ValueRange, opts ...WriteOption) error {
+	cfg := &writeConfig{ValueInputOption: "RAW"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	q := SheetValueQuery{
+		ValueInputOption:        cfg.ValueInputOption,
+		IncludeValuesInResponse: cfg.ResponseValues != nil,
+	}
+
+	vr, err := r1c1ValueRange(vr, cfg.R1C1)
+	if err != nil {
+		return err
+	}
+
+	// Check Value paramters
+	err = c.VerifySheetValueRange(vr, opts...)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s/values/%s", Sheets, spreadsheetID, vr.Range)
+
+	res, err := doContext[UpdateValuesResponse](c.context(), c.Client, "PUT", url, q, vr.payload())
+	if err != nil {
+		return err
+	}
+
+	if cfg.ResponseValues != nil && res.UpdatedData != nil {
+		*cfg.ResponseValues = *res.UpdatedData
+	}
+
+	return nil
+}
+
+/*
+ * # Spreadsheet Values: Batch Update
+ * - Writes several (possibly disjoint) ranges in a single values:batchUpdate call instead of one UpdateSpreadsheet call per range
+ * - Every range is verified as UpdateSpreadsheet would verify it; the first invalid range is reported by index without sending anything
+ * - valueInputOption defaults to RAW when empty
+ * https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/batchUpdate
+ */
+func (c *SheetsClient) BatchUpdateValues(sheetID string, ranges []*ValueRange, valueInputOption string) (*BatchUpdateValuesResponse, error) {
+	if len(ranges) == 0 {
+		return nil, ErrNoData
+	}
+	if valueInputOption == "" {
+		valueInputOption = "RAW"
+	}
+
+	req := &BatchUpdateValuesRequest{
+		ValueInputOption: valueInputOption,
+		Data:             make([]interface{}, 0, len(ranges)),
+	}
+	for i, vr := range ranges {
+		if vr == nil {
+			return nil, fmt.Errorf("range %d: ValueRange cannot be nil", i)
+		}
+		if err := c.VerifySheetValueRange(vr); err != nil {
+			return nil, fmt.Errorf("range %d (%s): %w", i, vr.Range, err)
+		}
+		req.Data = append(req.Data, vr.payload())
+	}
+
+	url := fmt.Sprintf(SheetValuesBatchUpdate, sheetID)
+
+	res, err := doContext[BatchUpdateValuesResponse](c.context(), c.Client, "POST", url, nil, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+ * # Spreadsheet Values: Append
+ * - Appends values within the range of a spreadsheet. The caller must specify the spreadsheet ID, range, and a valueInputOption
+ *   - https://sheets.googleapis.com/v4/spreadsheets/{spreadsheetId}/values/{range}
+ *   - https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/update
+ */
+func (c *SheetsClient) AppendSpreadsheet(spreadsheetID string, vr *ValueRange, opts ...WriteOption) error {
+	cfg := &writeConfig{ValueInputOption: "RAW"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	q := SheetValueQuery{
+		ValueInputOption:        cfg.ValueInputOption,
+		IncludeValuesInResponse: cfg.ResponseValues != nil,
+	}
+	if cfg.InsertRows {
+		q.InsertDataOption = "INSERT_ROWS"
+	}
+
+	vr, err := r1c1ValueRange(vr, cfg.R1C1)
+	if err != nil {
+		return err
+	}
+
+	// Check Value paramters
+	err = c.VerifySheetValueRange(vr, opts...)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s/values/%s:append", Sheets, spreadsheetID, vr.Range)
+
+	res, err := doContext[AppendValuesResponse](c.context(), c.Client, "POST", url, q, vr.payload())
+	if err != nil {
+		return err
+	}
+
+	if cfg.ResponseValues != nil && res.Updates != nil && res.Updates.UpdatedData != nil {
+		*cfg.ResponseValues = *res.Updates.UpdatedData
+	}
+
+	return nil
+}
+
+// r1c1ValueRange returns a copy of vr with its R1C1 Range converted to A1 when r1c1 is set, leaving the caller's range untouched
+func r1c1ValueRange(vr *ValueRange, r1c1 bool) (*ValueRange, error) {
+	if !r1c1 || vr == nil || vr.Range == "" {
+		return vr, nil
+	}
+	a1, err := R1C1ToA1(vr.Range, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	converted := *vr
+	converted.Range = a1
+	return &converted, nil
+}
+
+// ### Sheet Colors
+// ---------------------------------------------------------------------
+
+// RGB returns an opaque Color from 0-255 channel values, converting them to the 0-1 floats the API expects
+func RGB(r, g, b uint8) *Color {
+	return &Color{
+		Alpha: 1.0,
+		Red:   float64(r) / 255.0,
+		Green: float64(g) / 255.0,
+		Blue:  float64(b) / 255.0,
+	}
+}
+
+// PaletteColor is a named 0xRRGGBB color; call Color() for a fresh *Color to use in a format
+type PaletteColor uint32
+
+const (
+	ColorBlack      PaletteColor = 0x000000
+	ColorWhite      PaletteColor = 0xFFFFFF
+	ColorLightGray  PaletteColor = 0xD9D9D9
+	ColorLightGreen PaletteColor = 0xB6D7A8 // Default header background
+	ColorLightBlue  PaletteColor = 0xA4C2F4
+	ColorLightRed   PaletteColor = 0xEA9999
+	ColorYellow     PaletteColor = 0xFFE599
+)
+
+// Color returns the palette color as an opaque *Color
+func (p PaletteColor) Color() *Color {
+	return RGB(uint8(p>>16), uint8(p>>8), uint8(p))
+}
+
+// HeaderFormat customizes the header row styling applied by FormatHeaderAndAutoSize
+type HeaderFormat struct {
+	BackgroundColor *Color            // Background color of the header row
+	TextColor       *Color            // Text color of the header row; the sheet default when nil
+	FontSize        int               // Font size in points
+	Bold            bool              // Bold header text
+	Italic          bool              // Italic header text
+	Notes           map[string]string // Notes attached to header cells, keyed by header name (e.g. field descriptions)
+}
+
+// DefaultHeaderFormat returns the default header styling: a green background with bold 10pt text
+func DefaultHeaderFormat() *HeaderFormat {
+	return &HeaderFormat{
+		BackgroundColor: ColorLightGreen.Color(),
+		FontSize:        10,
+		Bold:            true,
+	}
+}
+
+/*
+ * # Format Header and AutoSize
+ * - Sets the header row to bold and green (or the given HeaderFormat), and auto-sizes all columns
+ * - When HeaderFormat.Notes is set, the header row is read to place each note on its column
+ */
+func (c *SheetsClient) FormatHeaderAndAutoSize(spreadsheetID string, sheet *Sheet, rows, columns int, headerFormat ...*HeaderFormat) error {
+	hf := DefaultHeaderFormat()
+	if len(headerFormat) > 0 && headerFormat[0] != nil {
+		hf = headerFormat[0]
+	}
+
+	var header []string
+	if len(hf.Notes) > 0 {
+		headers, err := c.HeadersFromSheet(spreadsheetID, fmt.Sprintf("%s!1:1", sheet.Properties.Title))
+		if err != nil {
+			return err
+		}
+		if headers != nil {
+			header = *headers
+		}
+	}
+
+	return c.formatHeader(spreadsheetID, sheet.Properties.SheetID, 0, rows, columns, header, hf, sheet.Properties.GridProperties)
+}
+
+// formatHeader sends the header formatting batchUpdate for a data block starting at startRow, including notes for the named header columns;
+// ranges are checked against grid when it is known
+func (c *SheetsClient) formatHeader(spreadsheetID string, sheetID, startRow, rows, columns int, header []string, hf *HeaderFormat, grid *GridProperties) error {
+	return headerFormatRequests(sheetID, startRow, rows, columns, header, hf, grid).Execute(c, spreadsheetID)
+}
+
+/*
+ * # Auto Resize Columns
+ * - Auto-sizes the columns in [startCol, endCol) to fit their contents, without changing any formatting
+ */
+func (c *SheetsClient) AutoResizeColumns(spreadsheetID string, sheetID int64, startCol, endCol int) error {
+	return NewBatchUpdate(int(sheetID)).
+		AutoResize(&DimensionRange{Dimension: "COLUMNS", StartIndex: startCol, EndIndex: endCol}).
+		Execute(c, spreadsheetID)
+}
+
+/*
+ * # Set Grid Properties
+ * - Sets the frozen row and column counts of a sheet, and whether its gridlines are hidden
+ */
+func (c *SheetsClient) SetGridProperties(spreadsheetID string, sheetID int64, frozenRows, frozenCols int, hideGridlines bool) error {
+	update := &SheetBatchRequest{
+		Requests: []*SheetRequest{
+			{
+				UpdateSheetProperties: &UpdateSheetPropertiesRequest{
+					Properties: &SheetProperties{
+						SheetID: int(sheetID),
+						GridProperties: &GridProperties{
+							FrozenRowCount:    frozenRows,
+							FrozenColumnCount: frozenCols,
+							HideGridlines:     hideGridlines,
+						},
 					},
+					Fields: "gridProperties(frozenRowCount,frozenColumnCount,hideGridlines)",
 				},
 			},
-			Fields: "userEnteredFormat(backgroundColor,textFormat)",
+		},
+	}
+
+	_, err := c.BatchUpdateSpreadsheet(spreadsheetID, update)
+	return err
+}
+
+// headerFormatRequests builds the batchUpdate requests used by FormatHeaderAndAutoSize for a data block whose header
+// is on the zero-based startRow; header names place hf.Notes, and a non-nil grid bounds every range
+func headerFormatRequests(sheetID, startRow, rows, columns int, header []string, hf *HeaderFormat, grid *GridProperties) *BatchUpdate {
+	b := NewBatchUpdate(sheetID).WithinGrid(grid)
+
+	// Style the header row
+	b.RepeatCellFormat(&GridRange{StartRowIndex: startRow, EndRowIndex: startRow + 1, EndColumnIndex: columns}, &CellFormat{
+		BackgroundColor: hf.BackgroundColor,
+		TextFormat: &TextFormat{
+			ForegroundColor: hf.TextColor,
+			FontSize:        hf.FontSize,
+			Bold:            hf.Bold,
+			Italic:          hf.Italic,
+		},
+	})
+
+	// Add a filter view for the header row
+	b.BasicFilter(&GridRange{StartRowIndex: startRow, EndRowIndex: startRow + rows, EndColumnIndex: columns})
+
+	// Attach notes to the named header cells
+	for i, name := range header {
+		if note, ok := hf.Notes[name]; ok {
+			b.Note(startRow, i, note)
+		}
+	}
+
+	// Auto resize all columns
+	b.AutoResize(&DimensionRange{Dimension: "COLUMNS", StartIndex: 0, EndIndex: columns})
+
+	return b
+}
+
+// ### Sheet Batch Update Builder
+// ---------------------------------------------------------------------
+
+/*
+ * # Batch Update Builder
+ * - Accumulates batchUpdate requests for one sheet, setting each request's sheetId and fields mask
+ * - Ranges passed to the builder are copied, so callers can reuse them
+ * - Ranges are validated as they are added; Execute sends nothing if any was invalid
+ * - WithinGrid also checks later ranges against the sheet's known row and column counts
+ *
+ *	err := NewBatchUpdate(sheetID).FreezeRows(1).AutoResize(&DimensionRange{Dimension: "COLUMNS", EndIndex: 5}).Execute(c, spreadsheetID)
+ */
+type BatchUpdate struct {
+	sheetID  int
+	requests []*SheetRequest
+	grid     *GridProperties // Sheet dimensions ranges are checked against, when known
+	err      error           // First invalid range added, reported by Err and Execute
+}
+
+// NewBatchUpdate starts a batchUpdate for the sheet with the given sheetId
+func NewBatchUpdate(sheetID int) *BatchUpdate {
+	return &BatchUpdate{sheetID: sheetID}
+}
+
+// WithinGrid checks ranges added after it against grid's row and column counts; a nil grid skips the check
+func (b *BatchUpdate) WithinGrid(grid *GridProperties) *BatchUpdate {
+	b.grid = grid
+	return b
+}
+
+// RepeatCellFormat applies format to every cell in r; the fields mask covers exactly the fields set in format
+func (b *BatchUpdate) RepeatCellFormat(r *GridRange, format *CellFormat) *BatchUpdate {
+	b.requests = append(b.requests, &SheetRequest{
+		RepeatCell: &RepeatCellRequest{
+			Range:  b.gridRange(r),
+			Cell:   &CellData{UserEnteredFormat: format},
+			Fields: fieldsMask("userEnteredFormat", format),
+		},
+	})
+	return b
+}
+
+// AutoResize fits the rows or columns in r to their contents
+func (b *BatchUpdate) AutoResize(r *DimensionRange) *BatchUpdate {
+	dr := *r
+	dr.SheetID = b.sheetID
+	b.check(dr.Validate())
+	b.check(dr.within(b.grid))
+	b.requests = append(b.requests, &SheetRequest{
+		AutoResizeDimensions: &AutoResizeDimensionsRequest{
+			Dimensions: &dr,
+		},
+	})
+	return b
+}
+
+// BasicFilter sets the sheet's basic filter to r
+func (b *BatchUpdate) BasicFilter(r *GridRange) *BatchUpdate {
+	b.requests = append(b.requests, &SheetRequest{
+		SetBasicFilter: &SetBasicFilterRequest{
+			Filter: &BasicFilter{
+				Range: b.gridRange(r),
+			},
+		},
+	})
+	return b
+}
+
+// FreezeRows freezes the first n rows of the sheet
+func (b *BatchUpdate) FreezeRows(n int) *BatchUpdate {
+	return b.gridProperties(&GridProperties{FrozenRowCount: n}, "gridProperties.frozenRowCount")
+}
+
+// FreezeColumns freezes the first n columns of the sheet
+func (b *BatchUpdate) FreezeColumns(n int) *BatchUpdate {
+	return b.gridProperties(&GridProperties{FrozenColumnCount: n}, "gridProperties.frozenColumnCount")
+}
+
+// Note sets the note on the cell at the zero-based row and column
+func (b *BatchUpdate) Note(row, col int, note string) *BatchUpdate {
+	b.requests = append(b.requests, &SheetRequest{
+		UpdateCells: &UpdateCellsRequest{
+			Rows: []RowData{{Values: []CellData{{Note: note}}}},
+			Range: b.gridRange(&GridRange{
+				StartRowIndex:    row,
+				EndRowIndex:      row + 1,
+				StartColumnIndex: col,
+				EndColumnIndex:   col + 1,
+			}),
+			Fields: "note",
+		},
+	})
+	return b
+}
+
+// Request returns the accumulated requests as a SheetBatchRequest
+func (b *BatchUpdate) Request() *SheetBatchRequest {
+	return &SheetBatchRequest{Requests: b.requests}
+}
+
+// Err returns the first validation error from the ranges added to the builder
+func (b *BatchUpdate) Err() error {
+	return b.err
+}
+
+// Execute sends the accumulated requests to the spreadsheet in a single batchUpdate
+func (b *BatchUpdate) Execute(c *SheetsClient, spreadsheetID string) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	_, err := c.BatchUpdateSpreadsheet(spreadsheetID, b.Request())
+	return err
+}
+
+func (b *BatchUpdate) gridProperties(gp *GridProperties, fields string) *BatchUpdate {
+	b.requests = append(b.requests, &SheetRequest{
+		UpdateSheetProperties: &UpdateSheetPropertiesRequest{
+			Properties: &SheetProperties{
+				SheetID:        b.sheetID,
+				GridProperties: gp,
+			},
+			Fields: fields,
 		},
 	})
+	return b
+}
+
+// gridRange returns a copy of r targeting the builder's sheet
+func (b *BatchUpdate) gridRange(r *GridRange) *GridRange {
+	gr := *r
+	gr.SheetID = b.sheetID
+	b.check(gr.Validate(b.grid))
+	return &gr
+}
+
+// check records err if it is the builder's first error
+func (b *BatchUpdate) check(err error) {
+	if b.err == nil && err != nil {
+		b.err = err
+	}
+}
+
+// ### Sheet Range Validation
+// ---------------------------------------------------------------------
+var (
+	ErrInvalidRange = errors.New("invalid range")
+)
+
+/*
+ * # Validate GridRange
+ * - Indexes must be non-negative and each end must be greater than its start (the range is half-open)
+ * - When grid is given, the range must also fit within its row and column counts
+ */
+func (r *GridRange) Validate(grid *GridProperties) error {
+	switch {
+	case r.StartRowIndex < 0 || r.StartColumnIndex < 0:
+		return fmt.Errorf("%w: negative start index (rows %d, columns %d)", ErrInvalidRange, r.StartRowIndex, r.StartColumnIndex)
+	case r.EndRowIndex <= r.StartRowIndex:
+		return fmt.Errorf("%w: rows [%d, %d) are empty or inverted", ErrInvalidRange, r.StartRowIndex, r.EndRowIndex)
+	case r.EndColumnIndex <= r.StartColumnIndex:
+		return fmt.Errorf("%w: columns [%d, %d) are empty or inverted", ErrInvalidRange, r.StartColumnIndex, r.EndColumnIndex)
+	}
+
+	if grid != nil {
+		if grid.RowCount > 0 && r.EndRowIndex > grid.RowCount {
+			return fmt.Errorf("%w: row %d is beyond the sheet's %d rows", ErrInvalidRange, r.EndRowIndex, grid.RowCount)
+		}
+		if grid.ColumnCount > 0 && r.EndColumnIndex > grid.ColumnCount {
+			return fmt.Errorf("%w: column %d is beyond the sheet's %d columns", ErrInvalidRange, r.EndColumnIndex, grid.ColumnCount)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the dimension range is non-negative and non-empty
+func (r *DimensionRange) Validate() error {
+	if r.StartIndex < 0 || r.EndIndex <= r.StartIndex {
+		return fmt.Errorf("%w: %s [%d, %d) are empty, inverted or negative", ErrInvalidRange, strings.ToLower(r.Dimension), r.StartIndex, r.EndIndex)
+	}
+	return nil
+}
+
+// within checks that the dimension range ends inside grid's row or column count; a nil grid always passes
+func (r *DimensionRange) within(grid *GridProperties) error {
+	if grid == nil {
+		return nil
+	}
+
+	count := grid.RowCount
+	if r.Dimension == "COLUMNS" {
+		count = grid.ColumnCount
+	}
+	if count > 0 && r.EndIndex > count {
+		return fmt.Errorf("%w: %s %d is beyond the sheet's %d", ErrInvalidRange, strings.ToLower(r.Dimension), r.EndIndex, count)
+	}
+	return nil
+}
+
+// fieldsMask returns root(field,...) listing the JSON names of v's non-zero fields, or root alone when none are set
+func fieldsMask(root string, v interface{}) string {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if !val.IsValid() || val.Kind() != reflect.Struct {
+		return root
+	}
+
+	var fields []string
+	for i := 0; i < val.NumField(); i++ {
+		if val.Field(i).IsZero() {
+			continue
+		}
+		name := strings.Split(val.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+
+	if len(fields) == 0 {
+		return root
+	}
+	return fmt.Sprintf("%s(%s)", root, strings.Join(fields, ","))
+}
+
+/*
+ * # Save to Sheet
+ * - Saves a variety of data types to a Google Sheet (array, map, slice, struct)
+ * - Use WithAppend() to append below existing rows instead of replacing them
+ * - Use WithDryRun() to inspect the planned requests without sending them
+ * - Use WithLinkColumns() or a `rego:"link"` tag to write URLs as clickable HYPERLINK formulas
+ * - Use WithMetadata() or WithMetadataTab() to record export details (time, source, row count) alongside the data
+ * - Fields tagged `rego:"format=currency"` (or date, datetime, time, number, percent, scientific, text) get that number format
+ *   on their column's data rows when the header is written
+ * - Use WithSavedSpreadsheet() to get the ID and URL of the spreadsheet written to, e.g. one created because sheetID was empty
+ * - Returns ErrNoData without sending any requests when data is empty
+ */
+func (c *SheetsClient) SaveToSheet(data any, sheetID, sheetName string, headers *[]string, opts ...SaveOption) error {
+	cfg := &saveConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.HeaderFormat == nil {
+		cfg.HeaderFormat = DefaultHeaderFormat()
+	}
+
+	// Dereference all pointers first to simplify further processing
+	val, err := ss.DerefPointers(reflect.ValueOf(data))
+	if err != nil {
+		return err
+	}
+
+	// Nothing to write, so avoid creating or touching the sheet at all
+	switch val.Kind() {
+	case reflect.Invalid:
+		return ErrNoData
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if val.Len() == 0 {
+			return ErrNoData
+		}
+	}
+
+	if cfg.DryRun != nil {
+		if sheetName == "" {
+			sheetName = DefaultSheetName
+		}
+		vr, err := c.saveValueRange(data, val, sheetName, headers)
+		if err != nil {
+			return err
+		}
+		startRow := 0
+		*cfg.DryRun = SavePlan{
+			SpreadsheetID: sheetID,
+			Append:        cfg.Append,
+			ValueRange:    vr,
+		}
+		if len(cfg.Metadata) > 0 {
+			cfg.DryRun.Metadata = cfg.metadataRange(sheetName)
+		}
+		if cfg.metadataAbove() {
+			vr.Range = fmt.Sprintf("%s!%s", sheetName, rowRange(DefaultColumnSpan, 2))
+			startRow = 1
+		}
+		if len(vr.Values) > 0 {
+			header := append([]string(nil), vr.Values[0]...)
+			format := headerFormatRequests(0, startRow, len(vr.Values), len(header), header, cfg.HeaderFormat, nil)
+			if err := format.Err(); err != nil {
+				return err
+			}
+			cfg.DryRun.Format = format.Request()
+			if numbers := numberFormatRequests(0, startRow, len(vr.Values), header, formatColumns(val), nil); numbers != nil {
+				if err := numbers.Err(); err != nil {
+					return err
+				}
+				cfg.DryRun.NumberFormat = numbers.Request()
+			}
+			if links := linkColumns(val, cfg.LinkColumns); len(links) > 0 {
+				hyperlinkRows(vr.Values, vr.kinds, header, links, true)
+			}
+		}
+		return nil
+	}
+
+	// Handle sheet creation if ID isn't provided
+	sheet := &Spreadsheet{}
+	if sheetID == "" {
+		c.Log.Println("Creating new sheet as no sheet ID was provided.")
+		newSpreadsheet := &Spreadsheet{
+			Properties: &SpreadsheetProperties{
+				Title: fmt.Sprintf("{Rego} New Spreadsheet %s", time.Now().Format("2006-01-02 15:04:05")),
+			},
+			Sheets: []Sheet{
+				{
+					Properties: &SheetProperties{
+						Title: sheetName,
+					},
+				},
+			},
+		}
+		sheet, err = c.CreateSpreadsheet(newSpreadsheet)
+		if err != nil {
+			return err
+		}
+		sheetID = sheet.SpreadsheetID
+	} else {
+		sheet, err = c.GetSpreadsheet(sheetID)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.Saved != nil {
+		*cfg.Saved = *sheet
+	}
+
+	if sheetName == "" {
+		sheetName = DefaultSheetName
+	}
+
+	vr, err := c.saveValueRange(data, val, sheetName, headers)
+	if err != nil {
+		return err
+	}
+
+	// With a metadata row above it, the data block (and its header) starts on the second row
+	startRow, headerRange := 0, fmt.Sprintf("%s!1:1", sheetName)
+	if cfg.metadataAbove() {
+		startRow, headerRange = 1, fmt.Sprintf("%s!2:2", sheetName)
+		vr.Range = fmt.Sprintf("%s!%s", sheetName, rowRange(DefaultColumnSpan, 2))
+	}
+
+	var headerRow []string
+	if len(vr.Values) > 0 {
+		headerRow = append(headerRow, vr.Values[0]...)
+	}
+	links := linkColumns(val, cfg.LinkColumns)
+
+	writeHeader := true
+	if cfg.Append {
+		existing, err := c.ReadSpreadsheetValues(sheetID, headerRange)
+		if err != nil {
+			return err
+		}
+		if len(existing.Values) > 0 && len(vr.Values) > 0 {
+			header := vr.Values[0]
+			switch {
+			case headersMatch(existing.Values[0], header):
+				c.Log.Debug("Sheet already has a matching header row, skipping header.")
+			case cfg.RewriteHeader:
+				c.Log.Println("Rewriting mismatched header row.")
+				hr := &ValueRange{
+					Range:  headerRange,
+					Values: [][]string{header},
+				}
+				if err := c.UpdateSpreadsheet(sheetID, hr); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%w: %v != %v", ErrHeaderMismatch, existing.Values[0], header)
+			}
+			vr.Values = vr.Values[1:]
+			if len(vr.kinds) > 0 {
+				vr.kinds = vr.kinds[1:]
+			}
+			writeHeader = false
+		}
+	}
+
+	var writeOpts []WriteOption
+	if writeHeader {
+		writeOpts = append(writeOpts, WithHeaderRow())
+	}
+	if len(links) > 0 {
+		hyperlinkRows(vr.Values, vr.kinds, headerRow, links, writeHeader)
+		writeOpts = append(writeOpts, WithValueInputOption("USER_ENTERED"))
+	}
+
+	if cfg.Append {
+		c.Log.Println("Appending spreadsheet data.")
+		writeOpts = append(writeOpts, WithInsertRows())
+		if err := c.AppendSpreadsheet(sheetID, vr, writeOpts...); err != nil {
+			return err
+		}
+	} else {
+		c.Log.Println("Updating spreadsheet data.")
+		if err := c.UpdateSpreadsheet(sheetID, vr, writeOpts...); err != nil {
+			return err
+		}
+	}
+
+	if writeHeader && len(vr.Values) > 0 {
+		c.Log.Println("Auto-formatting the spreadsheet.")
+		rows := len(vr.Values)
+		columns := len(headerRow)
+		formats := formatColumns(val)
+		for _, sheet := range sheet.Sheets {
+			if sheet.Properties.Title != sheetName {
+				continue
+			}
+			grid := writtenGrid(sheet.Properties.GridProperties, startRow+rows, columns)
+			if err := c.formatHeader(sheetID, sheet.Properties.SheetID, startRow, rows, columns, headerRow, cfg.HeaderFormat, grid); err != nil {
+				return err
+			}
+
+			// Columns tagged `rego:"format=..."` get their number format in a follow-up batchUpdate
+			if numbers := numberFormatRequests(sheet.Properties.SheetID, startRow, rows, headerRow, formats, grid); numbers != nil {
+				c.Log.Debug("Applying number formats from struct tags.")
+				if err := numbers.Execute(c, sheetID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if len(cfg.Metadata) > 0 {
+		if err := c.writeMetadata(sheetID, sheet, sheetName, cfg); err != nil {
+			return err
+		}
+	}
+
+	c.Log.Println("Sheet updated successfully: ", sheet.SpreadsheetURL)
+	return nil
+}
+
+// linkColumns returns the columns to write as hyperlinks: those requested plus any `rego:"link"` fields of the data's element type
+func linkColumns(val reflect.Value, requested []string) []string {
+	links := append([]string(nil), requested...)
+	if typ := rowType(val); typ != nil {
+		links = append(links, ss.TaggedFieldNames(typ, "rego", "link")...)
+	}
+	return links
+}
+
+// rowType returns the struct type of the rows in val (a struct, or a slice or array of them), or nil for maps and other data
+func rowType(val reflect.Value) reflect.Type {
+	typ := val.Type()
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		typ = typ.Elem()
+		// Use the first element's dynamic type for slices of interfaces
+		for i := 0; typ.Kind() == reflect.Interface && i < val.Len(); i++ {
+			if elem := val.Index(i).Elem(); elem.IsValid() {
+				typ = elem.Type()
+			}
+		}
+	case reflect.Map:
+		return nil
+	}
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	return typ
+}
+
+// numberFormatTypes maps the values of `rego:"format=..."` tags to the NumberFormat type applied to their columns
+var numberFormatTypes = map[string]string{
+	"currency":   "CURRENCY",
+	"date":       "DATE",
+	"datetime":   "DATE_TIME",
+	"number":     "NUMBER",
+	"percent":    "PERCENT",
+	"scientific": "SCIENTIFIC",
+	"text":       "TEXT",
+	"time":       "TIME",
+}
+
+// formatColumns returns the NumberFormat type of every field of val's rows tagged `rego:"format=..."`, keyed by flattened field name
+func formatColumns(val reflect.Value) map[string]string {
+	typ := rowType(val)
+	if typ == nil {
+		return nil
+	}
+
+	formats := make(map[string]string)
+	for name, formatType := range numberFormatTypes {
+		for _, field := range ss.TaggedFieldNames(typ, "rego", "format="+name) {
+			formats[field] = formatType
+		}
+	}
+	return formats
+}
+
+// numberFormatRequests formats the data rows of each header column that is, or is nested under, a field in formats; it
+// returns nil when no column is formatted. rows counts the header row, and a non-nil grid bounds the ranges.
+func numberFormatRequests(sheetID, startRow, rows int, header []string, formats map[string]string, grid *GridProperties) *BatchUpdate {
+	if len(formats) == 0 || rows < 2 {
+		return nil
+	}
+
+	var b *BatchUpdate
+	for i, column := range header {
+		// The most specific tagged field wins for columns nested under more than one
+		field, formatType := "", ""
+		for key, t := range formats {
+			if (column == key || strings.HasPrefix(column, key+".")) && len(key) > len(field) {
+				field, formatType = key, t
+			}
+		}
+		if formatType == "" {
+			continue
+		}
+
+		if b == nil {
+			b = NewBatchUpdate(sheetID).WithinGrid(grid)
+		}
+		b.RepeatCellFormat(&GridRange{
+			StartRowIndex:    startRow + 1,
+			EndRowIndex:      startRow + rows,
+			StartColumnIndex: i,
+			EndColumnIndex:   i + 1,
+		}, &CellFormat{NumberFormat: &NumberFormat{Type: formatType}})
+	}
+	return b
+}
+
+// writtenGrid returns a copy of grid grown to hold rows and columns, since writing values expands the sheet past
+// the dimensions read before the write; a nil grid stays nil
+func writtenGrid(grid *GridProperties, rows, columns int) *GridProperties {
+	if grid == nil {
+		return nil
+	}
+
+	gp := *grid
+	gp.RowCount = max(gp.RowCount, rows)
+	gp.ColumnCount = max(gp.ColumnCount, columns)
+	return &gp
+}
+
+/*
+ * hyperlinkRows prepares rows for a USER_ENTERED write, rewriting them in place
+ * - Non-empty cells in link columns become =HYPERLINK("url") formulas, with quotes in the URL doubled
+ * - Cells whose kind in kinds (aligned with rows, when known) is numeric or bool are left as is, so they are still sent typed
+ * - Every other non-empty cell is prefixed with ' so it is stored as literal text, exactly as a RAW write would
+ * - When hasHeader is set, rows[0] is the header row and is kept as text
+ */
+func hyperlinkRows(rows [][]string, kinds [][]reflect.Kind, header []string, links []string, hasHeader bool) {
+	isLink := make([]bool, len(header))
+	for i, name := range header {
+		for _, link := range links {
+			if name == link {
+				isLink[i] = true
+				break
+			}
+		}
+	}
+
+	typed := len(kinds) == len(rows)
+	for r, row := range rows {
+		for i, cell := range row {
+			switch {
+			case cell == "":
+			case i < len(isLink) && isLink[i] && !(hasHeader && r == 0):
+				row[i] = fmt.Sprintf(`=HYPERLINK("%s")`, strings.ReplaceAll(cell, `"`, `""`))
+			default:
+				if typed && i < len(kinds[r]) {
+					if _, ok := typedCell(cell, kinds[r][i]); ok {
+						continue
+					}
+				}
+				row[i] = "'" + cell
+			}
+		}
+	}
+}
+
+// writeMetadata writes cfg.Metadata above the header, or into cfg.MetadataTab, adding that tab if the spreadsheet lacks it
+func (c *SheetsClient) writeMetadata(sheetID string, spreadsheet *Spreadsheet, sheetName string, cfg *saveConfig) error {
+	if cfg.MetadataTab != "" {
+		found := false
+		for _, sheet := range spreadsheet.Sheets {
+			if sheet.Properties != nil && sheet.Properties.Title == cfg.MetadataTab {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.Log.Println("Adding metadata tab:", cfg.MetadataTab)
+			if _, err := c.AddSheet(sheetID, cfg.MetadataTab); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.Log.Println("Writing export metadata.")
+	return c.UpdateSpreadsheet(sheetID, cfg.metadataRange(sheetName))
+}
+
+/*
+ * # Save to Sheet (Context)
+ * - SaveToSheet bound to ctx; once ctx is cancelled no further requests are issued and ctx.Err() is returned
+ */
+func (c *SheetsClient) SaveToSheetContext(ctx context.Context, data any, sheetID, sheetName string, headers *[]string, opts ...SaveOption) error {
+	return c.WithContext(ctx).SaveToSheet(data, sheetID, sheetName, headers, opts...)
+}
+
+/*
+ * # Upsert to Sheet
+ * - Writes data to an existing tab keyed on keyColumn: rows whose key matches an existing row are updated in place, the rest are appended
+ * - Matched rows are written in a single values.batchUpdate request, followed by at most one append
+ * - The existing header row must match the generated headers (ErrHeaderMismatch otherwise); an empty tab is written header first
+ * - Rows with an empty key are always appended; for duplicate keys in the sheet, the last matching row is updated
+ * - Returns ErrNoData without sending any requests when data is empty
+ */
+func (c *SheetsClient) UpsertToSheet(data interface{}, sheetID, sheetName, keyColumn string, headers *[]string) error {
+	if sheetID == "" {
+		return fmt.Errorf("upsert requires an existing spreadsheet ID")
+	}
+
+	val, err := ss.DerefPointers(reflect.ValueOf(data))
+	if err != nil {
+		return err
+	}
+	switch val.Kind() {
+	case reflect.Invalid:
+		return ErrNoData
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if val.Len() == 0 {
+			return ErrNoData
+		}
+	}
+
+	if sheetName == "" {
+		sheetName = DefaultSheetName
+	}
+
+	vr, err := c.saveValueRange(data, val, sheetName, headers)
+	if err != nil {
+		return err
+	}
+	if len(vr.Values) == 0 {
+		return ErrNoData
+	}
+	header := vr.Values[0]
+
+	keyIndex := -1
+	for i, column := range header {
+		if column == keyColumn {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return fmt.Errorf("key column %q not found in header row %v", keyColumn, header)
+	}
+
+	existing, err := c.ReadSpreadsheetValues(sheetID, fmt.Sprintf("%s!%s", sheetName, DefaultColumnSpan))
+	if err != nil {
+		return err
+	}
+
+	// Nothing to match against, so the whole range (header included) is written as is
+	if len(existing.Values) == 0 {
+		c.Log.Println("Sheet is empty, writing all rows.")
+		return c.UpdateSpreadsheet(sheetID, vr)
+	}
+	if !headersMatch(existing.Values[0], header) {
+		return fmt.Errorf("%w: %v != %v", ErrHeaderMismatch, existing.Values[0], header)
+	}
+
+	// Sheet row numbers are 1-based and the header occupies row 1
+	rowOfKey := make(map[string]int, len(existing.Values)-1)
+	for i, row := range existing.Values[1:] {
+		if keyIndex < len(row) && row[keyIndex] != "" {
+			rowOfKey[row[keyIndex]] = i + 2
+		}
+	}
+
+	appended := &ValueRange{
+		Range:          fmt.Sprintf("%s!%s", sheetName, DefaultColumnSpan),
+		MajorDimension: "ROWS",
+	}
+	var updates []*ValueRange
+	for i, row := range vr.Values[1:] {
+		var kinds []reflect.Kind
+		if len(vr.kinds) == len(vr.Values) {
+			kinds = vr.kinds[i+1]
+		}
+
+		n, ok := rowOfKey[row[keyIndex]]
+		if !ok || row[keyIndex] == "" {
+			appended.Values = append(appended.Values, row)
+			appended.kinds = append(appended.kinds, kinds)
+			continue
+		}
+
+		updated := &ValueRange{
+			Range:          fmt.Sprintf("%s!%s", sheetName, rowSpan(DefaultColumnSpan, n)),
+			MajorDimension: "ROWS",
+			Values:         [][]string{row},
+		}
+		if kinds != nil {
+			updated.kinds = [][]reflect.Kind{kinds}
+		}
+		c.Log.Debugf("Updating row %d for key %q", n, row[keyIndex])
+		updates = append(updates, updated)
+	}
+
+	if len(updates) > 0 {
+		c.Log.Printf("Updating %d existing rows.", len(updates))
+		if _, err := c.BatchUpdateValues(sheetID, updates, "RAW"); err != nil {
+			return err
+		}
+	}
+
+	if len(appended.Values) > 0 {
+		c.Log.Printf("Appending %d new rows.", len(appended.Values))
+		if err := c.AppendSpreadsheet(sheetID, appended); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-	// Add a filter view for the header row
-	format.Requests = append(format.Requests, &SheetRequest{
-		SetBasicFilter: &SetBasicFilterRequest{
-			Filter: &BasicFilter{
-				Range: &GridRange{
-					SheetID:          sheet.Properties.SheetID,
-					StartRowIndex:    0,
-					EndRowIndex:      rows,
-					StartColumnIndex: 0,
-					EndColumnIndex:   columns,
-				},
-			},
-		},
-	})
+// rowSpan limits a column span such as "A:ZZ" to a single 1-based row, e.g. "A5:ZZ5"
+func rowSpan(span string, row int) string {
+	start, end, ok := strings.Cut(span, ":")
+	if !ok {
+		return fmt.Sprintf("%s%d", span, row)
+	}
+	return fmt.Sprintf("%s%d:%s%d", start, row, end, row)
+}
 
-	// Auto resize all columns
-	format.Requests = append(format.Requests, &SheetRequest{
-		AutoResizeDimensions: &AutoResizeDimensionsRequest{
-			Dimensions: &DimensionRange{
-				SheetID:    sheet.Properties.SheetID,
-				Dimension:  "COLUMNS",
-				StartIndex: 0,
-				EndIndex:   columns,
-			},
-		},
-	})
+/*
+ * # Save Channel to Sheet
+ * - Appends the items received on ch to an existing tab in batches of DefaultStreamBatchSize rows, so the whole dataset is never held in memory
+ * - The header row is written with the first batch unless the tab already starts with a matching one (ErrHeaderMismatch for any other)
+ * - Without headers, the header row is generated from the first batch's items
+ * - Columns are fixed by the header row: later items are written under it, and fields outside it are dropped
+ * - Stops with the client's context error if it is cancelled before ch is closed
+ * - Returns ErrNoData without sending any requests when ch is closed before any item arrives
+ */
+func (c *SheetsClient) SaveChannelToSheet(ch <-chan interface{}, sheetID, sheetName string, headers *[]string) error {
+	if sheetID == "" {
+		return fmt.Errorf("streaming requires an existing spreadsheet ID")
+	}
+	if sheetName == "" {
+		sheetName = DefaultSheetName
+	}
+	size := DefaultStreamBatchSize
+	if size <= 0 {
+		size = 1
+	}
 
-	// Execute the batchUpdate request
-	_, err := do[any](c.Client, "POST", url, nil, format)
-	if err != nil {
-		return err
+	var header []string
+	written := 0
+	batch := make([]any, 0, size)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		vr := c.GenerateValueRange(batch, sheetName, headers)
+		batch = batch[:0]
+		rows := len(vr.Values) - 1
+		if rows == 0 {
+			return nil
+		}
+
+		if header == nil {
+			header = vr.Values[0]
+			headers = &header
+
+			existing, err := c.ReadSpreadsheetValues(sheetID, fmt.Sprintf("%s!1:1", sheetName))
+			if err != nil {
+				return err
+			}
+			if len(existing.Values) > 0 {
+				if !headersMatch(existing.Values[0], header) {
+					return fmt.Errorf("%w: %v != %v", ErrHeaderMismatch, existing.Values[0], header)
+				}
+				c.Log.Debug("Sheet already has a matching header row, skipping header.")
+				vr.Values = vr.Values[1:]
+				if len(vr.kinds) > 0 {
+					vr.kinds = vr.kinds[1:]
+				}
+			}
+		} else {
+			// The header row is already in the sheet, so only the aligned data rows are appended
+			vr = alignValueRange(vr, header)
+			vr.Values = vr.Values[1:]
+			if len(vr.kinds) > 0 {
+				vr.kinds = vr.kinds[1:]
+			}
+		}
+
+		c.Log.Debugf("Appending batch of %d rows to %s", rows, sheetName)
+		if err := c.AppendSpreadsheet(sheetID, vr); err != nil {
+			return err
+		}
+		written += rows
+		return nil
 	}
 
-	return nil
+	ctx := c.context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case item, ok := <-ch:
+			if !ok {
+				if err := flush(); err != nil {
+					return err
+				}
+				if written == 0 {
+					return ErrNoData
+				}
+				c.Log.Printf("Streamed %d rows to %s.", written, sheetName)
+				return nil
+			}
+			batch = append(batch, item)
+			if len(batch) == size {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// alignValueRange rearranges the columns of vr (header row included) to match header, leaving cells empty for columns vr lacks
+func alignValueRange(vr *ValueRange, header []string) *ValueRange {
+	index := make(map[string]int, len(vr.Values[0]))
+	for i, column := range vr.Values[0] {
+		index[column] = i
+	}
+	typed := len(vr.kinds) == len(vr.Values)
+
+	aligned := &ValueRange{
+		Range:          vr.Range,
+		MajorDimension: vr.MajorDimension,
+		Values:         make([][]string, len(vr.Values)),
+	}
+	if typed {
+		aligned.kinds = make([][]reflect.Kind, len(vr.Values))
+	}
+	for r, row := range vr.Values {
+		aligned.Values[r] = make([]string, len(header))
+		if typed {
+			aligned.kinds[r] = make([]reflect.Kind, len(header))
+		}
+		for i, column := range header {
+			j, ok := index[column]
+			if !ok || j >= len(row) {
+				continue
+			}
+			aligned.Values[r][i] = row[j]
+			if typed && j < len(vr.kinds[r]) {
+				aligned.kinds[r][i] = vr.kinds[r][j]
+			}
+		}
+	}
+	return aligned
 }
 
 /*
- * # Save to Sheet
- * - Saves a variety of data types to a Google Sheet (array, map, slice, struct)
+ * # Create Spreadsheet with Data
+ * - Creates a spreadsheet with one tab per entry in tabs, then writes and formats each tab's data as SaveToSheet would
+ * - Tabs are ordered by title, since map iteration order is random
+ * - Tabs with empty data are created but left blank
+ * - Returns the created spreadsheet, whose Sheets carry the assigned sheet IDs
  */
-func (c *SheetsClient) SaveToSheet(data any, sheetID, sheetName string, headers *[]string) error {
-	// Dereference all pointers first to simplify further processing
-	val, err := ss.DerefPointers(reflect.ValueOf(data))
-	if err != nil {
-		return err
+func (c *SheetsClient) CreateSpreadsheetWithData(title string, tabs map[string]interface{}) (*Spreadsheet, error) {
+	titles := make([]string, 0, len(tabs))
+	for tab := range tabs {
+		titles = append(titles, tab)
 	}
+	sort.Strings(titles)
 
-	// Handle sheet creation if ID isn't provided
-	sheet := &Spreadsheet{}
-	if sheetID == "" {
-		c.Log.Println("Creating new sheet as no sheet ID was provided.")
-		newSpreadsheet := &Spreadsheet{
-			Properties: &SpreadsheetProperties{
-				Title: fmt.Sprintf("{Rego} New Spreadsheet %s", time.Now().Format("2006-01-02 15:04:05")),
-			},
-			Sheets: []Sheet{
-				{
-					Properties: &SheetProperties{
-						Title: sheetName,
-					},
-				},
+	newSpreadsheet := &Spreadsheet{
+		Properties: &SpreadsheetProperties{
+			Title: title,
+		},
+	}
+	for i, tab := range titles {
+		newSpreadsheet.Sheets = append(newSpreadsheet.Sheets, Sheet{
+			Properties: &SheetProperties{
+				Index: i,
+				Title: tab,
 			},
+		})
+	}
+
+	spreadsheet, err := c.CreateSpreadsheet(newSpreadsheet)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties == nil {
+			continue
 		}
-		sheet, err = c.CreateSpreadsheet(newSpreadsheet)
+		tab := sheet.Properties.Title
+		data, ok := tabs[tab]
+		if !ok {
+			continue
+		}
+
+		val, err := ss.DerefPointers(reflect.ValueOf(data))
 		if err != nil {
-			return err
+			return spreadsheet, err
 		}
-		sheetID = sheet.SpreadsheetID
-	} else {
-		sheet, err = c.GetSpreadsheet(sheetID)
+		switch val.Kind() {
+		case reflect.Invalid:
+			continue
+		case reflect.Slice, reflect.Array, reflect.Map:
+			if val.Len() == 0 {
+				continue
+			}
+		}
+
+		vr, err := c.saveValueRange(data, val, tab, nil)
 		if err != nil {
-			return err
+			return spreadsheet, fmt.Errorf("tab %s: %w", tab, err)
+		}
+		if len(vr.Values) == 0 {
+			continue
+		}
+
+		c.Log.Println("Writing data to tab:", tab)
+		if err := c.UpdateSpreadsheet(spreadsheet.SpreadsheetID, vr); err != nil {
+			return spreadsheet, fmt.Errorf("tab %s: %w", tab, err)
+		}
+		rows, columns := len(vr.Values), len(vr.Values[0])
+		grid := writtenGrid(sheet.Properties.GridProperties, rows, columns)
+		if err := c.formatHeader(spreadsheet.SpreadsheetID, sheet.Properties.SheetID, 0, rows, columns, vr.Values[0], DefaultHeaderFormat(), grid); err != nil {
+			return spreadsheet, fmt.Errorf("tab %s: %w", tab, err)
 		}
 	}
 
-	if sheetName == "" {
-		sheetName = "Sheet1"
+	return spreadsheet, nil
+}
+
+/*
+ * # Export to CSV
+ * - Writes data as CSV using the same flattened, aligned rows SaveToSheet would write to a sheet
+ */
+func (c *SheetsClient) ExportCSV(data interface{}, w io.Writer, headers *[]string) error {
+	val, err := ss.DerefPointers(reflect.ValueOf(data))
+	if err != nil {
+		return err
 	}
 
-	vr := &ValueRange{
-		Range: fmt.Sprintf("%s!A:ZZ", sheetName),
+	vr, err := c.saveValueRange(data, val, "", headers)
+	if err != nil {
+		return err
 	}
-	switch v := data.(type) {
-	case [][]string:
-		vr.Values = v
-	default:
-		vr, err = c.prepareAndGenerateValueRange(val, sheetName, headers)
-		if err != nil {
-			return err
-		}
+
+	return csv.NewWriter(w).WriteAll(vr.Values)
+}
+
+/*
+ * # Export to XLSX
+ * - Writes data as a single-sheet .xlsx workbook using the same flattened, aligned rows SaveToSheet would write to a sheet
+ * - The header row gets the DefaultHeaderFormat styling and a filter, as FormatHeaderAndAutoSize applies in Sheets
+ */
+func (c *SheetsClient) ExportXLSX(data interface{}, w io.Writer, headers *[]string) error {
+	val, err := ss.DerefPointers(reflect.ValueOf(data))
+	if err != nil {
+		return err
 	}
 
-	c.Log.Println("Updating spreadsheet data.")
-	if err := c.UpdateSpreadsheet(sheetID, vr); err != nil {
+	vr, err := c.saveValueRange(data, val, "", headers)
+	if err != nil {
 		return err
 	}
 
-	c.Log.Println("Auto-formatting the spreadsheet.")
-	rows := len(vr.Values)
-	columns := len(vr.Values[0])
-	for _, sheet := range sheet.Sheets {
-		if sheet.Properties.Title == sheetName {
-			c.FormatHeaderAndAutoSize(sheetID, &sheet, rows, columns)
+	return writeXLSX(w, DefaultSheetName, vr.Values, DefaultHeaderFormat())
+}
+
+// saveValueRange builds the ValueRange written by SaveToSheet, using raw [][]string data as-is
+func (c *SheetsClient) saveValueRange(data any, val reflect.Value, sheetName string, headers *[]string) (*ValueRange, error) {
+	if v, ok := data.([][]string); ok {
+		return &ValueRange{
+			Range:  fmt.Sprintf("%s!%s", sheetName, DefaultColumnSpan),
+			Values: v,
+		}, nil
+	}
+	return c.prepareAndGenerateValueRange(val, sheetName, headers)
+}
+
+// headersMatch reports whether an existing header row matches the generated one, ignoring trailing empty cells which the API omits
+func headersMatch(existing, generated []string) bool {
+	trim := func(row []string) []string {
+		for len(row) > 0 && row[len(row)-1] == "" {
+			row = row[:len(row)-1]
 		}
+		return row
 	}
+	existing, generated = trim(existing), trim(generated)
 
-	c.Log.Println("Sheet updated successfully: ", sheet.SpreadsheetURL)
-	return nil
+	if len(existing) != len(generated) {
+		return false
+	}
+	for i := range existing {
+		if existing[i] != generated[i] {
+			return false
+		}
+	}
+	return true
 }
 
 func (c *SheetsClient) prepareAndGenerateValueRange(val reflect.Value, sheetName string, headers *[]string) (*ValueRange, error) {
@@ -423,7 +2259,7 @@ func (c *SheetsClient) GetSpreadsheet(sheetID string) (*Spreadsheet, error) {
 		IncludeGridData: false,
 	}
 
-	spreadsheet, err := do[Spreadsheet](c.Client, "GET", url, q, nil)
+	spreadsheet, err := doContext[Spreadsheet](c.context(), c.Client, "GET", url, q, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -434,13 +2270,24 @@ func (c *SheetsClient) GetSpreadsheet(sheetID string) (*Spreadsheet, error) {
 /*
  * # Spreadsheet: Read
  * Reads values from a spreadsheet
+ * - Values is empty (not nil) when the range has no data
  * spreadsheets/{spreadsheetId}/values/{range}
  * https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/get
  */
-func (c *SheetsClient) ReadSpreadsheetValues(sheetID, rangeNotation string) (*ValueRange, error) {
+func (c *SheetsClient) ReadSpreadsheetValues(sheetID, rangeNotation string, opts ...ReadOption) (*ValueRange, error) {
+	cfg := &readConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
 
 	if rangeNotation == "" {
-		rangeNotation = "Sheet1!A:ZZ"
+		rangeNotation = fmt.Sprintf("%s!%s", DefaultSheetName, DefaultColumnSpan)
+	} else if cfg.R1C1 {
+		a1, err := R1C1ToA1(rangeNotation, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		rangeNotation = a1
 	}
 
 	q := SheetValueQuery{
@@ -450,10 +2297,298 @@ func (c *SheetsClient) ReadSpreadsheetValues(sheetID, rangeNotation string) (*Va
 
 	url := fmt.Sprintf("%s/%s/values/%s", Sheets, sheetID, rangeNotation)
 
-	vr, err := do[ValueRange](c.Client, "GET", url, q, nil)
+	vr, err := doContext[ValueRange](c.context(), c.Client, "GET", url, q, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	// The API omits `values` entirely for an empty range
+	if vr.Values == nil {
+		vr.Values = [][]string{}
+	}
+
 	return &vr, nil
 }
+
+/*
+ * # Spreadsheet: Read Cell
+ * Reads a single cell (e.g. "Sheet1!B2") and returns its typed value: float64, bool or string, as the API renders it unformatted
+ * - Returns nil for an empty cell
+ * - Returns ErrNotSingleCell for a multi-cell range; a cell beyond the sheet's grid is reported by the API
+ * spreadsheets/{spreadsheetId}/values/{range}
+ * https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/get
+ */
+func (c *SheetsClient) GetCell(sheetID, a1Cell string) (interface{}, error) {
+	if err := checkSingleCell(a1Cell); err != nil {
+		return nil, err
+	}
+
+	q := SheetValueQuery{
+		MajorDimension:    "ROWS",
+		ValueRenderOption: "UNFORMATTED_VALUE",
+	}
+
+	url := fmt.Sprintf("%s/%s/values/%s", Sheets, sheetID, a1Cell)
+
+	vr, err := doContext[typedValueRange](c.context(), c.Client, "GET", url, q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The API omits `values` entirely for an empty cell
+	if len(vr.Values) == 0 || len(vr.Values[0]) == 0 {
+		return nil, nil
+	}
+	return vr.Values[0][0], nil
+}
+
+/*
+ * # Spreadsheet: Write Cell
+ * Writes one value to a single cell (e.g. "Sheet1!B2"), such as a status flag
+ * - Numbers and booleans are sent as native values, anything else as its text; nil clears the cell
+ * - Values are stored RAW by default; pass WithValueInputOption("USER_ENTERED") to have formulas and dates parsed
+ * - Returns ErrNotSingleCell for a multi-cell range
+ * spreadsheets/{spreadsheetId}/values/{range}
+ * https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/update
+ */
+func (c *SheetsClient) SetCell(sheetID, a1Cell string, value interface{}, opts ...WriteOption) error {
+	if err := checkSingleCell(a1Cell); err != nil {
+		return err
+	}
+
+	vr := &ValueRange{
+		Range:          a1Cell,
+		MajorDimension: "ROWS",
+		Values:         [][]string{{""}},
+	}
+	if value != nil {
+		vr.Values[0][0] = fmt.Sprint(value)
+		vr.kinds = [][]reflect.Kind{{reflect.ValueOf(value).Kind()}}
+	}
+
+	return c.UpdateSpreadsheet(sheetID, vr, opts...)
+}
+
+// checkSingleCell returns ErrNotSingleCell unless a1 (optionally sheet-qualified) names one cell, such as "B2"
+func checkSingleCell(a1 string) error {
+	cell := strings.TrimPrefix(a1, sheetOfRange(a1)+"!")
+	row := strings.TrimLeft(strings.ToUpper(cell), "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	if len(row) == len(cell) || row == "" || strings.Trim(row, "0123456789") != "" || row[0] == '0' {
+		return fmt.Errorf("%w: %q", ErrNotSingleCell, a1)
+	}
+	return nil
+}
+
+/*
+ * # Spreadsheet: Batch Read
+ * Reads several ranges from a spreadsheet in one request, returning them in the order requested
+ * - Values is empty (not nil) for ranges with no data
+ * spreadsheets/{spreadsheetId}/values:batchGet
+ * https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/batchGet
+ */
+func (c *SheetsClient) BatchGetValues(sheetID string, ranges []string) ([]*ValueRange, error) {
+	q := SheetValueQuery{
+		Ranges:            ranges,
+		MajorDimension:    "ROWS",
+		ValueRenderOption: "FORMATTED_VALUE",
+	}
+
+	url := fmt.Sprintf("%s/%s/values:batchGet", Sheets, sheetID)
+
+	res, err := doContext[BatchGetValuesResponse](c.context(), c.Client, "GET", url, q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vr := range res.ValueRanges {
+		if vr.Values == nil {
+			vr.Values = [][]string{}
+		}
+	}
+
+	return res.ValueRanges, nil
+}
+
+/*
+ * # Spreadsheet: Concurrent Read
+ * Reads several ranges with individual gets, for when values:batchGet is unavailable
+ * - At most concurrency reads are in flight at once (at least 1)
+ * - Every read goes through the client's shared rate limiter, so the workers cannot burst past the quota
+ * - Results are returned in the order requested; once a read fails no further reads are started, in-flight ones are
+ *   cancelled, and that first failure is returned
+ */
+func (c *SheetsClient) ReadRangesConcurrently(sheetID string, ranges []string, concurrency int) ([]*ValueRange, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(c.context())
+	defer cancel()
+	sc := c.WithContext(ctx)
+
+	results := make([]*ValueRange, len(ranges))
+	sem := make(chan struct{}, concurrency)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	for i, rangeNotation := range ranges {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, rangeNotation string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			vr, err := sc.ReadSpreadsheetValues(sheetID, rangeNotation)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("range %s: %w", rangeNotation, err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			results[i] = vr
+		}(i, rangeNotation)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// The client's own context was cancelled before every read started
+	if err := c.context().Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+/*
+ * # Spreadsheet: Read as Structs
+ * Reads several ranges (e.g. one per tab) sharing a header row and joins their rows into one struct slice via TableToStructs
+ * - Every non-empty range must have the same header row, otherwise ErrHeaderMismatch is returned
+ * - Short rows are padded, as the API omits trailing empty cells
+ */
+func (c *SheetsClient) ReadSheetsAsStructs(sheetID string, ranges []string) ([]interface{}, error) {
+	vrs, err := c.BatchGetValues(sheetID, ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	var table [][]string
+	for _, vr := range vrs {
+		if len(vr.Values) == 0 {
+			continue
+		}
+		header := vr.Values[0]
+		if table == nil {
+			table = [][]string{header}
+		} else if !headersMatch(table[0], header) {
+			return nil, fmt.Errorf("%w: range %s has %v, want %v", ErrHeaderMismatch, vr.Range, header, table[0])
+		}
+
+		for _, row := range vr.Values[1:] {
+			if len(row) < len(table[0]) {
+				row = append(row, make([]string, len(table[0])-len(row))...)
+			}
+			table = append(table, row)
+		}
+	}
+
+	if table == nil {
+		return []interface{}{}, nil
+	}
+	return ss.TableToStructs(table)
+}
+
+/*
+ * # Spreadsheet: Headers
+ * Reads the first row of a range and returns it as headers, for passing to SaveToSheet so exported columns align with an existing tab
+ * - Use a single-row range (e.g. "Sheet1!1:1") to avoid reading the whole sheet
+ * - Returns nil if the range is empty
+ */
+func (c *SheetsClient) HeadersFromSheet(sheetID, rangeNotation string) (*[]string, error) {
+	if rangeNotation == "" {
+		rangeNotation = fmt.Sprintf("%s!1:1", DefaultSheetName)
+	}
+
+	vr, err := c.ReadSpreadsheetValues(sheetID, rangeNotation)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vr.Values) == 0 || len(vr.Values[0]) == 0 {
+		return nil, nil
+	}
+
+	headers := make([]string, len(vr.Values[0]))
+	copy(headers, vr.Values[0])
+	return &headers, nil
+}
+
+/*
+ * # Spreadsheet: Read as Map
+ * Reads a range and returns each row as a map of header -> value, keyed by the value in keyColumn
+ * - The first row of the range is used as the header row
+ * - Rows with an empty key are skipped; by default the last row wins for duplicate keys (see WithDuplicateKeyError)
+ */
+func (c *SheetsClient) ReadSheetAsMap(sheetID, rangeNotation, keyColumn string, opts ...ReadOption) (map[string]map[string]string, error) {
+	cfg := &readConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	vr, err := c.ReadSpreadsheetValues(sheetID, rangeNotation, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]string)
+	if len(vr.Values) == 0 {
+		return result, nil
+	}
+
+	header := vr.Values[0]
+	keyIndex := -1
+	for i, column := range header {
+		if column == keyColumn {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return nil, fmt.Errorf("key column %q not found in header row %v", keyColumn, header)
+	}
+
+	for i, row := range vr.Values[1:] {
+		if keyIndex >= len(row) || row[keyIndex] == "" {
+			continue
+		}
+		key := row[keyIndex]
+		if _, ok := result[key]; ok && cfg.ErrorOnDuplicate {
+			// Counted from the first row below the header, since the range need not start on the sheet's first row
+			return nil, fmt.Errorf("%w: %q (data row %d)", ErrDuplicateKey, key, i+1)
+		}
+
+		// The API omits trailing empty cells, so short rows are padded with ""
+		record := make(map[string]string, len(header))
+		for j, column := range header {
+			if j < len(row) {
+				record[column] = row[j]
+			} else {
+				record[column] = ""
+			}
+		}
+		result[key] = record
+	}
+
+	return result, nil
+}
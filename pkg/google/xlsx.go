@@ -0,0 +1,150 @@
+/*
+# Google Workspace - Sheets (XLSX)
+
+This package writes Office Open XML (.xlsx) workbooks that mirror the structure SaveToSheet writes to Google Sheets:
+https://learn.microsoft.com/en-us/openspecs/office_standards/ms-xlsx
+
+:Copyright: (c) 2025 by Gemini Space Station, LLC, see AUTHORS for more info
+:License: See the LICENSE file for details
+:Author: Anthony Dardano <anthony.dardano@gemini.com>
+*/
+
+// pkg/google/xlsx.go
+package google
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+	// xlsxFontSize is the body font size, which the header font matches unless HeaderFormat.FontSize is set
+	xlsxFontSize = 10
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+)
+
+// writeXLSX writes rows as a single-sheet workbook, styling the first row with hf when it is non-nil
+func writeXLSX(w io.Writer, sheetName string, rows [][]string, hf *HeaderFormat) error {
+	var name bytes.Buffer
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return err
+	}
+
+	parts := []struct {
+		path    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(xlsxContentTypes)},
+		{"_rels/.rels", []byte(xlsxRootRels)},
+		{"xl/workbook.xml", []byte(fmt.Sprintf(xlsxWorkbook, name.String()))},
+		{"xl/_rels/workbook.xml.rels", []byte(xlsxWorkbookRels)},
+		{"xl/styles.xml", xlsxStyles(hf)},
+		{"xl/worksheets/sheet1.xml", xlsxSheet(rows, hf != nil)},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, part := range parts {
+		fw, err := zw.Create(part.path)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", part.path, err)
+		}
+		if _, err := fw.Write(part.content); err != nil {
+			return fmt.Errorf("writing %s: %w", part.path, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// xlsxSheet renders the worksheet part, writing every cell as an inline string; header cells use style 1
+func xlsxSheet(rows [][]string, styleHeader bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	columns := 0
+	for r, row := range rows {
+		columns = max(columns, len(row))
+		fmt.Fprintf(&buf, `<row r="%d">`, r+1)
+		for col, value := range row {
+			style := ""
+			if r == 0 && styleHeader {
+				style = ` s="1"`
+			}
+			fmt.Fprintf(&buf, `<c r="%s%d" t="inlineStr"%s><is><t xml:space="preserve">`, columnName(col), r+1, style)
+			xml.EscapeText(&buf, []byte(value))
+			buf.WriteString(`</t></is></c>`)
+		}
+		buf.WriteString(`</row>`)
+	}
+	buf.WriteString(`</sheetData>`)
+
+	// Add a filter over the data block, like the basic filter SaveToSheet sets
+	if len(rows) > 0 && columns > 0 {
+		fmt.Fprintf(&buf, `<autoFilter ref="A1:%s%d"/>`, columnName(columns-1), len(rows))
+	}
+
+	buf.WriteString(`</worksheet>`)
+	return buf.Bytes()
+}
+
+// xlsxStyles renders the styles part: style 0 is the default, style 1 the header format
+func xlsxStyles(hf *HeaderFormat) []byte {
+	if hf == nil {
+		hf = &HeaderFormat{}
+	}
+
+	font := ""
+	if hf.Bold {
+		font += `<b/>`
+	}
+	if hf.Italic {
+		font += `<i/>`
+	}
+	if hf.TextColor != nil {
+		font += fmt.Sprintf(`<color rgb="%s"/>`, xlsxColor(hf.TextColor))
+	}
+	size := xlsxFontSize
+	if hf.FontSize > 0 {
+		size = hf.FontSize
+	}
+	font += fmt.Sprintf(`<sz val="%d"/><name val="Arial"/>`, size)
+
+	fill := `<patternFill patternType="none"/>`
+	if hf.BackgroundColor != nil {
+		fill = fmt.Sprintf(`<patternFill patternType="solid"><fgColor rgb="%s"/></patternFill>`, xlsxColor(hf.BackgroundColor))
+	}
+
+	return []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		fmt.Sprintf(`<fonts count="2"><font><sz val="%d"/><name val="Arial"/></font><font>`, xlsxFontSize) + font + `</font></fonts>` +
+		`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill><fill>` + fill + `</fill></fills>` +
+		`<borders count="1"><border/></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/></cellXfs>` +
+		`</styleSheet>`)
+}
+
+// xlsxColor converts a 0-1 float Color to the opaque ARGB hex string used by SpreadsheetML
+func xlsxColor(c *Color) string {
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	return fmt.Sprintf("FF%02X%02X%02X", channel(c.Red), channel(c.Green), channel(c.Blue))
+}
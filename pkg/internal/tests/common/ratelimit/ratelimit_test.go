@@ -194,3 +194,45 @@ func TestRateLimiterDecrementBehavior(t *testing.T) {
 		t.Errorf("Expected Available to decrement, got %d", rl.Available)
 	}
 }
+
+func TestRateLimiterExhaustedQuotaBlocks(t *testing.T) {
+	rl := ratelimit.NewRateLimiter(100)
+	rl.ResetHeaders = true
+	defer rl.Stop()
+
+	resetTime := time.Now().Add(2 * time.Second).Unix()
+	headers := http.Header{}
+	headers.Set("X-Rate-Limit-Limit", "100")
+	headers.Set("X-Rate-Limit-Remaining", "0")
+	headers.Set("X-Rate-Limit-Reset", strconv.FormatInt(resetTime, 10))
+	rl.UpdateFromHeaders(headers)
+
+	if state := rl.State(); state.Available != 0 || state.ResetTimestamp != resetTime {
+		t.Errorf("State() = %+v, want Available 0 and ResetTimestamp %d", state, resetTime)
+	}
+
+	start := time.Now()
+	rl.Wait()
+	if elapsed := time.Since(start); time.Now().Unix() < resetTime || elapsed < time.Second {
+		t.Errorf("Wait() returned after %v, before the quota reset at %d", elapsed, resetTime)
+	}
+	if state := rl.State(); state.Available != state.Limit {
+		t.Errorf("State().Available = %d after the reset, want %d", state.Available, state.Limit)
+	}
+}
+
+func TestRateLimiterBackoff(t *testing.T) {
+	rl := ratelimit.NewRateLimiter(100)
+	defer rl.Stop()
+
+	rl.Backoff(500 * time.Millisecond)
+	if blocked := rl.State().BlockedUntil; time.Until(blocked) <= 0 {
+		t.Fatalf("State().BlockedUntil = %v, want a time in the future", blocked)
+	}
+
+	start := time.Now()
+	rl.Wait()
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("Wait() returned after %v, want at least the 500ms backoff", elapsed)
+	}
+}
@@ -0,0 +1,127 @@
+// pkg/internal/tests/common/requests/pagination_test.go
+package requests_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gemini-oss/rego/pkg/common/requests"
+)
+
+// itemsServer serves three pages of {"items": [...], "nextPageToken": "..."} keyed by the pageToken parameter
+func itemsServer(t *testing.T) *httptest.Server {
+	pages := map[string]string{
+		"":   `{"items":[{"id":1},{"id":2}],"nextPageToken":"p2"}`,
+		"p2": `{"items":[{"id":3}],"nextPageToken":"p3"}`,
+		"p3": `{"items":[{"id":4}]}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pageSize") != "2" {
+			t.Errorf("pageSize = %q, want the query on every page", r.URL.Query().Get("pageSize"))
+		}
+		page, ok := pages[r.URL.Query().Get("pageToken")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", requests.JSON)
+		fmt.Fprint(w, page)
+	}))
+}
+
+func TestPaginatedRequestItemsKey(t *testing.T) {
+	mockServer := itemsServer(t)
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), nil, nil)
+	items, err := client.PaginatedRequest(context.Background(), "GET", mockServer.URL, map[string]string{"pageSize": "2"}, nil,
+		requests.PageOptions{ItemsKey: "items", TokenKey: "nextPageToken"})
+	if err != nil {
+		t.Fatalf("PaginatedRequest() error = %v", err)
+	}
+
+	var ids []int
+	for _, item := range items {
+		var v struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(item, &v); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", item, err)
+		}
+		ids = append(ids, v.ID)
+	}
+	if want := []int{1, 2, 3, 4}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("PaginatedRequest() ids = %v, want %v", ids, want)
+	}
+}
+
+func TestPaginatedRequestLinkHeader(t *testing.T) {
+	var mockServer *httptest.Server
+	mockServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("after") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?limit=2&after=b>; rel="next", <%s?limit=2>; rel="self"`, mockServer.URL, mockServer.URL))
+			fmt.Fprint(w, `["a","b"]`)
+			return
+		}
+		if got := r.URL.Query()["limit"]; len(got) != 1 {
+			t.Errorf("limit = %v, want the query sent once", got)
+		}
+		fmt.Fprint(w, `["c"]`)
+	}))
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), nil, nil)
+	items, err := client.PaginatedRequest(context.Background(), "GET", mockServer.URL, map[string]string{"limit": "2"}, nil, requests.PageOptions{})
+	if err != nil {
+		t.Fatalf("PaginatedRequest() error = %v", err)
+	}
+
+	want := []json.RawMessage{json.RawMessage(`"a"`), json.RawMessage(`"b"`), json.RawMessage(`"c"`)}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("PaginatedRequest() = %s, want %s", items, want)
+	}
+}
+
+func TestPaginatedRequestFunc(t *testing.T) {
+	mockServer := itemsServer(t)
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), nil, nil)
+	opts := requests.PageOptions{ItemsKey: "items", TokenKey: "nextPageToken"}
+	query := map[string]string{"pageSize": "2"}
+
+	var pages []string
+	err := client.PaginatedRequestFunc(context.Background(), "GET", mockServer.URL, query, nil, opts, func(page []json.RawMessage) error {
+		raw, err := json.Marshal(page)
+		pages = append(pages, string(raw))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("PaginatedRequestFunc() error = %v", err)
+	}
+	want := []string{`[{"id":1},{"id":2}]`, `[{"id":3}]`, `[{"id":4}]`}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("PaginatedRequestFunc() pages = %v, want %v", pages, want)
+	}
+
+	t.Run("Callback Error", func(t *testing.T) {
+		stop := errors.New("stop")
+		var calls int
+		err := client.PaginatedRequestFunc(context.Background(), "GET", mockServer.URL, query, nil, opts, func(page []json.RawMessage) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) {
+			t.Errorf("PaginatedRequestFunc() error = %v, want %v", err, stop)
+		}
+		if calls != 1 {
+			t.Errorf("callback invoked %d times, want pagination to stop after 1", calls)
+		}
+	})
+}
@@ -4,10 +4,17 @@ package requests_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -97,6 +104,88 @@ func TestDecodeJSON(t *testing.T) {
 	}
 }
 
+// arrayReader generates a JSON array of n (> 0) objects on demand and tracks how many bytes have been read
+type arrayReader struct {
+	n, next int
+	pending []byte
+	read    int
+	closed  bool
+}
+
+func (r *arrayReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		switch {
+		case r.next == 0:
+			r.pending = []byte(`[{"id":0}`)
+			r.next++
+		case r.next < r.n:
+			r.pending = []byte(fmt.Sprintf(`,{"id":%d}`, r.next))
+			r.next++
+		case !r.closed:
+			r.pending = []byte(`]`)
+			r.closed = true
+		default:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.read += n
+	return n, nil
+}
+
+func TestDecodeJSONStream(t *testing.T) {
+	const total = 100000
+	r := &arrayReader{n: total}
+
+	count := 0
+	firstRead := 0
+	err := requests.DecodeJSONStream(r, func(raw json.RawMessage) error {
+		var element struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &element); err != nil {
+			return err
+		}
+		if element.ID != count {
+			return fmt.Errorf("element %d has id %d", count, element.ID)
+		}
+		if count == 0 {
+			firstRead = r.read
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DecodeJSONStream() error = %v", err)
+	}
+	if count != total {
+		t.Errorf("DecodeJSONStream() callback fired %d times, want %d", count, total)
+	}
+	if firstRead >= r.read/2 {
+		t.Errorf("DecodeJSONStream() read %d of %d bytes before the first callback, want incremental reads", firstRead, r.read)
+	}
+
+	t.Run("Callback Error", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := requests.DecodeJSONStream(strings.NewReader(`[1,2,3]`), func(json.RawMessage) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("DecodeJSONStream() = %v after %d calls, want %v after 1", err, calls, stop)
+		}
+	})
+
+	t.Run("Not An Array", func(t *testing.T) {
+		err := requests.DecodeJSONStream(strings.NewReader(`{"id":1}`), func(json.RawMessage) error { return nil })
+		if err == nil {
+			t.Error("DecodeJSONStream() expected an error for a non-array body")
+		}
+	})
+}
+
 func TestSetQueryParams(t *testing.T) {
 	type QueryStruct struct {
 		Param1 string `json:"param1"`
@@ -153,6 +242,37 @@ func TestSetQueryParams(t *testing.T) {
 	}
 }
 
+func TestDefaultQuery(t *testing.T) {
+	var got url.Values
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		w.Header().Set("Content-Type", requests.JSON)
+		w.Write([]byte(`{}`))
+	}))
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), nil, nil)
+	client.DefaultQuery = url.Values{"alt": {"json"}, "key": {"default"}}
+
+	query := struct {
+		Key   string `json:"key"`
+		Limit int    `json:"limit"`
+	}{Key: "override", Limit: 10}
+	if _, _, err := client.DoRequest(context.Background(), "GET", mockServer.URL, query, nil); err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+
+	if got.Get("alt") != "json" {
+		t.Errorf("Expected default param alt=json, got %q", got.Get("alt"))
+	}
+	if values := got["key"]; len(values) != 1 || values[0] != "override" {
+		t.Errorf("Expected per-request key to override the default, got %v", values)
+	}
+	if got.Get("limit") != "10" {
+		t.Errorf("Expected per-request param limit=10, got %q", got.Get("limit"))
+	}
+}
+
 func TestSetJSONPayload(t *testing.T) {
 	type PayloadStruct struct {
 		Field1 string   `json:"field1"`
@@ -225,6 +345,25 @@ func TestSetFormURLEncodedPayload(t *testing.T) {
 			"field1=test&field2=123&field3%5B%5D=one&field3%5B%5D=two",
 			true,
 		},
+		{
+			"Nested Form Data",
+			struct {
+				Scopes []string `url:"scopes"`
+				Owner  struct {
+					Name  string `url:"name"`
+					Email string `url:"email"`
+				} `url:"owner"`
+			}{
+				Scopes: []string{"read", "write"},
+				Owner: struct {
+					Name  string `url:"name"`
+					Email string `url:"email"`
+				}{Name: "gemini", Email: "ops@gemini.com"},
+			},
+			false,
+			"owner%5Bemail%5D=ops%40gemini.com&owner%5Bname%5D=gemini&scopes%5B%5D=read&scopes%5B%5D=write",
+			true,
+		},
 		{
 			"Nil Form Data",
 			nil,
@@ -460,6 +599,50 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+// apiError is a structured error produced by a test ErrorParser
+type apiError struct {
+	Code    int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func TestErrorParser(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", requests.JSON)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"code":403,"message":"The caller does not have permission"}}`))
+	}))
+	defer mockServer.Close()
+
+	parser := requests.ErrorParser(func(status int, body []byte) error {
+		var res struct {
+			Error *apiError `json:"error"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil || res.Error == nil {
+			return nil
+		}
+		return res.Error
+	})
+	client := requests.NewClient(mockServer.Client(), requests.Headers{"Content-Type": requests.JSON}, nil, parser)
+
+	_, _, err := client.DoRequest(context.Background(), "GET", mockServer.URL, nil, nil)
+
+	var reqErr *requests.RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("Expected error of type *requests.RequestError, got %T", err)
+	}
+	if reqErr.Message != "The caller does not have permission" {
+		t.Errorf("Expected parsed error message, got '%s'", reqErr.Message)
+	}
+	var parsed *apiError
+	if !errors.As(err, &parsed) || parsed.Code != http.StatusForbidden {
+		t.Errorf("Expected errors.As to find the parsed *apiError, got %v", parsed)
+	}
+}
+
 func TestStatusCodeChecks(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -543,6 +726,37 @@ func TestClientMethods(t *testing.T) {
 		}
 	})
 
+	t.Run("SetHTTPClient", func(t *testing.T) {
+		var used bool
+		custom := &http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				used = true
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+					Header:     make(http.Header),
+					Request:    req,
+				}, nil
+			}),
+		}
+
+		client.SetHTTPClient(custom)
+		if client.HTTPClient() != custom {
+			t.Errorf("Expected HTTPClient to return the custom client")
+		}
+		if _, _, err := client.DoRequest(context.Background(), "GET", "http://gemini.com", nil, nil); err != nil {
+			t.Fatalf("DoRequest() error = %v", err)
+		}
+		if !used {
+			t.Errorf("Expected the request to use the custom client's transport")
+		}
+
+		client.SetHTTPClient(nil)
+		if client.HTTPClient() == nil || client.HTTPClient() == custom {
+			t.Errorf("Expected SetHTTPClient(nil) to restore a default client")
+		}
+	})
+
 	t.Run("ExtractParam", func(t *testing.T) {
 		url := "http://gemini.com?param1=value1&param2=value2"
 		param := client.ExtractParam(url, "param1")
@@ -551,3 +765,514 @@ func TestClientMethods(t *testing.T) {
 		}
 	})
 }
+
+// TestUserAgentAndAccept tests the default User-Agent and Accept headers and that client and per-request headers win.
+func TestUserAgentAndAccept(t *testing.T) {
+	var got http.Header
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	t.Run("Defaults", func(t *testing.T) {
+		client := requests.NewClient(mockServer.Client(), nil, nil)
+		if _, _, err := client.DoRequest(context.Background(), "GET", mockServer.URL, nil, nil); err != nil {
+			t.Fatalf("DoRequest() error = %v", err)
+		}
+		if ua := got.Get("User-Agent"); ua != requests.DefaultUserAgent || !strings.HasPrefix(ua, "rego/") {
+			t.Errorf("User-Agent = %q, want %q", ua, requests.DefaultUserAgent)
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		client := requests.NewClient(mockServer.Client(), nil, nil, requests.UserAgent("sheets-sync/1.0"), requests.Accept(requests.JSON))
+		if _, _, err := client.DoRequest(context.Background(), "GET", mockServer.URL, nil, nil); err != nil {
+			t.Fatalf("DoRequest() error = %v", err)
+		}
+		if ua := got.Get("User-Agent"); ua != "sheets-sync/1.0" {
+			t.Errorf("User-Agent = %q, want %q", ua, "sheets-sync/1.0")
+		}
+		if accept := got.Get("Accept"); accept != requests.JSON {
+			t.Errorf("Accept = %q, want %q", accept, requests.JSON)
+		}
+	})
+
+	t.Run("Overrides", func(t *testing.T) {
+		client := requests.NewClient(mockServer.Client(), requests.Headers{"Accept": requests.XML}, nil, requests.Accept(requests.JSON))
+		if _, _, err := client.DoRequest(context.Background(), "GET", mockServer.URL, nil, nil); err != nil {
+			t.Fatalf("DoRequest() error = %v", err)
+		}
+		if accept := got.Get("Accept"); accept != requests.XML {
+			t.Errorf("Accept with client header = %q, want %q", accept, requests.XML)
+		}
+
+		ctx := requests.WithRequestHeaders(context.Background(), requests.Headers{"Accept": requests.CSS, "User-Agent": "one-off"})
+		if _, _, err := client.DoRequest(ctx, "GET", mockServer.URL, nil, nil); err != nil {
+			t.Fatalf("DoRequest() error = %v", err)
+		}
+		if accept := got.Get("Accept"); accept != requests.CSS {
+			t.Errorf("Accept with per-request header = %q, want %q", accept, requests.CSS)
+		}
+		if ua := got.Get("User-Agent"); ua != "one-off" {
+			t.Errorf("User-Agent with per-request header = %q, want %q", ua, "one-off")
+		}
+	})
+}
+
+// TestMaxResponseBytes tests that responses over the client's size limit fail and those within it succeed.
+func TestMaxResponseBytes(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		// Stream the body in chunks, without a Content-Length
+		for i := 0; i < 64; i++ {
+			fmt.Fprint(w, strings.Repeat("x", 1024))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name    string
+		limit   int64
+		wantErr bool
+	}{
+		{"Unlimited", 0, false},
+		{"Under Limit", 64 * 1024, false},
+		{"Over Limit", 16 * 1024, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := requests.NewClient(mockServer.Client(), nil, nil)
+			client.MaxResponseBytes = tt.limit
+
+			_, body, err := client.DoRequest(context.Background(), "GET", mockServer.URL, nil, nil)
+			if tt.wantErr {
+				if !errors.Is(err, requests.ErrResponseTooLarge) {
+					t.Fatalf("DoRequest() error = %v, want %v", err, requests.ErrResponseTooLarge)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DoRequest() error = %v", err)
+			}
+			if len(body) != 64*1024 {
+				t.Errorf("DoRequest() body length = %d, want %d", len(body), 64*1024)
+			}
+		})
+	}
+}
+
+// TestTooManyRequestsBacksOff tests that a 429 with Retry-After makes the rate limiter back off before the retry.
+func TestTooManyRequestsBacksOff(t *testing.T) {
+	var calls int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	rateLimiter := ratelimit.NewRateLimiter(100)
+	defer rateLimiter.Stop()
+	client := requests.NewClient(mockServer.Client(), nil, rateLimiter)
+
+	start := time.Now()
+	if _, _, err := client.DoRequest(context.Background(), "GET", mockServer.URL, nil, nil); err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*time.Second {
+		t.Errorf("DoRequest() retried after %v, want at least the 2s Retry-After", elapsed)
+	}
+	if calls != 2 {
+		t.Errorf("server calls = %d, want 2", calls)
+	}
+	if state := rateLimiter.State(); state.RetryAfter != 2 || state.BlockedUntil.IsZero() {
+		t.Errorf("State() = %+v, want RetryAfter 2 and a BlockedUntil time", state)
+	}
+}
+
+// TestWithTiming tests that an opted-in request records populated, monotonic phase timings.
+func TestWithTiming(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), nil, nil)
+	ctx, timing := requests.WithTiming(context.Background())
+	if _, _, err := client.DoRequest(ctx, "GET", mockServer.URL, nil, nil); err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+
+	if timing.Connect <= 0 || timing.FirstByte <= 0 || timing.Total <= 0 {
+		t.Fatalf("Timing = %+v, want Connect, FirstByte and Total populated", timing)
+	}
+	if timing.Connect > timing.FirstByte || timing.FirstByte > timing.Total {
+		t.Errorf("Timing = %+v, want Connect <= FirstByte <= Total", timing)
+	}
+	if timing.FirstByte < 10*time.Millisecond {
+		t.Errorf("Timing.FirstByte = %v, want at least the server's 10ms delay", timing.FirstByte)
+	}
+	if timing.ReusedConn {
+		t.Errorf("Timing.ReusedConn = true for the first request, want false")
+	}
+}
+
+// TestTimingExcludesThrottle tests that Total stops once the body is read, before a 429's backoff, and that a failed
+// request still records it.
+func TestTimingExcludesThrottle(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), nil, nil)
+	ctx, timing := requests.WithTiming(requests.WithAcceptedStatus(context.Background(), http.StatusTooManyRequests))
+	start := time.Now()
+	if _, _, err := client.DoRequest(ctx, "GET", mockServer.URL, nil, nil); !errors.Is(err, requests.ErrAcceptedStatus) {
+		t.Fatalf("DoRequest() error = %v, want ErrAcceptedStatus", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("DoRequest() returned after %v, want it to back off for the 1s Retry-After", elapsed)
+	}
+	if timing.Total <= 0 || timing.Total >= 500*time.Millisecond {
+		t.Errorf("Timing.Total = %v, want the request alone, without the backoff", timing.Total)
+	}
+
+	t.Run("Transport Error", func(t *testing.T) {
+		// A cancelled context fails the request in the transport, without retries
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		ctx, timing := requests.WithTiming(cancelled)
+		if _, _, err := client.DoRequest(ctx, "GET", mockServer.URL, nil, nil); err == nil {
+			t.Fatal("DoRequest() error = nil, want a transport error")
+		}
+		if timing.Total <= 0 {
+			t.Errorf("Timing.Total = %v, want it recorded for the failed request", timing.Total)
+		}
+	})
+}
+
+// TestAcceptedStatus tests that accepted non-2xx codes return the body with ErrAcceptedStatus instead of a RequestError.
+func TestAcceptedStatus(t *testing.T) {
+	var calls int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"error":"not found"}`)
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name     string
+		accepted []int
+		ctx      context.Context
+		status   string
+	}{
+		{"Client", []int{http.StatusNotFound}, context.Background(), "404"},
+		{"Per Request", nil, requests.WithAcceptedStatus(context.Background(), http.StatusNotFound), "404"},
+		{"Retryable Status", []int{http.StatusServiceUnavailable}, context.Background(), "503"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			client := requests.NewClient(mockServer.Client(), nil, nil)
+			client.AcceptedStatus = tt.accepted
+
+			resp, body, err := client.DoRequest(tt.ctx, "GET", mockServer.URL, map[string]string{"status": tt.status}, nil)
+			if !errors.Is(err, requests.ErrAcceptedStatus) {
+				t.Fatalf("DoRequest() error = %v, want %v", err, requests.ErrAcceptedStatus)
+			}
+			var reqErr *requests.RequestError
+			if errors.As(err, &reqErr) {
+				t.Errorf("DoRequest() error = %v, want no RequestError", err)
+			}
+			if strconv.Itoa(resp.StatusCode) != tt.status || string(body) != `{"error":"not found"}` {
+				t.Errorf("DoRequest() = %d %s, want %s with the body", resp.StatusCode, body, tt.status)
+			}
+			if calls != 1 {
+				t.Errorf("server calls = %d, want 1 (no retries)", calls)
+			}
+		})
+	}
+
+	t.Run("Not Accepted", func(t *testing.T) {
+		client := requests.NewClient(mockServer.Client(), nil, nil)
+		_, _, err := client.DoRequest(context.Background(), "GET", mockServer.URL, map[string]string{"status": "404"}, nil)
+		var reqErr *requests.RequestError
+		if !errors.As(err, &reqErr) || errors.Is(err, requests.ErrAcceptedStatus) {
+			t.Errorf("DoRequest() error = %v, want a RequestError", err)
+		}
+	})
+}
+
+// TestClone tests that a cloned client's settings change independently of the original.
+func TestClone(t *testing.T) {
+	original := requests.NewClient(nil, requests.Headers{"Content-Type": requests.JSON, "X-Team": "iam"}, nil)
+	original.DefaultQuery = url.Values{"fields": {"id"}}
+
+	clone := original.Clone(requests.Accept(requests.XML))
+	clone.Headers["X-Team"] = "infra"
+	clone.UpdateContentType(requests.FormURLEncoded)
+	clone.DefaultQuery.Add("fields", "name")
+
+	if original.Headers["X-Team"] != "iam" || original.Headers["Content-Type"] != requests.JSON {
+		t.Errorf("original Headers = %v, want them unchanged by the clone", original.Headers)
+	}
+	if got := original.DefaultQuery["fields"]; !reflect.DeepEqual(got, []string{"id"}) {
+		t.Errorf("original DefaultQuery = %v, want it unchanged by the clone", got)
+	}
+	if original.Accept != "" || clone.Accept != requests.XML {
+		t.Errorf("Accept = %q (original), %q (clone), want the option applied to the clone only", original.Accept, clone.Accept)
+	}
+	if clone.Headers["X-Team"] != "infra" || clone.Headers["Content-Type"] != requests.FormURLEncoded {
+		t.Errorf("clone Headers = %v, want the clone's changes", clone.Headers)
+	}
+
+	if clone.RateLimiter != original.RateLimiter {
+		t.Errorf("Clone() rate limiter is not shared by default")
+	}
+	limiter := ratelimit.NewRateLimiter(10)
+	defer limiter.Stop()
+	if own := original.Clone(limiter); own.RateLimiter != limiter || original.RateLimiter == limiter {
+		t.Errorf("Clone(limiter) did not give only the clone its own rate limiter")
+	}
+}
+
+// TestHead tests that HEAD requests return the status and headers without a body-read error.
+func TestHead(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Method = %s, want HEAD", r.Method)
+		}
+		if r.URL.Path == "/missing" {
+			w.Header().Set("Content-Type", requests.JSON)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", requests.PDF)
+		w.Header().Set("Content-Length", "2048")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), requests.Headers{"Content-Type": requests.JSON}, nil)
+	client.UpdateBodyType(requests.JSON)
+
+	status, headers, err := client.Head(context.Background(), mockServer.URL+"/report.pdf", map[string]string{"alt": "media"})
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	if status != http.StatusOK || headers.Get("Content-Length") != "2048" || headers.Get("Content-Type") != requests.PDF {
+		t.Errorf("Head() = %d %v, want 200 with Content-Length 2048", status, headers)
+	}
+
+	status, _, err = client.Head(context.Background(), mockServer.URL+"/missing", nil)
+	var reqErr *requests.RequestError
+	if status != http.StatusNotFound || !errors.As(err, &reqErr) || reqErr.Message != "Not Found" {
+		t.Errorf("Head() = %d, %v; want 404 with a Not Found RequestError", status, err)
+	}
+}
+
+// TestAllowedMethods tests that the Allow header of an OPTIONS response is parsed into methods.
+func TestAllowedMethods(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			t.Errorf("Method = %s, want OPTIONS", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "" {
+			t.Errorf("Content-Type = %q, want none on a bodiless OPTIONS request", ct)
+		}
+		w.Header().Add("Allow", "GET, head,POST")
+		w.Header().Add("Allow", "OPTIONS, GET")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), requests.Headers{"Content-Type": requests.JSON}, nil)
+	client.UpdateBodyType(requests.JSON)
+
+	got, err := client.AllowedMethods(context.Background(), mockServer.URL)
+	if err != nil {
+		t.Fatalf("AllowedMethods() error = %v", err)
+	}
+	want := []string{"GET", "HEAD", "POST", "OPTIONS"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AllowedMethods() = %v, want %v", got, want)
+	}
+}
+
+// TestMergeHeaders tests that merged headers are canonicalized, with override and canonical keys winning.
+func TestMergeHeaders(t *testing.T) {
+	base := requests.Headers{"content-type": requests.XML, "x-api-key": "abc"}
+	override := requests.Headers{"Content-Type": requests.JSON, "accept": requests.All, "ACCEPT": requests.Plain, "Accept": requests.JSON}
+
+	got := requests.MergeHeaders(base, override)
+	want := requests.Headers{"Content-Type": requests.JSON, "X-Api-Key": "abc", "Accept": requests.JSON}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeHeaders() = %v, want %v", got, want)
+	}
+	if _, ok := base["Content-Type"]; ok {
+		t.Errorf("MergeHeaders() modified base: %v", base)
+	}
+}
+
+// TestLowercaseContentType tests that a lowercase content-type header still selects JSON encoding.
+func TestLowercaseContentType(t *testing.T) {
+	var gotType []string
+	var gotBody string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotType = r.Header.Values("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), requests.Headers{"content-type": requests.JSON}, nil)
+	client.EncodeByContentType = true
+	if _, _, err := client.DoRequest(context.Background(), "POST", mockServer.URL, nil, map[string]string{"name": "rego"}); err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	if !reflect.DeepEqual(gotType, []string{requests.JSON}) {
+		t.Errorf("Content-Type = %v, want a single %s", gotType, requests.JSON)
+	}
+	if gotBody != `{"name":"rego"}` {
+		t.Errorf("Body = %q, want the JSON-encoded payload", gotBody)
+	}
+
+	t.Run("Default Sends No Body", func(t *testing.T) {
+		client := requests.NewClient(mockServer.Client(), requests.Headers{"Content-Type": requests.JSON}, nil)
+		if _, _, err := client.DoRequest(context.Background(), "POST", mockServer.URL, nil, map[string]string{"name": "rego"}); err != nil {
+			t.Fatalf("DoRequest() error = %v", err)
+		}
+		if gotBody != "" {
+			t.Errorf("Body = %q, want none without a BodyType", gotBody)
+		}
+	})
+}
+
+// TestConcurrentDoRequest fires concurrent requests through one client and rate limiter while its settings change;
+// run with -race to check the client's synchronization.
+func TestConcurrentDoRequest(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Rate-Limit-Limit", "1000")
+		w.Header().Set("X-Rate-Limit-Remaining", "900")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer mockServer.Close()
+
+	rateLimiter := ratelimit.NewRateLimiter(1000)
+	defer rateLimiter.Stop()
+	client := requests.NewClient(mockServer.Client(), requests.Headers{"Content-Type": requests.JSON}, rateLimiter)
+	client.UpdateBodyType(requests.JSON)
+
+	const workers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, body, err := client.DoRequest(context.Background(), "POST", mockServer.URL, map[string]string{"n": fmt.Sprint(i)}, map[string]int{"n": i})
+			if err == nil && string(body) != `{"ok":true}` {
+				err = fmt.Errorf("unexpected body %q", body)
+			}
+			errs <- err
+		}(i)
+	}
+
+	// Change the client's settings while requests are in flight
+	for i := 0; i < workers; i++ {
+		client.UpdateContentType(requests.JSON)
+		client.UpdateBodyType(requests.JSON)
+		client.SetHTTPClient(mockServer.Client())
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("DoRequest() error = %v", err)
+		}
+	}
+}
+
+func TestRetriedPOSTResendsBody(t *testing.T) {
+	payload := map[string]interface{}{"field1": "value1"}
+	expectedBody := `{"field1":"value1"}`
+
+	var bodies []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	client := requests.NewClient(mockServer.Client(), requests.Headers{"Content-Type": requests.JSON}, nil)
+	client.BodyType = requests.JSON
+
+	t.Run("Retry", func(t *testing.T) {
+		bodies = nil
+		_, _, err := client.DoRequest(context.Background(), "POST", mockServer.URL, nil, payload)
+		if err != nil {
+			t.Fatalf("DoRequest() unexpected error: %v", err)
+		}
+		if len(bodies) != 2 {
+			t.Fatalf("Expected 2 requests, got %d", len(bodies))
+		}
+		for i, body := range bodies {
+			if body != expectedBody {
+				t.Errorf("Request %d body = %q, want %q", i, body, expectedBody)
+			}
+		}
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		bodies = []string{"skip retry"}
+		_, _, err := client.DoRequest(context.Background(), "POST", mockServer.URL+"/redirect", nil, payload)
+		if err != nil {
+			t.Fatalf("DoRequest() unexpected error: %v", err)
+		}
+		if len(bodies) != 2 || bodies[1] != expectedBody {
+			t.Errorf("Redirected body = %v, want %q", bodies[1:], expectedBody)
+		}
+	})
+
+	t.Run("GetBody", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "http://gemini.com", nil)
+		if err := requests.SetJSONPayload(req, payload); err != nil {
+			t.Fatalf("SetJSONPayload() error = %v", err)
+		}
+		io.ReadAll(req.Body)
+		if req.GetBody == nil {
+			t.Fatal("SetJSONPayload() did not set GetBody")
+		}
+		rc, _ := req.GetBody()
+		body, _ := io.ReadAll(rc)
+		if string(body) != expectedBody {
+			t.Errorf("GetBody() = %q, want %q", string(body), expectedBody)
+		}
+	})
+}
@@ -2,9 +2,19 @@
 package starstruct_test
 
 import (
+	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gemini-oss/rego/pkg/common/starstruct"
 )
@@ -64,6 +74,70 @@ func TestPrettyJSON(t *testing.T) {
 	}
 }
 
+// TestPrettyJSONWithOptions tests the indent and HTML escaping options of PrettyJSONWithOptions.
+func TestPrettyJSONWithOptions(t *testing.T) {
+	data := map[string]string{"query": "a < b && c > d"}
+
+	tests := []struct {
+		name       string
+		indent     string
+		escapeHTML bool
+		want       string
+	}{
+		{
+			name:       "Escaped",
+			indent:     "  ",
+			escapeHTML: true,
+			want:       "{\n  \"query\": \"a \\u003c b \\u0026\\u0026 c \\u003e d\"\n}\n",
+		},
+		{
+			name:       "Literal",
+			indent:     "\t",
+			escapeHTML: false,
+			want:       "{\n\t\"query\": \"a < b && c > d\"\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := starstruct.PrettyJSONWithOptions(data, tt.indent, tt.escapeHTML)
+			if err != nil {
+				t.Fatalf("PrettyJSONWithOptions() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PrettyJSONWithOptions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCompactJSON tests that CompactJSON produces single-line output equivalent to PrettyJSON.
+func TestCompactJSON(t *testing.T) {
+	compact, err := starstruct.CompactJSON(defaultTestStruct)
+	if err != nil {
+		t.Fatalf("CompactJSON() error = %v", err)
+	}
+	pretty, err := starstruct.PrettyJSON(defaultTestStruct)
+	if err != nil {
+		t.Fatalf("PrettyJSON() error = %v", err)
+	}
+
+	want := `{"name":"Anthony Dardano","age":0,"tags":["Staff Enterprise Infrastructure Engineer","DJ"],"address":{"city":"N/A","state":"FL"}}`
+	if compact != want {
+		t.Errorf("CompactJSON() = %q, want %q", compact, want)
+	}
+	if strings.HasSuffix(compact, "\n") {
+		t.Error("CompactJSON() output has a trailing newline")
+	}
+
+	var compactJSON, prettyJSON interface{}
+	json.Unmarshal([]byte(compact), &compactJSON)
+	json.Unmarshal([]byte(pretty), &prettyJSON)
+	if !reflect.DeepEqual(compactJSON, prettyJSON) {
+		t.Errorf("CompactJSON() = %v, PrettyJSON() = %v", compact, pretty)
+	}
+}
+
 // TestStructToMap tests the StructToMap function for various struct inputs.
 func TestToMap(t *testing.T) {
 	testStruct := defaultTestStruct
@@ -101,6 +175,94 @@ func TestToMap(t *testing.T) {
 	}
 }
 
+// TestToMapOpts tests that ToMapOpts honors WithIncludeZero and WithExcludeNil.
+func TestToMapOpts(t *testing.T) {
+	type inner struct {
+		City string `json:"city"`
+	}
+	type outer struct {
+		Name    string            `json:"name"`
+		Age     int               `json:"age"`
+		Email   string            `json:"email"`
+		Address *inner            `json:"address"`
+		Tags    []string          `json:"tags"`
+		Labels  map[string]string `json:"labels"`
+	}
+	item := outer{Name: "Anthony"}
+
+	tests := []struct {
+		name     string
+		opts     []starstruct.Option
+		wantKeys []string
+	}{
+		{
+			name:     "Defaults",
+			wantKeys: []string{"name"},
+		},
+		{
+			name:     "Include Zero",
+			opts:     []starstruct.Option{starstruct.WithIncludeZero()},
+			wantKeys: []string{"address", "age", "email", "labels", "name", "tags"},
+		},
+		{
+			name:     "Include Zero Exclude Nil",
+			opts:     []starstruct.Option{starstruct.WithIncludeZero(), starstruct.WithExcludeNil()},
+			wantKeys: []string{"age", "email", "name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := starstruct.ToMapOpts(item, tt.opts...)
+			if err != nil {
+				t.Fatalf("ToMapOpts() error = %v", err)
+			}
+			keys := make([]string, 0, len(got))
+			for k := range got {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("ToMapOpts() keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+// TestToMapNested tests that ToMap converts map and pointer fields into nested maps.
+func TestToMapNested(t *testing.T) {
+	type inner struct {
+		City  string `json:"city"`
+		State string `json:"state"`
+	}
+	type outer struct {
+		Name    string           `json:"name"`
+		Offices map[string]inner `json:"offices"`
+		Home    *inner           `json:"home"`
+	}
+	item := outer{
+		Name:    "Anthony",
+		Offices: map[string]inner{"hq": {City: "New York", State: "NY"}},
+		Home:    &inner{City: "Miami", State: "FL"},
+	}
+
+	want := map[string]interface{}{
+		"name": "Anthony",
+		"offices": map[string]interface{}{
+			"hq": map[string]interface{}{"city": "New York", "state": "NY"},
+		},
+		"home": map[string]interface{}{"city": "Miami", "state": "FL"},
+	}
+
+	got, err := starstruct.ToMap(item, false)
+	if err != nil {
+		t.Fatalf("ToMap() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToMap() = %#v, want %#v", got, want)
+	}
+}
+
 // TestFlattenStructFields tests the FlattenStructFields function for various struct inputs.
 func TestFlattenStructFields(t *testing.T) {
 	testStruct := defaultTestStruct
@@ -147,6 +309,111 @@ func TestFlattenStructFieldsSelective(t *testing.T) {
 	}
 }
 
+// TestFlattenStructFieldsHeadersUnchanged tests that a headers template can be reused across calls without being expanded in place.
+func TestFlattenStructFieldsHeadersUnchanged(t *testing.T) {
+	fields := []string{"name", "tags", "address.state"}
+	headers := &fields
+
+	first, err := starstruct.FlattenStructFields(defaultTestStruct, starstruct.WithHeaders(headers))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	if !reflect.DeepEqual(*headers, []string{"name", "tags", "address.state"}) {
+		t.Fatalf("FlattenStructFields() modified headers to %v", *headers)
+	}
+
+	other := defaultTestStruct
+	other.Tags = append(append([]string(nil), other.Tags...), "Third")
+	second, err := starstruct.FlattenStructFields(other, starstruct.WithHeaders(headers))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	if !reflect.DeepEqual(*headers, []string{"name", "tags", "address.state"}) {
+		t.Errorf("FlattenStructFields() modified headers to %v", *headers)
+	}
+	if len(second) != len(first)+1 {
+		t.Errorf("FlattenStructFields() second call = %v, want one more tag than %v", second, first)
+	}
+}
+
+// TestWithColumnOrder tests that WithColumnOrder reorders generated columns without dropping any.
+func TestWithColumnOrder(t *testing.T) {
+	got, err := starstruct.FlattenStructFields(defaultTestStruct,
+		starstruct.WithGenerate(),
+		starstruct.WithColumnOrder([]string{"address.state", "tags", "age"}),
+	)
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+
+	want := [][]string{
+		{"address.state", "FL"},
+		{"tags.00", "Staff Enterprise Infrastructure Engineer"},
+		{"tags.01", "DJ"},
+		{"age", "0"},
+		{"name", "Anthony Dardano"},
+		{"address.city", "N/A"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenStructFields() = %v, want %v", got, want)
+	}
+}
+
+// TestFlattenStructFieldsStrict tests that WithStrict rejects headers matching no field, while the default stays lenient.
+func TestFlattenStructFieldsStrict(t *testing.T) {
+	fields := []string{"name", "nickname", "tags", "address.zip"}
+
+	got, err := starstruct.FlattenStructFields(defaultTestStruct, starstruct.WithHeaders(&fields))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v, wantErr false", err)
+	}
+	found := false
+	for _, pair := range got {
+		if pair[0] == "nickname" {
+			found = pair[1] == ""
+		}
+	}
+	if !found {
+		t.Errorf("FlattenStructFields() = %v, want empty nickname column", got)
+	}
+
+	fields = []string{"name", "nickname", "tags", "address.zip"}
+	_, err = starstruct.FlattenStructFields(defaultTestStruct, starstruct.WithHeaders(&fields), starstruct.WithStrict())
+	if !errors.Is(err, starstruct.ErrUnknownHeaders) {
+		t.Fatalf("FlattenStructFields() error = %v, want %v", err, starstruct.ErrUnknownHeaders)
+	}
+	if !strings.Contains(err.Error(), "nickname, address.zip") {
+		t.Errorf("FlattenStructFields() error = %q, want it to list nickname and address.zip", err)
+	}
+
+	fields = []string{"name", "tags", "address"}
+	if _, err := starstruct.FlattenStructFields(defaultTestStruct, starstruct.WithHeaders(&fields), starstruct.WithStrict()); err != nil {
+		t.Errorf("FlattenStructFields() error = %v, want nil for known headers", err)
+	}
+}
+
+// TestFlattenToOrderedMap tests that FlattenToOrderedMap returns pairs in header order and leaves the headers untouched.
+func TestFlattenToOrderedMap(t *testing.T) {
+	fields := []string{"address.state", "name", "tags"}
+	want := []starstruct.FieldPair{
+		{Key: "address.state", Value: "FL"},
+		{Key: "name", Value: "Anthony Dardano"},
+		{Key: "tags.00", Value: "Staff Enterprise Infrastructure Engineer"},
+		{Key: "tags.01", Value: "DJ"},
+	}
+
+	got, err := starstruct.FlattenToOrderedMap(defaultTestStruct, starstruct.WithHeaders(&fields))
+	if err != nil {
+		t.Fatalf("FlattenToOrderedMap() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenToOrderedMap() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(fields, []string{"address.state", "name", "tags"}) {
+		t.Errorf("FlattenToOrderedMap() modified headers to %v", fields)
+	}
+}
+
 // TestGenerateFieldNames tests the FlattenStructFields function for dynamic field generation using TestStruct.
 func TestGenerateFieldNames(t *testing.T) {
 	testStruct := defaultTestStruct
@@ -231,6 +498,84 @@ func TestKeyOrderingInMap(t *testing.T) {
 	}
 }
 
+// TestNumericMapKeyOrdering tests that map keys which are all integers sort numerically rather than lexicographically.
+func TestNumericMapKeyOrdering(t *testing.T) {
+	item := struct {
+		Months map[string]int `json:"months"`
+	}{Months: map[string]int{}}
+
+	var want [][]string
+	for i := 1; i <= 12; i++ {
+		item.Months[strconv.Itoa(i)] = i
+		want = append(want, []string{"months." + strconv.Itoa(i), strconv.Itoa(i)})
+	}
+
+	got, err := starstruct.FlattenStructFields(item, starstruct.WithGenerate())
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenStructFields() = %v, want %v", got, want)
+	}
+
+	item.Months["Q1"] = 0
+	fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf(item))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	if (*fields)[0] != "months.1" || (*fields)[1] != "months.10" || (*fields)[len(*fields)-1] != "months.Q1" {
+		t.Errorf("GenerateFieldNames() = %v, want lexicographic order for mixed keys", *fields)
+	}
+}
+
+// TestMapKeyFormatting tests that integer map keys are formatted canonically and that keys containing the "."
+// delimiter are escaped rather than colliding with nested paths.
+func TestMapKeyFormatting(t *testing.T) {
+	type inventory struct {
+		Counts map[int]string               `json:"counts"`
+		Hosts  map[string]string            `json:"hosts"`
+		Nested map[string]map[string]string `json:"nested"`
+	}
+	item := inventory{
+		Counts: map[int]string{-1: "minus", 7: "seven"},
+		Hosts:  map[string]string{"example.com": "dotted", `back\slash`: "escaped"},
+		Nested: map[string]map[string]string{"example": {"com": "nested"}},
+	}
+
+	got := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(item, "", &got); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	want := map[string]string{
+		"counts.-1":          "minus",
+		"counts.7":           "seven",
+		`hosts.example\.com`: "dotted",
+		`hosts.back\\slash`:  "escaped",
+		"nested.example.com": "nested",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenNestedStructs() = %v, want %v", got, want)
+	}
+
+	fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf(item))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	for _, field := range *fields {
+		if _, ok := want[field]; !ok {
+			t.Errorf("GenerateFieldNames() produced %q, which FlattenNestedStructs did not", field)
+		}
+	}
+
+	var back inventory
+	if err := starstruct.MapToStruct(got, &back); err != nil {
+		t.Fatalf("MapToStruct() error = %v", err)
+	}
+	if !reflect.DeepEqual(back, item) {
+		t.Errorf("MapToStruct() = %+v, want %+v", back, item)
+	}
+}
+
 // TestComplexNestedStructureOrdering ensures that nested structures are ordered properly.
 func TestComplexNestedStructureOrdering(t *testing.T) {
 	testStruct := struct {
@@ -317,3 +662,1503 @@ func TestFromTableToStructs(t *testing.T) {
 		}
 	}
 }
+
+// TestDerefPointers tests that nil pointers dereference to the zero value of the pointed-to type.
+func TestDerefPointers(t *testing.T) {
+	var nilString *string
+	var nilInt *int
+	var nilStruct *TestStruct
+	name := "Anthony"
+	namePtr := &name
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		wantType reflect.Type
+		want     interface{}
+	}{
+		{"Nil *string", nilString, reflect.TypeOf(""), ""},
+		{"Nil **int", &nilInt, reflect.TypeOf(0), 0},
+		{"Nil *struct", nilStruct, reflect.TypeOf(TestStruct{}), TestStruct{}},
+		{"Non-nil **string", &namePtr, reflect.TypeOf(""), "Anthony"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := starstruct.DerefPointers(reflect.ValueOf(tt.input))
+			if err != nil {
+				t.Fatalf("DerefPointers() error = %v", err)
+			}
+			if !got.IsValid() || got.Type() != tt.wantType {
+				t.Fatalf("DerefPointers() type = %v, want %v", got, tt.wantType)
+			}
+			if !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("DerefPointers() = %v, want %v", got.Interface(), tt.want)
+			}
+		})
+	}
+}
+
+// TestNilPointerFieldColumns tests that a nil pointer-struct field gets the same single key from GenerateFieldNames as
+// from flattening, while its fields stay valid headers under WithStrict.
+func TestNilPointerFieldColumns(t *testing.T) {
+	type Profile struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	type user struct {
+		ID      string   `json:"id"`
+		Profile *Profile `json:"profile"`
+	}
+	u := user{ID: "1"}
+
+	fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf(u))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	flat := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(u, "", &flat); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	headers := append([]string(nil), *fields...)
+	sort.Strings(headers)
+	if !reflect.DeepEqual(headers, keys) {
+		t.Errorf("GenerateFieldNames() = %v, flattened keys = %v, want the same keys", headers, keys)
+	}
+
+	rows, err := starstruct.FlattenStructFields(u, starstruct.WithGenerate())
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	wantRows := [][]string{{"id", "1"}, {"profile", "<nil>"}}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("FlattenStructFields() = %v, want %v", rows, wantRows)
+	}
+
+	if _, err := starstruct.FlattenStructFields(u, starstruct.WithHeaders(&[]string{"id", "profile.name"}), starstruct.WithStrict()); err != nil {
+		t.Errorf("FlattenStructFields() with WithStrict error = %v, want nil for a field of the nil struct", err)
+	}
+}
+
+// TestFlattenNestedStructsInterface tests that interface fields holding scalars, maps, and slices are flattened.
+func TestFlattenNestedStructsInterface(t *testing.T) {
+	type withExtra struct {
+		Name  string      `json:"name"`
+		Extra interface{} `json:"extra"`
+	}
+
+	tests := []struct {
+		name  string
+		extra interface{}
+		want  map[string]string
+	}{
+		{
+			name:  "String",
+			extra: "DJ",
+			want:  map[string]string{"name": "Anthony", "extra": "DJ"},
+		},
+		{
+			name:  "Map",
+			extra: map[string]interface{}{"city": "Miami", "state": "FL"},
+			want:  map[string]string{"name": "Anthony", "extra.city": "Miami", "extra.state": "FL"},
+		},
+		{
+			name:  "Slice",
+			extra: []string{"Engineer", "DJ"},
+			want:  map[string]string{"name": "Anthony", "extra.00": "Engineer", "extra.01": "DJ"},
+		},
+		{
+			name:  "Nil",
+			extra: nil,
+			want:  map[string]string{"name": "Anthony"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			err := starstruct.FlattenNestedStructs(withExtra{Name: "Anthony", Extra: tt.extra}, "", &got)
+			if err != nil {
+				t.Fatalf("FlattenNestedStructs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FlattenNestedStructs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEmbeddedStructInline tests that anonymous embedded structs are promoted to the parent level, like encoding/json.
+func TestEmbeddedStructInline(t *testing.T) {
+	type Base struct {
+		ID      string `json:"id"`
+		Created string `json:"created"`
+	}
+	type user struct {
+		Base
+		Name string `json:"name"`
+	}
+	type taggedUser struct {
+		Base `json:"base"`
+		Name string `json:"name"`
+	}
+
+	u := user{Base: Base{ID: "1", Created: "today"}, Name: "Anthony"}
+	tu := taggedUser{Base: Base{ID: "1", Created: "today"}, Name: "Anthony"}
+
+	t.Run("GenerateFieldNames", func(t *testing.T) {
+		got, err := starstruct.GenerateFieldNames("", reflect.ValueOf(u))
+		if err != nil {
+			t.Fatalf("GenerateFieldNames() error = %v", err)
+		}
+		want := []string{"id", "created", "name"}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("GenerateFieldNames() = %v, want %v", *got, want)
+		}
+
+		got, err = starstruct.GenerateFieldNames("", reflect.ValueOf(tu))
+		if err != nil {
+			t.Fatalf("GenerateFieldNames() error = %v", err)
+		}
+		want = []string{"base.id", "base.created", "name"}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("GenerateFieldNames() with explicit name = %v, want %v", *got, want)
+		}
+	})
+
+	t.Run("FlattenNestedStructs", func(t *testing.T) {
+		got := map[string]string{}
+		if err := starstruct.FlattenNestedStructs(u, "", &got); err != nil {
+			t.Fatalf("FlattenNestedStructs() error = %v", err)
+		}
+		want := map[string]string{"id": "1", "created": "today", "name": "Anthony"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("FlattenNestedStructs() = %v, want %v", got, want)
+		}
+
+		got = map[string]string{}
+		if err := starstruct.FlattenNestedStructs(tu, "", &got); err != nil {
+			t.Fatalf("FlattenNestedStructs() error = %v", err)
+		}
+		want = map[string]string{"base.id": "1", "base.created": "today", "name": "Anthony"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("FlattenNestedStructs() with explicit name = %v, want %v", got, want)
+		}
+	})
+}
+
+// TestEmbeddedPointerStructInline tests that an anonymous embedded *struct promotes its fields like a value embed.
+func TestEmbeddedPointerStructInline(t *testing.T) {
+	type Base struct {
+		ID      string `json:"id"`
+		Created string `json:"created"`
+	}
+	type user struct {
+		*Base
+		Name string `json:"name"`
+	}
+
+	t.Run("Non-nil Embed", func(t *testing.T) {
+		u := user{Base: &Base{ID: "1", Created: "today"}, Name: "Anthony"}
+
+		fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf(u))
+		if err != nil {
+			t.Fatalf("GenerateFieldNames() error = %v", err)
+		}
+		wantFields := []string{"id", "created", "name"}
+		if !reflect.DeepEqual(*fields, wantFields) {
+			t.Errorf("GenerateFieldNames() = %v, want %v", *fields, wantFields)
+		}
+
+		got := map[string]string{}
+		if err := starstruct.FlattenNestedStructs(u, "", &got); err != nil {
+			t.Fatalf("FlattenNestedStructs() error = %v", err)
+		}
+		want := map[string]string{"id": "1", "created": "today", "name": "Anthony"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("FlattenNestedStructs() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Nil Embed", func(t *testing.T) {
+		u := user{Name: "Anthony"}
+
+		got := map[string]string{}
+		if err := starstruct.FlattenNestedStructs(u, "", &got); err != nil {
+			t.Fatalf("FlattenNestedStructs() error = %v", err)
+		}
+		want := map[string]string{"name": "Anthony"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("FlattenNestedStructs() = %v, want %v", got, want)
+		}
+
+		rows, err := starstruct.FlattenStructFields(u, starstruct.WithHeaders(&[]string{"id", "name"}))
+		if err != nil {
+			t.Fatalf("FlattenStructFields() error = %v", err)
+		}
+		wantRows := [][]string{{"id", ""}, {"name", "Anthony"}}
+		if !reflect.DeepEqual(rows, wantRows) {
+			t.Errorf("FlattenStructFields() = %v, want %v", rows, wantRows)
+		}
+
+		fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf(u))
+		if err != nil {
+			t.Fatalf("GenerateFieldNames() error = %v", err)
+		}
+		wantFields := []string{"id", "created", "name"}
+		if !reflect.DeepEqual(*fields, wantFields) {
+			t.Errorf("GenerateFieldNames() = %v, want %v", *fields, wantFields)
+		}
+
+		fields, err = starstruct.GenerateFieldNames("", reflect.ValueOf(u), starstruct.WithExcludeNil())
+		if err != nil {
+			t.Fatalf("GenerateFieldNames() with WithExcludeNil error = %v", err)
+		}
+		wantFields = []string{"name"}
+		if !reflect.DeepEqual(*fields, wantFields) {
+			t.Errorf("GenerateFieldNames() with WithExcludeNil = %v, want %v", *fields, wantFields)
+		}
+	})
+}
+
+// TestDurationFields tests that time.Duration fields flatten readably, or as a count of WithDurationUnit's unit.
+func TestDurationFields(t *testing.T) {
+	type job struct {
+		Name    string         `json:"name"`
+		Timeout time.Duration  `json:"timeout"`
+		Retry   *time.Duration `json:"retry"`
+	}
+	retry := 90 * time.Second
+	j := job{Name: "sync", Timeout: time.Hour, Retry: &retry}
+
+	tests := []struct {
+		name string
+		opts []starstruct.Option
+		want map[string]string
+	}{
+		{"Default", nil, map[string]string{"name": "sync", "timeout": "1h0m0s", "retry": "1m30s"}},
+		{"Minutes", []starstruct.Option{starstruct.WithDurationUnit(time.Minute)}, map[string]string{"name": "sync", "timeout": "60", "retry": "1.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			if err := starstruct.FlattenNestedStructs(j, "", &got, tt.opts...); err != nil {
+				t.Fatalf("FlattenNestedStructs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FlattenNestedStructs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	rows, err := starstruct.FlattenStructFields(j, starstruct.WithGenerate(), starstruct.WithDurationUnit(time.Second))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	want := [][]string{{"name", "sync"}, {"timeout", "3600"}, {"retry", "90"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("FlattenStructFields() = %v, want %v", rows, want)
+	}
+
+	// A duration rendered as "1h0m0s" is text, so it must not be reported as a numeric leaf
+	kinds, err := starstruct.FlattenStructKinds(j, "")
+	if err != nil {
+		t.Fatalf("FlattenStructKinds() error = %v", err)
+	}
+	if kind, ok := kinds["timeout"]; ok {
+		t.Errorf("FlattenStructKinds()[timeout] = %v, want no kind", kind)
+	}
+	kinds, err = starstruct.FlattenStructKinds(j, "", starstruct.WithDurationUnit(time.Second))
+	if err != nil {
+		t.Fatalf("FlattenStructKinds() error = %v", err)
+	}
+	if kinds["timeout"] != reflect.Float64 {
+		t.Errorf("FlattenStructKinds()[timeout] = %v, want %v", kinds["timeout"], reflect.Float64)
+	}
+}
+
+// uuid is a minimal array-backed UUID, like the types most UUID libraries provide
+type uuid [16]byte
+
+func (u uuid) MarshalText() ([]byte, error) {
+	h := hex.EncodeToString(u[:])
+	return []byte(h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]), nil
+}
+
+// TestTextMarshalerFields tests that encoding.TextMarshaler fields flatten to a single column holding their text form.
+func TestTextMarshalerFields(t *testing.T) {
+	type device struct {
+		ID    uuid    `json:"id"`
+		IP    net.IP  `json:"ip"`
+		Peers []uuid  `json:"peers"`
+		Proxy *net.IP `json:"proxy"`
+	}
+	id := uuid{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	d := device{ID: id, IP: net.ParseIP("192.168.1.10"), Peers: []uuid{id}}
+
+	fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf(d))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	wantFields := []string{"id", "ip", "peers", "proxy"}
+	if !reflect.DeepEqual(*fields, wantFields) {
+		t.Errorf("GenerateFieldNames() = %v, want %v", *fields, wantFields)
+	}
+
+	got := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(d, "", &got); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	want := map[string]string{
+		"id":       "123e4567-e89b-12d3-a456-426614174000",
+		"ip":       "192.168.1.10",
+		"peers.00": "123e4567-e89b-12d3-a456-426614174000",
+		"proxy":    "<nil>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenNestedStructs() = %v, want %v", got, want)
+	}
+}
+
+// TestWithFloatFormat tests that float leaves use the configured strconv.FormatFloat format and precision.
+func TestWithFloatFormat(t *testing.T) {
+	type reading struct {
+		Total float64   `json:"total"`
+		Ratio float32   `json:"ratio"`
+		Rates []float64 `json:"rates"`
+	}
+	r := reading{Total: 1e6, Ratio: 0.1, Rates: []float64{1.0 / 3}}
+
+	tests := []struct {
+		name string
+		opts []starstruct.Option
+		want map[string]string
+	}{
+		{"Default", nil, map[string]string{"total": "1e+06", "ratio": "0.1", "rates.00": "0.3333333333333333"}},
+		{"Fixed", []starstruct.Option{starstruct.WithFloatFormat('f', 2)}, map[string]string{"total": "1000000.00", "ratio": "0.10", "rates.00": "0.33"}},
+		{"Shortest", []starstruct.Option{starstruct.WithFloatFormat('f', -1)}, map[string]string{"total": "1000000", "ratio": "0.1", "rates.00": "0.3333333333333333"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			if err := starstruct.FlattenNestedStructs(r, "", &got, tt.opts...); err != nil {
+				t.Fatalf("FlattenNestedStructs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FlattenNestedStructs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWithBoolFormat tests that bool leaves render in each configured style and read back with MapToStruct.
+func TestWithBoolFormat(t *testing.T) {
+	type account struct {
+		Active bool  `json:"active"`
+		Admin  *bool `json:"admin"`
+	}
+	admin := false
+	a := account{Active: true, Admin: &admin}
+
+	tests := []struct {
+		name   string
+		format starstruct.BoolFormat
+		want   map[string]string
+	}{
+		{"TrueFalse", starstruct.BoolTrueFalse, map[string]string{"active": "true", "admin": "false"}},
+		{"Upper", starstruct.BoolUpper, map[string]string{"active": "TRUE", "admin": "FALSE"}},
+		{"YesNo", starstruct.BoolYesNo, map[string]string{"active": "yes", "admin": "no"}},
+		{"OneZero", starstruct.BoolOneZero, map[string]string{"active": "1", "admin": "0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			if err := starstruct.FlattenNestedStructs(a, "", &got, starstruct.WithBoolFormat(tt.format)); err != nil {
+				t.Fatalf("FlattenNestedStructs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FlattenNestedStructs() = %v, want %v", got, tt.want)
+			}
+
+			var back account
+			if err := starstruct.MapToStruct(got, &back, starstruct.WithBoolFormat(tt.format)); err != nil {
+				t.Fatalf("MapToStruct() error = %v", err)
+			}
+			if !reflect.DeepEqual(back, a) {
+				t.Errorf("MapToStruct() = %+v, want the original %+v", back, a)
+			}
+		})
+	}
+}
+
+// TestWithMaxKeys tests that flattening fails with ErrTooManyKeys once the key cap is exceeded.
+func TestWithMaxKeys(t *testing.T) {
+	type inventory struct {
+		Name  string                       `json:"name"`
+		Items map[string]map[string]string `json:"items"`
+	}
+	inv := inventory{Name: "warehouse", Items: map[string]map[string]string{}}
+	for i := 0; i < 100; i++ {
+		inv.Items["item"+strconv.Itoa(i)] = map[string]string{"sku": strconv.Itoa(i), "bin": "A"}
+	}
+
+	_, err := starstruct.FlattenStructFields(inv, starstruct.WithGenerate(), starstruct.WithMaxKeys(50))
+	if !errors.Is(err, starstruct.ErrTooManyKeys) {
+		t.Fatalf("FlattenStructFields() error = %v, want %v", err, starstruct.ErrTooManyKeys)
+	}
+	if !strings.Contains(err.Error(), "51 keys, limit 50") {
+		t.Errorf("FlattenStructFields() error = %q, want the key count reported", err)
+	}
+
+	got := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(inv, "", &got, starstruct.WithMaxKeys(201)); err != nil {
+		t.Errorf("FlattenNestedStructs() under the limit error = %v", err)
+	}
+	if len(got) != 201 {
+		t.Errorf("FlattenNestedStructs() produced %d keys, want 201", len(got))
+	}
+}
+
+// TestWithSliceColumns tests that a record with an empty slice lines up with the indexed columns of one without.
+func TestWithSliceColumns(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+		Qty  int    `json:"qty"`
+	}
+	type order struct {
+		ID    string `json:"id"`
+		Items []item `json:"items"`
+		Note  string `json:"note"`
+	}
+	full := order{ID: "1", Items: []item{{"bolt", 2}, {"nut", 4}}, Note: "rush"}
+	empty := order{ID: "2", Note: "hold"}
+
+	fullRows, err := starstruct.FlattenStructFields(full, starstruct.WithGenerate())
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	var columns []string
+	for _, pair := range fullRows {
+		columns = append(columns, pair[0])
+	}
+
+	emptyRows, err := starstruct.FlattenStructFields(empty, starstruct.WithGenerate(), starstruct.WithSliceColumns(columns))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	want := [][]string{
+		{"id", "2"},
+		{"items.00.name", ""},
+		{"items.00.qty", ""},
+		{"items.01.name", ""},
+		{"items.01.qty", ""},
+		{"note", "hold"},
+	}
+	if !reflect.DeepEqual(emptyRows, want) {
+		t.Errorf("FlattenStructFields() = %v, want %v", emptyRows, want)
+	}
+	for i := range fullRows {
+		if fullRows[i][0] != emptyRows[i][0] {
+			t.Errorf("column %d = %q, want %q to align with the non-empty record", i, emptyRows[i][0], fullRows[i][0])
+		}
+	}
+
+	// Without the option the empty slice still collapses to a single key
+	collapsed, err := starstruct.FlattenStructFields(empty, starstruct.WithGenerate())
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	if want := [][]string{{"id", "2"}, {"items", ""}, {"note", "hold"}}; !reflect.DeepEqual(collapsed, want) {
+		t.Errorf("FlattenStructFields() = %v, want %v", collapsed, want)
+	}
+}
+
+// TestWithZeroPadWidth tests that a fixed pad width gives slices of different lengths keys of the same width.
+func TestWithZeroPadWidth(t *testing.T) {
+	type row struct {
+		Items []int `json:"items"`
+	}
+	short := row{Items: make([]int, 9)}
+	long := row{Items: make([]int, 150)}
+
+	for _, r := range []row{short, long} {
+		got := map[string]string{}
+		if err := starstruct.FlattenNestedStructs(r, "", &got, starstruct.WithZeroPadWidth(3)); err != nil {
+			t.Fatalf("FlattenNestedStructs() error = %v", err)
+		}
+		if len(got) != len(r.Items) {
+			t.Errorf("FlattenNestedStructs() produced %d keys, want %d", len(got), len(r.Items))
+		}
+		for key := range got {
+			if len(key) != len("items.000") {
+				t.Errorf("key %q, want a 3-digit index", key)
+			}
+		}
+		if _, ok := got["items.008"]; !ok {
+			t.Errorf("FlattenNestedStructs() missing items.008 for %d items", len(r.Items))
+		}
+	}
+
+	// Unset, the width follows each slice's length
+	auto := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(short, "", &auto); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	if _, ok := auto["items.08"]; !ok {
+		t.Errorf("FlattenNestedStructs() = %v, want 2-digit indexes", auto)
+	}
+}
+
+// TestToOrderedMap tests that ToOrderedMap returns sorted keys at every level, identically on repeated calls.
+func TestToOrderedMap(t *testing.T) {
+	type contact struct {
+		Zip   string `json:"zip"`
+		Email string `json:"email"`
+	}
+	type user struct {
+		Name     string            `json:"name"`
+		Labels   map[string]string `json:"labels"`
+		Contacts []contact         `json:"contacts"`
+		Age      int               `json:"age"`
+	}
+	item := user{
+		Name:     "Anthony",
+		Labels:   map[string]string{"team": "it", "role": "admin", "site": "nyc", "env": "prod"},
+		Contacts: []contact{{Zip: "10001", Email: "a@example.com"}},
+		Age:      30,
+	}
+
+	// serialize writes the entries by hand, so only the OrderedMap's own order decides the output
+	var serialize func(om starstruct.OrderedMap) string
+	serialize = func(om starstruct.OrderedMap) string {
+		var b strings.Builder
+		for _, entry := range om {
+			switch v := entry.Value.(type) {
+			case starstruct.OrderedMap:
+				fmt.Fprintf(&b, "%s{%s}", entry.Key, serialize(v))
+			case []interface{}:
+				fmt.Fprintf(&b, "%s[", entry.Key)
+				for _, elem := range v {
+					b.WriteString(serialize(elem.(starstruct.OrderedMap)))
+				}
+				b.WriteString("]")
+			default:
+				fmt.Fprintf(&b, "%s=%v;", entry.Key, v)
+			}
+		}
+		return b.String()
+	}
+
+	first, err := starstruct.ToOrderedMap(item)
+	if err != nil {
+		t.Fatalf("ToOrderedMap() error = %v", err)
+	}
+	want := "age=30;contacts[email=a@example.com;zip=10001;]labels{env=prod;role=admin;site=nyc;team=it;}name=Anthony;"
+	for i := 0; i < 20; i++ {
+		om, err := starstruct.ToOrderedMap(item)
+		if err != nil {
+			t.Fatalf("ToOrderedMap() error = %v", err)
+		}
+		if got := serialize(om); got != want {
+			t.Fatalf("ToOrderedMap() call %d = %s, want %s", i, got, want)
+		}
+	}
+
+	raw, err := json.Marshal(first)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	wantJSON := `{"age":30,"contacts":[{"email":"a@example.com","zip":"10001"}],"labels":{"env":"prod","role":"admin","site":"nyc","team":"it"},"name":"Anthony"}`
+	if string(raw) != wantJSON {
+		t.Errorf("json.Marshal(ToOrderedMap()) = %s, want %s", raw, wantJSON)
+	}
+	if v, ok := first.Get("name"); !ok || v != "Anthony" {
+		t.Errorf("Get(name) = %v, %v, want Anthony, true", v, ok)
+	}
+}
+
+// TestUnsupportedFields tests that chan and func fields are omitted, or rendered as a placeholder, without failing.
+func TestUnsupportedFields(t *testing.T) {
+	type job struct {
+		Name     string       `json:"name"`
+		Done     chan int     `json:"done"`
+		Callback func() error `json:"callback"`
+		Retries  int          `json:"retries"`
+	}
+	item := job{Name: "sync", Done: make(chan int), Callback: func() error { return nil }, Retries: 3}
+
+	got, err := starstruct.FlattenStructFields(item, starstruct.WithGenerate())
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	if want := [][]string{{"name", "sync"}, {"retries", "3"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenStructFields() = %v, want %v", got, want)
+	}
+
+	got, err = starstruct.FlattenStructFields(item, starstruct.WithGenerate(), starstruct.WithUnsupportedPlaceholder("<unsupported>"))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	want := [][]string{{"name", "sync"}, {"done", "<unsupported>"}, {"callback", "<unsupported>"}, {"retries", "3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenStructFields() with placeholder = %v, want %v", got, want)
+	}
+
+	// A slice of channels no longer aborts header generation
+	fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf([]chan int{make(chan int)}))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	if len(*fields) != 0 {
+		t.Errorf("GenerateFieldNames() = %v, want no fields", *fields)
+	}
+}
+
+// TestWithExcludeFields tests that an excluded field is removed together with the fields nested under it.
+func TestWithExcludeFields(t *testing.T) {
+	type geo struct {
+		Lat string `json:"lat"`
+		Lng string `json:"lng"`
+	}
+	type address struct {
+		City string `json:"city"`
+		Geo  geo    `json:"geo"`
+	}
+	type user struct {
+		Name    string  `json:"name"`
+		Address address `json:"address"`
+		Email   string  `json:"email"`
+	}
+	item := user{Name: "Anthony", Address: address{City: "N/A", Geo: geo{Lat: "1", Lng: "2"}}, Email: "a@example.com"}
+
+	tests := []struct {
+		name string
+		opts []starstruct.Option
+		want [][]string
+	}{
+		{
+			name: "Generated",
+			opts: []starstruct.Option{starstruct.WithExcludeFields([]string{"address.geo"})},
+			want: [][]string{{"name", "Anthony"}, {"address.city", "N/A"}, {"email", "a@example.com"}},
+		},
+		{
+			name: "With Generate",
+			opts: []starstruct.Option{starstruct.WithGenerate(), starstruct.WithExcludeFields([]string{"address", "email"})},
+			want: [][]string{{"name", "Anthony"}},
+		},
+		{
+			name: "With Headers",
+			opts: []starstruct.Option{
+				starstruct.WithHeaders(&[]string{"name", "address"}),
+				starstruct.WithExcludeFields([]string{"address.geo.lat"}),
+			},
+			want: [][]string{{"name", "Anthony"}, {"address.city", "N/A"}, {"address.geo.lng", "2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := starstruct.FlattenStructFields(item, tt.opts...)
+			if err != nil {
+				t.Fatalf("FlattenStructFields() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FlattenStructFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWithTagName tests that a custom tag drives field names, inlining and skipping instead of the json tag.
+func TestWithTagName(t *testing.T) {
+	type meta struct {
+		Source string `json:"source" rego:"Source"`
+	}
+	type account struct {
+		ID       string `json:"id" rego:"Account ID"`
+		Email    string `json:"email" rego:"-"`
+		Meta     meta   `json:"meta" rego:",inline"`
+		Internal string `json:"internal"`
+		Secret   string `json:"-" rego:"Secret Note"`
+	}
+	item := account{ID: "42", Email: "a@example.com", Meta: meta{Source: "okta"}, Internal: "x", Secret: "s"}
+
+	got, err := starstruct.FlattenStructFields(item, starstruct.WithGenerate(), starstruct.WithTagName("rego"))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	want := [][]string{{"Account ID", "42"}, {"Source", "okta"}, {"internal", "x"}, {"Secret Note", "s"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenStructFields() = %v, want %v", got, want)
+	}
+
+	fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf(item), starstruct.WithTagName("rego"))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	if want := []string{"Account ID", "Source", "internal", "Secret Note"}; !reflect.DeepEqual(*fields, want) {
+		t.Errorf("GenerateFieldNames() = %v, want %v", *fields, want)
+	}
+
+	m, err := starstruct.ToMapOpts(item, starstruct.WithTagName("rego"))
+	if err != nil {
+		t.Fatalf("ToMapOpts() error = %v", err)
+	}
+	if _, ok := m["email"]; ok {
+		t.Errorf("ToMapOpts() = %v, want the rego:\"-\" field skipped", m)
+	}
+	if _, ok := m["Account ID"]; !ok || len(m) != 4 {
+		t.Errorf("ToMapOpts() = %v, want keys named by the rego tag", m)
+	}
+
+	var back account
+	flat := map[string]string{"Account ID": "7", "Source": "gws", "Secret Note": "t"}
+	if err := starstruct.MapToStruct(flat, &back, starstruct.WithTagName("rego")); err != nil {
+		t.Fatalf("MapToStruct() error = %v", err)
+	}
+	if back.ID != "7" || back.Meta.Source != "gws" || back.Secret != "t" {
+		t.Errorf("MapToStruct() = %+v, want fields matched by the rego tag", back)
+	}
+
+	// The default still follows json tags
+	got, err = starstruct.FlattenStructFields(item, starstruct.WithGenerate())
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	if got[0][0] != "id" {
+		t.Errorf("FlattenStructFields() first column = %q, want %q", got[0][0], "id")
+	}
+}
+
+// TestGenerateFieldNamesPointerMapValues tests that struct pointers held in a map expand into their fields.
+func TestGenerateFieldNamesPointerMapValues(t *testing.T) {
+	type inner struct {
+		Owner string `json:"owner"`
+		Size  int    `json:"size"`
+	}
+	type bucket struct {
+		Name  string            `json:"name"`
+		Zones map[string]*inner `json:"zones"`
+	}
+	item := bucket{Name: "logs", Zones: map[string]*inner{"east": {Owner: "it", Size: 3}, "west": {Owner: "sec", Size: 5}}}
+
+	fields, err := starstruct.GenerateFieldNames("", reflect.ValueOf(item))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	want := []string{"name", "zones.east.owner", "zones.east.size", "zones.west.owner", "zones.west.size"}
+	if !reflect.DeepEqual(*fields, want) {
+		t.Errorf("GenerateFieldNames() = %v, want %v", *fields, want)
+	}
+
+	got, err := starstruct.FlattenStructFields(item, starstruct.WithGenerate())
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	wantRows := [][]string{{"name", "logs"}, {"zones.east.owner", "it"}, {"zones.east.size", "3"}, {"zones.west.owner", "sec"}, {"zones.west.size", "5"}}
+	if !reflect.DeepEqual(got, wantRows) {
+		t.Errorf("FlattenStructFields() = %v, want %v", got, wantRows)
+	}
+}
+
+// flatRecord has only scalar fields, so it takes the cached flat-struct path
+type flatRecord struct {
+	ID       int           `json:"id" rego:"ID"`
+	Name     string        `json:"name" rego:"Full Name"`
+	Active   bool          `json:"active"`
+	Score    float64       `json:"score"`
+	Count    uint16        `json:"count"`
+	Timeout  time.Duration `json:"timeout"`
+	Internal string        `json:"-"`
+	hidden   string
+}
+
+// TestFlatStructCache tests that flat structs flatten identically with and without the per-type field cache.
+func TestFlatStructCache(t *testing.T) {
+	item := flatRecord{ID: 7, Name: "Anthony", Active: true, Score: 1.5, Count: 3, Timeout: time.Minute, Internal: "x", hidden: "y"}
+	want := map[string]string{
+		"id":      "7",
+		"name":    "Anthony",
+		"active":  "true",
+		"score":   "1.5",
+		"count":   "3",
+		"timeout": "1m0s",
+	}
+
+	for i := 0; i < 2; i++ {
+		cached := map[string]string{}
+		if err := starstruct.FlattenNestedStructs(item, "", &cached); err != nil {
+			t.Fatalf("FlattenNestedStructs() error = %v", err)
+		}
+		uncached := map[string]string{}
+		if err := starstruct.FlattenNestedStructs(item, "", &uncached, starstruct.WithoutFieldCache()); err != nil {
+			t.Fatalf("FlattenNestedStructs() error = %v", err)
+		}
+		if !reflect.DeepEqual(cached, want) || !reflect.DeepEqual(uncached, want) {
+			t.Errorf("FlattenNestedStructs() cached = %v, uncached = %v, want %v", cached, uncached, want)
+		}
+	}
+
+	// Keys resolved under one tag name are not reused for another
+	tagged := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(item, "user", &tagged, starstruct.WithTagName("rego")); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	if tagged["user.Full Name"] != "Anthony" || tagged["user.ID"] != "7" {
+		t.Errorf("FlattenNestedStructs() with rego tags = %v", tagged)
+	}
+
+	kinds, err := starstruct.FlattenStructKinds(item, "")
+	if err != nil {
+		t.Fatalf("FlattenStructKinds() error = %v", err)
+	}
+	if kinds["id"] != reflect.Int || kinds["score"] != reflect.Float64 || kinds["active"] != reflect.Bool {
+		t.Errorf("FlattenStructKinds() = %v, want the scalar kinds", kinds)
+	}
+}
+
+// TestFlattenStructFieldsKinds tests that the fields and kinds from one pass match FlattenStructFields and FlattenStructKinds.
+func TestFlattenStructFieldsKinds(t *testing.T) {
+	item := flatRecord{ID: 7, Name: "Anthony", Active: true, Score: 1.5, Count: 3, Timeout: time.Minute}
+	opts := []starstruct.Option{starstruct.WithGenerate(), starstruct.WithHeaderTransform(strings.ToUpper)}
+
+	fields, kinds, err := starstruct.FlattenStructFieldsKinds(item, opts...)
+	if err != nil {
+		t.Fatalf("FlattenStructFieldsKinds() error = %v", err)
+	}
+	wantFields, err := starstruct.FlattenStructFields(item, opts...)
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("FlattenStructFieldsKinds() fields = %v, want %v", fields, wantFields)
+	}
+	wantKinds, err := starstruct.FlattenStructKinds(item, "", starstruct.WithHeaderTransform(strings.ToUpper))
+	if err != nil {
+		t.Fatalf("FlattenStructKinds() error = %v", err)
+	}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("FlattenStructFieldsKinds() kinds = %v, want %v", kinds, wantKinds)
+	}
+	if kinds["ID"] != reflect.Int || kinds["ACTIVE"] != reflect.Bool {
+		t.Errorf("FlattenStructFieldsKinds() kinds = %v, want transformed keys", kinds)
+	}
+}
+
+// BenchmarkFlattenFlatStruct compares flattening a flat struct with and without the per-type field cache.
+func BenchmarkFlattenFlatStruct(b *testing.B) {
+	item := flatRecord{ID: 7, Name: "Anthony", Active: true, Score: 1.5, Count: 3, Timeout: time.Minute}
+	for _, bb := range []struct {
+		name string
+		opts []starstruct.Option
+	}{
+		{name: "Cached"},
+		{name: "Uncached", opts: []starstruct.Option{starstruct.WithoutFieldCache()}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				fieldMap := make(map[string]string, 8)
+				if err := starstruct.FlattenNestedStructs(item, "", &fieldMap, bb.opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// cachedRecord nests structs, times, and slices, whose names depend only on its type, beside a map, whose names do not
+type cachedRecord struct {
+	ID      int               `json:"id"`
+	Created time.Time         `json:"created"`
+	Owner   flatRecord        `json:"owner"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Skipped string            `json:"-"`
+}
+
+// TestTypeInfoCache tests that nested structs generate and flatten identically with and without the per-type metadata cache.
+func TestTypeInfoCache(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	items := []cachedRecord{
+		{ID: 1, Created: created, Owner: flatRecord{ID: 7, Name: "Anthony"}, Tags: []string{"a"}},
+		{ID: 2, Created: created, Owner: flatRecord{ID: 8, Name: "Dardano"}, Labels: map[string]string{"team": "it"}},
+	}
+
+	for _, opts := range [][]starstruct.Option{nil, {starstruct.WithTagName("rego")}, {starstruct.WithExcludeNil()}} {
+		cached, err := starstruct.GenerateFieldNames("", reflect.ValueOf(items), opts...)
+		if err != nil {
+			t.Fatalf("GenerateFieldNames() error = %v", err)
+		}
+		uncached, err := starstruct.GenerateFieldNames("", reflect.ValueOf(items), append(opts, starstruct.WithoutFieldCache())...)
+		if err != nil {
+			t.Fatalf("GenerateFieldNames() error = %v", err)
+		}
+		if !reflect.DeepEqual(*cached, *uncached) {
+			t.Errorf("GenerateFieldNames() cached = %v, uncached = %v", *cached, *uncached)
+		}
+
+		// Map keys found only on a later element must still be merged in
+		if !slices.Contains(*cached, "labels.team") {
+			t.Errorf("GenerateFieldNames() = %v, want labels.team merged from the second element", *cached)
+		}
+
+		for _, item := range items {
+			cachedMap := map[string]string{}
+			if err := starstruct.FlattenNestedStructs(item, "", &cachedMap, opts...); err != nil {
+				t.Fatalf("FlattenNestedStructs() error = %v", err)
+			}
+			uncachedMap := map[string]string{}
+			if err := starstruct.FlattenNestedStructs(item, "", &uncachedMap, append(opts, starstruct.WithoutFieldCache())...); err != nil {
+				t.Fatalf("FlattenNestedStructs() error = %v", err)
+			}
+			if !reflect.DeepEqual(cachedMap, uncachedMap) {
+				t.Errorf("FlattenNestedStructs() cached = %v, uncached = %v", cachedMap, uncachedMap)
+			}
+		}
+	}
+}
+
+// BenchmarkGenerateFieldNamesSlice compares generating headers for a slice of nested structs with and without the per-type metadata cache.
+func BenchmarkGenerateFieldNamesSlice(b *testing.B) {
+	type row struct {
+		ID      int        `json:"id"`
+		Created time.Time  `json:"created"`
+		Owner   flatRecord `json:"owner"`
+		Tags    []string   `json:"tags"`
+	}
+	items := make([]row, 1000)
+	for i := range items {
+		items[i] = row{ID: i, Owner: flatRecord{ID: i, Name: "Anthony"}, Tags: []string{"a", "b"}}
+	}
+
+	for _, bb := range []struct {
+		name string
+		opts []starstruct.Option
+	}{
+		{name: "Cached"},
+		{name: "Uncached", opts: []starstruct.Option{starstruct.WithoutFieldCache()}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := starstruct.GenerateFieldNames("", reflect.ValueOf(items), bb.opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// snakeCase converts camelCase key segments to snake_case, leaving the dots between segments alone
+func snakeCase(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 && key[i-1] != '.' {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// TestWithHeaderTransform tests that the transform applies to headers and flattened keys alike, so values stay aligned.
+func TestWithHeaderTransform(t *testing.T) {
+	type address struct {
+		StreetName string `json:"streetName"`
+		ZipCode    string `json:"zipCode"`
+	}
+	type person struct {
+		FirstName   string  `json:"firstName"`
+		LastName    string  `json:"lastName"`
+		HomeAddress address `json:"homeAddress"`
+	}
+	item := person{FirstName: "Anthony", LastName: "Dardano", HomeAddress: address{StreetName: "Main", ZipCode: "10001"}}
+
+	got, err := starstruct.FlattenStructFields(item, starstruct.WithGenerate(), starstruct.WithHeaderTransform(snakeCase))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	want := [][]string{
+		{"first_name", "Anthony"},
+		{"last_name", "Dardano"},
+		{"home_address.street_name", "Main"},
+		{"home_address.zip_code", "10001"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenStructFields() = %v, want %v", got, want)
+	}
+
+	// Filtering works with headers and exclusions in either form
+	for _, headers := range [][]string{{"lastName", "homeAddress"}, {"last_name", "home_address"}} {
+		got, err = starstruct.FlattenStructFields(item,
+			starstruct.WithHeaders(&headers),
+			starstruct.WithExcludeFields([]string{"homeAddress.zipCode"}),
+			starstruct.WithHeaderTransform(snakeCase),
+			starstruct.WithStrict(),
+		)
+		if err != nil {
+			t.Fatalf("FlattenStructFields(%v) error = %v", headers, err)
+		}
+		want = [][]string{{"last_name", "Dardano"}, {"home_address.street_name", "Main"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("FlattenStructFields(%v) = %v, want %v", headers, got, want)
+		}
+	}
+
+	fieldMap := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(item, "", &fieldMap, starstruct.WithHeaderTransform(strings.ToUpper)); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	if fieldMap["HOMEADDRESS.STREETNAME"] != "Main" || len(fieldMap) != 4 {
+		t.Errorf("FlattenNestedStructs() = %v, want upper-cased keys", fieldMap)
+	}
+}
+
+// TestMapToStructSliceOrder tests that flattening and then unflattening reconstructs slices in their original order.
+func TestMapToStructSliceOrder(t *testing.T) {
+	type line struct {
+		SKU    string   `json:"sku"`
+		Qty    int      `json:"qty"`
+		Serial []string `json:"serials"`
+	}
+	type order struct {
+		ID     string              `json:"id"`
+		Lines  []line              `json:"lines"`
+		Backup []*line             `json:"backup"`
+		Grid   [][]int             `json:"grid"`
+		Meta   []map[string]string `json:"meta"`
+		Pair   [2]string           `json:"pair"`
+		Steps  []int               `json:"steps"`
+	}
+
+	want := order{
+		ID:     "A-1",
+		Lines:  []line{{SKU: "x", Qty: 1, Serial: []string{"s1", "s2"}}, {SKU: "y", Qty: 2}},
+		Backup: []*line{nil, {SKU: "z", Qty: 3}},
+		Grid:   [][]int{{1, 2}, {3}},
+		Meta:   []map[string]string{{"team": "it"}, {"example.com": "primary"}},
+		Pair:   [2]string{"left", "right"},
+	}
+	// More than 100 elements pads indexes to three digits
+	for i := 0; i < 150; i++ {
+		want.Steps = append(want.Steps, 149-i)
+	}
+
+	flat := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(want, "", &flat); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	for _, key := range []string{"grid.00.01", "meta.01.example\\.com", "backup.01.sku", "steps.149"} {
+		if _, ok := flat[key]; !ok {
+			t.Errorf("FlattenNestedStructs() missing key %q", key)
+		}
+	}
+
+	var got order
+	if err := starstruct.MapToStruct(flat, &got); err != nil {
+		t.Fatalf("MapToStruct() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapToStruct() = %+v, want %+v", got, want)
+	}
+
+	if err := starstruct.MapToStruct(flat, got); err == nil {
+		t.Error("MapToStruct() with a non-pointer error = nil, want an error")
+	}
+}
+
+// TestFlattenStructFieldsContext tests that a cancelled context aborts flattening a large structure promptly.
+func TestFlattenStructFieldsContext(t *testing.T) {
+	type node struct {
+		ID       int               `json:"id"`
+		Children []cachedRecord    `json:"children"`
+		Labels   map[string]string `json:"labels"`
+	}
+	item := node{ID: 1, Labels: map[string]string{}}
+	for i := 0; i < 50000; i++ {
+		item.Children = append(item.Children, cachedRecord{ID: i, Tags: []string{"a", "b"}})
+		item.Labels[strconv.Itoa(i)] = "x"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	_, err := starstruct.FlattenStructFieldsContext(ctx, item, starstruct.WithGenerate())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FlattenStructFieldsContext() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("FlattenStructFieldsContext() took %v to abort", elapsed)
+	}
+
+	got, err := starstruct.FlattenStructFieldsContext(context.Background(), node{ID: 2}, starstruct.WithGenerate())
+	if err != nil {
+		t.Fatalf("FlattenStructFieldsContext() error = %v", err)
+	}
+	if len(got) == 0 || got[0][0] != "id" || got[0][1] != "2" {
+		t.Errorf("FlattenStructFieldsContext() = %v, want id first", got)
+	}
+}
+
+// TestGetByPath tests exact path lookups over a flattened map.
+func TestGetByPath(t *testing.T) {
+	flat := map[string]string{
+		"name":                  "Anthony",
+		"address.city":          "N/A",
+		"domains.example\\.com": "primary",
+	}
+
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"name", "Anthony", true},
+		{"address.city", "N/A", true},
+		{"address.city.", "N/A", true},
+		{"address", "", false},
+		{"domains.example\\.com", "primary", true},
+		{"domains.example.com", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := starstruct.GetByPath(flat, tt.path)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetByPath(%q) = (%q, %v), want (%q, %v)", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// TestFilterByPrefix tests that prefix filtering matches whole key segments only.
+func TestFilterByPrefix(t *testing.T) {
+	flat := map[string]string{
+		"address":               "",
+		"address.city":          "N/A",
+		"address.state":         "FL",
+		"addresses.00":          "HQ",
+		"domains.example\\.com": "primary",
+		"domains.example":       "secondary",
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   map[string]string
+	}{
+		{"Segment", "address", map[string]string{"address": "", "address.city": "N/A", "address.state": "FL"}},
+		{"Trailing Delimiter", "address.", map[string]string{"address": "", "address.city": "N/A", "address.state": "FL"}},
+		{"Leaf", "address.city", map[string]string{"address.city": "N/A"}},
+		{"Escaped Dot", "domains.example", map[string]string{"domains.example": "secondary"}},
+		{"No Match", "addr", map[string]string{}},
+		{"Empty", "", flat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := starstruct.FilterByPrefix(flat, tt.prefix)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterByPrefix(%q) = %v, want %v", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFlattenNestedStructsArray tests that fixed-size array fields flatten with indices, like slices.
+func TestFlattenNestedStructsArray(t *testing.T) {
+	type Point struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	type triangle struct {
+		Name     string   `json:"name"`
+		Vertices [3]Point `json:"vertices"`
+	}
+
+	item := triangle{Name: "right", Vertices: [3]Point{{0, 0}, {3, 0}, {0, 4}}}
+
+	got := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(item, "", &got); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	want := map[string]string{
+		"name":          "right",
+		"vertices.00.x": "0", "vertices.00.y": "0",
+		"vertices.01.x": "3", "vertices.01.y": "0",
+		"vertices.02.x": "0", "vertices.02.y": "4",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenNestedStructs() = %v, want %v", got, want)
+	}
+
+	fields := []string{"name", "vertices"}
+	pairs, err := starstruct.FlattenStructFields(item, starstruct.WithHeaders(&fields))
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	wantPairs := [][]string{
+		{"name", "right"},
+		{"vertices.00.x", "0"}, {"vertices.00.y", "0"},
+		{"vertices.01.x", "3"}, {"vertices.01.y", "0"},
+		{"vertices.02.x", "0"}, {"vertices.02.y", "4"},
+	}
+	if !reflect.DeepEqual(pairs, wantPairs) {
+		t.Errorf("FlattenStructFields() = %v, want %v", pairs, wantPairs)
+	}
+}
+
+// TestFlattenStructFieldsGenerateOptions tests that generated headers honor the same options as the flattened values.
+func TestFlattenStructFieldsGenerateOptions(t *testing.T) {
+	type Profile struct {
+		Title string `json:"title"`
+	}
+	type user struct {
+		Name    string   `json:"name"`
+		Nick    string   `json:"nick,omitempty"`
+		Profile *Profile `json:"profile"`
+	}
+	item := user{Name: "Anthony"}
+
+	tests := []struct {
+		name string
+		opts []starstruct.Option
+		want [][]string
+	}{
+		{"Default", nil, [][]string{{"name", "Anthony"}, {"nick", ""}, {"profile", "<nil>"}}},
+		{"Omit Empty", []starstruct.Option{starstruct.WithOmitEmpty()}, [][]string{{"name", "Anthony"}, {"profile", "<nil>"}}},
+		{"Exclude Nil", []starstruct.Option{starstruct.WithExcludeNil()}, [][]string{{"name", "Anthony"}, {"nick", ""}}},
+		{"Header Prefix", []starstruct.Option{starstruct.WithHeaderPrefix("user"), starstruct.WithExcludeNil()}, [][]string{{"user.name", "Anthony"}, {"user.nick", ""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := starstruct.FlattenStructFields(item, append(tt.opts, starstruct.WithGenerate())...)
+			if err != nil {
+				t.Fatalf("FlattenStructFields() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FlattenStructFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIgnoredFields tests that fields tagged `json:"-"` appear in neither headers nor flattened values.
+func TestIgnoredFields(t *testing.T) {
+	type withSecret struct {
+		Name   string `json:"name"`
+		Secret string `json:"-"`
+	}
+	item := withSecret{Name: "Anthony", Secret: "hunter2"}
+
+	headers, err := starstruct.GenerateFieldNames("", reflect.ValueOf(item))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	if !reflect.DeepEqual(*headers, []string{"name"}) {
+		t.Errorf("GenerateFieldNames() = %v, want [name]", *headers)
+	}
+
+	got := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(item, "", &got); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+	if want := map[string]string{"name": "Anthony"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenNestedStructs() = %v, want %v", got, want)
+	}
+}
+
+// TestGenerateFieldNamesOmitEmpty tests that zero-valued omitempty fields are skipped unless populated in any slice element.
+func TestGenerateFieldNamesOmitEmpty(t *testing.T) {
+	type contact struct {
+		Name  string `json:"name"`
+		Email string `json:"email,omitempty"`
+		Phone string `json:"phone,omitempty"`
+	}
+
+	single, err := starstruct.GenerateFieldNames("", reflect.ValueOf(contact{Name: "Anthony"}), starstruct.WithOmitEmpty())
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	if want := []string{"name"}; !reflect.DeepEqual(*single, want) {
+		t.Errorf("GenerateFieldNames() = %v, want %v", *single, want)
+	}
+
+	contacts := []contact{
+		{Name: "Anthony"},
+		{Name: "Dardano", Email: "dardano@gemini.com"},
+	}
+	merged, err := starstruct.GenerateFieldNames("", reflect.ValueOf(contacts), starstruct.WithOmitEmpty())
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	if want := []string{"name", "email"}; !reflect.DeepEqual(*merged, want) {
+		t.Errorf("GenerateFieldNames() over slice = %v, want %v", *merged, want)
+	}
+
+	all, err := starstruct.GenerateFieldNames("", reflect.ValueOf(contacts))
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	if want := []string{"name", "email", "phone"}; !reflect.DeepEqual(*all, want) {
+		t.Errorf("GenerateFieldNames() without option = %v, want %v", *all, want)
+	}
+}
+
+// TestTaggedFieldNames tests that tagged fields are found by their flattened keys, including nested and inlined structs.
+func TestTaggedFieldNames(t *testing.T) {
+	type Links struct {
+		Home  string `json:"home" rego:"link"`
+		Label string `json:"label"`
+	}
+	type Base struct {
+		Avatar string `json:"avatar" rego:"readonly,link"`
+	}
+	type Profile struct {
+		Base
+		URL    string `json:"url" rego:"link"`
+		Links  *Links `json:"links"`
+		Hidden string `json:"-" rego:"link"`
+	}
+
+	got := starstruct.TaggedFieldNames(reflect.TypeOf(&Profile{}), "rego", "link")
+	want := []string{"avatar", "url", "links.home"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TaggedFieldNames() = %v, want %v", got, want)
+	}
+}
+
+// TestStructDiff tests that StructDiff reports added, removed, and changed fields.
+func TestStructDiff(t *testing.T) {
+	before := defaultTestStruct
+	after := defaultTestStruct
+	after.Age = 30
+	after.Tags = []string{"Staff Enterprise Infrastructure Engineer"}
+	after.Address.City = "Miami"
+
+	want := map[string][2]string{
+		"age":          {"0", "30"},
+		"tags.01":      {"DJ", "<nil>"},
+		"address.city": {"N/A", "Miami"},
+	}
+
+	got, err := starstruct.StructDiff(before, after)
+	if err != nil {
+		t.Fatalf("StructDiff() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StructDiff() = %v, want %v", got, want)
+	}
+
+	// Reversing the arguments reports the tag as added
+	got, err = starstruct.StructDiff(after, before)
+	if err != nil {
+		t.Fatalf("StructDiff() error = %v", err)
+	}
+	if added := got["tags.01"]; added != [2]string{"<nil>", "DJ"} {
+		t.Errorf("StructDiff() added tags.01 = %v, want [<nil> DJ]", added)
+	}
+
+	got, err = starstruct.StructDiff(before, after, starstruct.WithHeaders(&[]string{"address"}))
+	if err != nil {
+		t.Fatalf("StructDiff() error = %v", err)
+	}
+	if want := map[string][2]string{"address.city": {"N/A", "Miami"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("StructDiff() with headers = %v, want %v", got, want)
+	}
+}
+
+// TestMapToStruct tests round-tripping a nested struct through FlattenNestedStructs and MapToStruct.
+func TestMapToStruct(t *testing.T) {
+	type office struct {
+		City  string `json:"city"`
+		Floor int    `json:"floor"`
+	}
+	type employee struct {
+		Name     string            `json:"name"`
+		Age      int               `json:"age"`
+		Active   bool              `json:"active"`
+		Salary   float64           `json:"salary"`
+		Started  time.Time         `json:"started"`
+		Tags     []string          `json:"tags"`
+		Offices  []office          `json:"offices"`
+		Home     *office           `json:"home"`
+		Manager  *office           `json:"manager"`
+		Contacts map[string]string `json:"contacts"`
+	}
+
+	want := employee{
+		Name:     "Anthony",
+		Age:      30,
+		Active:   true,
+		Salary:   1234.5,
+		Started:  time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+		Tags:     []string{"Engineer", "DJ"},
+		Offices:  []office{{City: "New York", Floor: 12}, {City: "Miami", Floor: 3}},
+		Home:     &office{City: "Miami"},
+		Contacts: map[string]string{"email": "anthony@gemini.com", "slack": "@anthony"},
+	}
+
+	flat := map[string]string{}
+	if err := starstruct.FlattenNestedStructs(want, "", &flat); err != nil {
+		t.Fatalf("FlattenNestedStructs() error = %v", err)
+	}
+
+	var got employee
+	if err := starstruct.MapToStruct(flat, &got); err != nil {
+		t.Fatalf("MapToStruct() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapToStruct() = %+v, want %+v", got, want)
+	}
+
+	if err := starstruct.MapToStruct(map[string]string{"age": "thirty"}, &got); err == nil {
+		t.Error("MapToStruct() expected an error parsing a non-numeric age")
+	}
+	if err := starstruct.MapToStruct(flat, got); err == nil {
+		t.Error("MapToStruct() expected an error for a non-pointer destination")
+	}
+}
+
+// TestWithHeaderPrefix tests that WithHeaderPrefix namespaces every header and key while header filtering still works.
+func TestWithHeaderPrefix(t *testing.T) {
+	prefix := starstruct.WithHeaderPrefix("user")
+
+	headers, err := starstruct.GenerateFieldNames("", reflect.ValueOf(defaultTestStruct), prefix)
+	if err != nil {
+		t.Fatalf("GenerateFieldNames() error = %v", err)
+	}
+	wantHeaders := []string{"user.name", "user.age", "user.tags", "user.address.city", "user.address.state"}
+	if !reflect.DeepEqual(*headers, wantHeaders) {
+		t.Errorf("GenerateFieldNames() = %v, want %v", *headers, wantHeaders)
+	}
+
+	generated, err := starstruct.FlattenStructFields(defaultTestStruct, starstruct.WithGenerate(), prefix)
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	for _, pair := range generated {
+		if !strings.HasPrefix(pair[0], "user.") {
+			t.Errorf("FlattenStructFields() key %q is missing the prefix", pair[0])
+		}
+	}
+
+	filtered, err := starstruct.FlattenStructFields(defaultTestStruct, starstruct.WithHeaders(&[]string{"name", "address"}), prefix)
+	if err != nil {
+		t.Fatalf("FlattenStructFields() error = %v", err)
+	}
+	wantFiltered := [][]string{
+		{"user.name", "Anthony Dardano"},
+		{"user.address.city", "N/A"},
+		{"user.address.state", "FL"},
+	}
+	if !reflect.DeepEqual(filtered, wantFiltered) {
+		t.Errorf("FlattenStructFields() with headers = %v, want %v", filtered, wantFiltered)
+	}
+}
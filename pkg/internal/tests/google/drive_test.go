@@ -0,0 +1,118 @@
+/*
+# Google Drive - Tests
+
+This package tests functions which interact with the Google Drive API:
+https://developers.google.com/drive/api/v3/reference/
+
+:Copyright: (c) 2025 by Gemini Space Station, LLC, see AUTHORS for more info
+:License: See the LICENSE file for details
+:Author: Anthony Dardano <anthony.dardano@gemini.com>
+*/
+
+// pkg/internal/tests/google/drive_test.go
+package google_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gemini-oss/rego/pkg/common/log"
+	"github.com/gemini-oss/rego/pkg/common/requests"
+	"github.com/gemini-oss/rego/pkg/google"
+)
+
+// captureTransport records the last request and answers with a canned JSON body
+type captureTransport struct {
+	req  *http.Request
+	body []byte
+	resp string
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+	if req.Body != nil {
+		t.body, _ = io.ReadAll(req.Body)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body:       io.NopCloser(strings.NewReader(t.resp)),
+		Header:     http.Header{"Content-Type": {requests.JSON}},
+		Request:    req,
+	}, nil
+}
+
+func TestUploadFile(t *testing.T) {
+	transport := &captureTransport{resp: `{"id":"file-id","name":"report.csv"}`}
+	httpClient := requests.NewClient(&http.Client{Transport: transport}, requests.Headers{"Content-Type": requests.JSON}, nil)
+	httpClient.BodyType = requests.JSON
+
+	dc := &google.DriveClient{Client: &google.Client{
+		HTTP: httpClient,
+		Log:  log.NewLogger("{google}", log.DEBUG),
+	}}
+
+	content := []byte("name,age\nAnthony,30\n")
+	file, err := dc.UploadFile(&google.File{Name: "report.csv", Parents: []string{"folder-id"}}, "text/csv", content)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if file.ID != "file-id" {
+		t.Errorf("Expected uploaded file ID 'file-id', got %q", file.ID)
+	}
+
+	req := transport.req
+	if req.Method != "POST" || !strings.HasPrefix(req.URL.String(), google.DriveUploadFiles+"?") {
+		t.Fatalf("Expected POST to %s, got %s %s", google.DriveUploadFiles, req.Method, req.URL)
+	}
+	if req.URL.Query().Get("uploadType") != "multipart" {
+		t.Errorf("Expected uploadType=multipart, got %q", req.URL.Query().Get("uploadType"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != requests.MultipartRelated || params["boundary"] == "" {
+		t.Fatalf("Expected multipart/related with a boundary, got %q", req.Header.Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(transport.body, []byte("--"+params["boundary"]+"\r\n")) ||
+		!bytes.HasSuffix(transport.body, []byte("\r\n--"+params["boundary"]+"--\r\n")) {
+		t.Errorf("Expected body to open and close with the boundary, got %q", transport.body)
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(transport.body), params["boundary"])
+
+	metadata, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("Reading metadata part: %v", err)
+	}
+	if ct := metadata.Header.Get("Content-Type"); ct != "application/json; charset=UTF-8" {
+		t.Errorf("Expected JSON metadata part, got %q", ct)
+	}
+	var meta map[string]interface{}
+	if err := json.NewDecoder(metadata).Decode(&meta); err != nil {
+		t.Fatalf("Decoding metadata part: %v", err)
+	}
+	if meta["name"] != "report.csv" {
+		t.Errorf("Expected metadata name 'report.csv', got %v", meta["name"])
+	}
+
+	media, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("Reading media part: %v", err)
+	}
+	if ct := media.Header.Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Expected media part of type text/csv, got %q", ct)
+	}
+	if got, _ := io.ReadAll(media); !bytes.Equal(got, content) {
+		t.Errorf("Expected media part %q, got %q", content, got)
+	}
+
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("Expected exactly two parts, got err = %v", err)
+	}
+}
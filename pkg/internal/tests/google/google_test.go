@@ -13,11 +13,14 @@ https://developers.google.com/workspace
 package google_test
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gemini-oss/rego/pkg/common/log"
+	"github.com/gemini-oss/rego/pkg/common/requests"
 	"github.com/gemini-oss/rego/pkg/google"
 )
 
@@ -79,3 +82,38 @@ func TestNewClient(t *testing.T) {
 		t.Fatalf("Expected scope to be 'https://www.googleapis.com/auth/userinfo.email', got %v", c.Auth.Scopes[0])
 	}
 }
+
+func TestParseError(t *testing.T) {
+	body := []byte(`{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND"}}`)
+
+	err := google.ParseError(http.StatusNotFound, body)
+	var detail *google.ErrorDetail
+	if !errors.As(err, &detail) {
+		t.Fatalf("Expected error of type *google.ErrorDetail, got %T", err)
+	}
+	if detail.Message != "Requested entity was not found." || detail.Status != "NOT_FOUND" {
+		t.Errorf("Expected parsed message and status, got %+v", detail)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", requests.JSON)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := requests.NewClient(server.Client(), nil, nil, requests.ErrorParser(google.ParseError))
+	_, _, err = client.DoRequest(context.Background(), "GET", server.URL, nil, nil)
+
+	var reqErr *requests.RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("Expected error of type *requests.RequestError, got %T", err)
+	}
+	if reqErr.Message != detail.Error() {
+		t.Errorf("Expected message '%s', got '%s'", detail.Error(), reqErr.Message)
+	}
+
+	if err := google.ParseError(http.StatusBadGateway, []byte("<html>Bad Gateway</html>")); err != nil {
+		t.Errorf("Expected nil for a non-Google body, got %v", err)
+	}
+}
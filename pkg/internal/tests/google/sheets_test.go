@@ -0,0 +1,2385 @@
+/*
+# Google Sheets - Tests
+
+This package tests functions which interact with the Google Sheets API:
+https://developers.google.com/sheets/api/reference/rest
+
+:Copyright: (c) 2025 by Gemini Space Station, LLC, see AUTHORS for more info
+:License: See the LICENSE file for details
+:Author: Anthony Dardano <anthony.dardano@gemini.com>
+*/
+
+// pkg/internal/tests/google/sheets_test.go
+package google_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gemini-oss/rego/pkg/common/log"
+	"github.com/gemini-oss/rego/pkg/common/requests"
+	"github.com/gemini-oss/rego/pkg/google"
+)
+
+type sheetRow struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// setupSheetsClient points the Sheets endpoints at a test server and returns a client which uses it
+func setupSheetsClient(t *testing.T, handler http.HandlerFunc) *google.SheetsClient {
+	t.Setenv("REGO_ENCRYPTION_KEY", "Xk9#mQ2$vL7pR4!wZ8@nB3^tY6&jH1*c")
+
+	server := httptest.NewServer(handler)
+	sheets, sheetByID, batchUpdate := google.Sheets, google.SheetByID, google.SheetValuesBatchUpdate
+	google.Sheets = server.URL + "/v4/spreadsheets"
+	google.SheetByID = google.Sheets + "/%s"
+	google.SheetValuesBatchUpdate = google.Sheets + "/%s/values:batchUpdate"
+	t.Cleanup(func() {
+		server.Close()
+		google.Sheets, google.SheetByID, google.SheetValuesBatchUpdate = sheets, sheetByID, batchUpdate
+	})
+
+	client := &google.Client{
+		HTTP: requests.NewClient(server.Client(), requests.Headers{"Content-Type": requests.JSON}, nil),
+		Log:  log.NewLogger("{google}", log.DEBUG),
+	}
+	client.HTTP.BodyType = requests.JSON
+
+	return client.Sheets()
+}
+
+// mockCall records a single request issued through mockDoer
+type mockCall struct {
+	Method string
+	URL    string
+	Query  interface{}
+	Data   interface{}
+}
+
+// mockDoer returns canned responses keyed by "METHOD URL" and records every call
+type mockDoer struct {
+	mu        sync.Mutex
+	responses map[string]string
+	calls     []mockCall
+}
+
+func (m *mockDoer) DoRequest(ctx context.Context, method string, url string, query interface{}, data interface{}) (*http.Response, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, mockCall{Method: method, URL: url, Query: query, Data: data})
+
+	body, ok := m.responses[method+" "+url]
+	if !ok {
+		res := &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+		return res, []byte(`{"error":{"code":404,"message":"not found"}}`), &requests.RequestError{StatusCode: res.StatusCode, Method: method, URL: url}
+	}
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK"}, []byte(body), nil
+}
+
+// writtenValues is a value range as encoded on the wire, where cells keep their JSON types
+type writtenValues struct {
+	Range  string          `json:"range"`
+	Values [][]interface{} `json:"values"`
+}
+
+// decodeWrittenValues round-trips a write payload through JSON, as the API would receive it
+func decodeWrittenValues(t *testing.T, data interface{}) *writtenValues {
+	t.Helper()
+	body, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Marshaling payload: %v", err)
+	}
+	written := &writtenValues{}
+	if err := json.Unmarshal(body, written); err != nil {
+		t.Fatalf("Unmarshaling payload: %v", err)
+	}
+	return written
+}
+
+// setupMockSheetsClient returns a client whose requests are served by a mockDoer
+func setupMockSheetsClient(responses map[string]string) (*google.SheetsClient, *mockDoer) {
+	mock := &mockDoer{responses: responses}
+	client := &google.Client{
+		Doer: mock,
+		Log:  log.NewLogger("{google}", log.DEBUG),
+	}
+	return &google.SheetsClient{Client: client}, mock
+}
+
+func TestUpdateSpreadsheet(t *testing.T) {
+	url := google.Sheets + "/sheet-id/values/Logs!A:B"
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"PUT " + url: `{"spreadsheetId":"sheet-id","updatedRange":"Logs!A1:B2","updatedRows":2}`,
+	})
+
+	vr := &google.ValueRange{
+		Range: "Logs!A:B",
+		Values: [][]string{
+			{"name", "age"},
+			{"Anthony", "30"},
+		},
+	}
+	if err := sc.UpdateSpreadsheet("sheet-id", vr); err != nil {
+		t.Fatalf("UpdateSpreadsheet() error = %v", err)
+	}
+
+	if len(mock.calls) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(mock.calls))
+	}
+	call := mock.calls[0]
+	if call.Method != "PUT" || call.URL != url {
+		t.Errorf("Request = %s %s, want PUT %s", call.Method, call.URL, url)
+	}
+	if q, ok := call.Query.(google.SheetValueQuery); !ok || q.ValueInputOption != "RAW" || q.IncludeValuesInResponse {
+		t.Errorf("Query = %+v, want ValueInputOption RAW", call.Query)
+	}
+	if call.Data != vr {
+		t.Errorf("Data = %+v, want %+v", call.Data, vr)
+	}
+
+	t.Run("Response Values", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			"PUT " + url: `{"spreadsheetId":"sheet-id","updatedRange":"Logs!A1:B2","updatedData":{"range":"Logs!A1:B2","majorDimension":"ROWS","values":[["name","age"],["Anthony","30"]]}}`,
+		})
+
+		var stored google.ValueRange
+		if err := sc.UpdateSpreadsheet("sheet-id", vr, google.WithResponseValues(&stored)); err != nil {
+			t.Fatalf("UpdateSpreadsheet() error = %v", err)
+		}
+		if q, ok := mock.calls[0].Query.(google.SheetValueQuery); !ok || !q.IncludeValuesInResponse {
+			t.Errorf("Query = %+v, want IncludeValuesInResponse", mock.calls[0].Query)
+		}
+		if stored.Range != "Logs!A1:B2" || !reflect.DeepEqual(stored.Values, vr.Values) {
+			t.Errorf("Response values = %+v, want %v", stored, vr.Values)
+		}
+	})
+
+	t.Run("Append Response Values", func(t *testing.T) {
+		appendURL := url + ":append"
+		sc, mock := setupMockSheetsClient(map[string]string{
+			"POST " + appendURL: `{"spreadsheetId":"sheet-id","tableRange":"Logs!A1:B2","updates":{"updatedRange":"Logs!A3:B3","updatedData":{"range":"Logs!A3:B3","values":[["Sarah","32"]]}}}`,
+		})
+
+		var stored google.ValueRange
+		row := &google.ValueRange{Range: "Logs!A:B", Values: [][]string{{"Sarah", "32"}}}
+		if err := sc.AppendSpreadsheet("sheet-id", row, google.WithResponseValues(&stored)); err != nil {
+			t.Fatalf("AppendSpreadsheet() error = %v", err)
+		}
+		if q, ok := mock.calls[0].Query.(google.SheetValueQuery); !ok || !q.IncludeValuesInResponse {
+			t.Errorf("Query = %+v, want IncludeValuesInResponse", mock.calls[0].Query)
+		}
+		if q, _ := mock.calls[0].Query.(google.SheetValueQuery); q.InsertDataOption != "" {
+			t.Errorf("InsertDataOption = %q, want the API default", q.InsertDataOption)
+		}
+		if stored.Range != "Logs!A3:B3" || !reflect.DeepEqual(stored.Values, row.Values) {
+			t.Errorf("Response values = %+v, want %v", stored, row.Values)
+		}
+	})
+
+	t.Run("Insert Rows", func(t *testing.T) {
+		appendURL := url + ":append"
+		sc, mock := setupMockSheetsClient(map[string]string{
+			"POST " + appendURL: `{"spreadsheetId":"sheet-id"}`,
+		})
+
+		row := &google.ValueRange{Range: "Logs!A:B", Values: [][]string{{"Sarah", "32"}}}
+		if err := sc.AppendSpreadsheet("sheet-id", row, google.WithInsertRows()); err != nil {
+			t.Fatalf("AppendSpreadsheet() error = %v", err)
+		}
+		if q, _ := mock.calls[0].Query.(google.SheetValueQuery); q.InsertDataOption != "INSERT_ROWS" {
+			t.Errorf("InsertDataOption = %q, want %q", q.InsertDataOption, "INSERT_ROWS")
+		}
+	})
+
+	t.Run("API Error", func(t *testing.T) {
+		sc, _ := setupMockSheetsClient(nil)
+		err := sc.UpdateSpreadsheet("missing-id", &google.ValueRange{Values: [][]string{{"name"}}})
+		if err == nil {
+			t.Fatal("Expected error for unknown spreadsheet, got nil")
+		}
+	})
+}
+
+func TestUpdateSpreadsheetRaggedRoundTrip(t *testing.T) {
+	// The API drops trailing empty cells, so rows read back are shorter than the header
+	readURL := google.Sheets + "/sheet-id/values/Users!A1:C3"
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + readURL: `{"range":"Users!A1:C3","majorDimension":"ROWS","values":[["name","email","team"],["Anthony","anthony@gemini.com"],["Sarah"]]}`,
+		"PUT " + readURL: `{"spreadsheetId":"sheet-id","updatedRange":"Users!A1:C3"}`,
+	})
+
+	vr, err := sc.ReadSpreadsheetValues("sheet-id", "Users!A1:C3")
+	if err != nil {
+		t.Fatalf("ReadSpreadsheetValues() error = %v", err)
+	}
+	if len(vr.Values[2]) != 1 {
+		t.Fatalf("ReadSpreadsheetValues() = %v, want a ragged range", vr.Values)
+	}
+
+	vr.Values[2][0] = "Sarah Smith"
+	if err := sc.UpdateSpreadsheet("sheet-id", vr); err != nil {
+		t.Fatalf("UpdateSpreadsheet() error = %v", err)
+	}
+	if err := sc.UpdateSpreadsheet("sheet-id", vr, google.WithHeaderRow()); err != nil {
+		t.Fatalf("UpdateSpreadsheet() with a header row error = %v", err)
+	}
+	if got := mock.calls[len(mock.calls)-1]; got.Method != "PUT" || got.URL != readURL {
+		t.Errorf("Request = %s %s, want PUT %s", got.Method, got.URL, readURL)
+	}
+}
+
+func TestVerifySheetValueRange(t *testing.T) {
+	sc := &google.SheetsClient{Client: &google.Client{}}
+
+	tests := []struct {
+		name    string
+		vr      *google.ValueRange
+		opts    []google.WriteOption
+		wantErr bool
+		wantRow int
+	}{
+		{
+			name:    "Nil Values",
+			vr:      &google.ValueRange{},
+			wantErr: true,
+			wantRow: -1,
+		},
+		{
+			name: "Wide Row",
+			vr: &google.ValueRange{
+				Values: [][]string{
+					{"name", "email"},
+					{"Anthony", "anthony@gemini.com"},
+					{"Dardano", "dardano@gemini.com", "extra"},
+				},
+			},
+			opts:    []google.WriteOption{google.WithHeaderRow()},
+			wantErr: true,
+			wantRow: 2,
+		},
+		{
+			name: "Short Row",
+			vr: &google.ValueRange{
+				Values: [][]string{
+					{"name", "email"},
+					{"Dardano"},
+				},
+			},
+			opts:    []google.WriteOption{google.WithHeaderRow()},
+			wantErr: false,
+		},
+		{
+			name: "No Header",
+			vr: &google.ValueRange{
+				Values: [][]string{
+					{"Anthony"},
+					{"Dardano", "dardano@gemini.com"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Valid Range",
+			vr: &google.ValueRange{
+				Values: [][]string{
+					{"name", "email"},
+					{"Anthony", "anthony@gemini.com"},
+				},
+			},
+			opts:    []google.WriteOption{google.WithHeaderRow()},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sc.VerifySheetValueRange(tt.vr, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifySheetValueRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.vr.Range == "" || tt.vr.MajorDimension != "ROWS" {
+				t.Errorf("VerifySheetValueRange() did not fill defaults: %+v", tt.vr)
+			}
+			if !tt.wantErr {
+				return
+			}
+
+			var vrErr *google.ValueRangeError
+			if !errors.As(err, &vrErr) {
+				t.Fatalf("Expected error of type *google.ValueRangeError, got %T", err)
+			}
+			if vrErr.Row != tt.wantRow {
+				t.Errorf("ValueRangeError.Row = %d, want %d", vrErr.Row, tt.wantRow)
+			}
+		})
+	}
+}
+
+func TestSaveToSheetAppend(t *testing.T) {
+	tests := []struct {
+		name            string
+		existingHeader  string
+		opts            []google.SaveOption
+		wantErr         error
+		wantHeaderWrite []string
+		wantAppended    [][]interface{}
+	}{
+		{
+			name:           "Matching Header",
+			existingHeader: `[["name","age"]]`,
+			wantAppended:   [][]interface{}{{"Anthony", 30.0}, {"Dardano", 25.0}},
+		},
+		{
+			name:           "Empty Sheet",
+			existingHeader: `[]`,
+			wantAppended:   [][]interface{}{{"name", "age"}, {"Anthony", 30.0}, {"Dardano", 25.0}},
+		},
+		{
+			name:           "Mismatched Header",
+			existingHeader: `[["name","email"]]`,
+			wantErr:        google.ErrHeaderMismatch,
+		},
+		{
+			name:            "Mismatched Header Rewrite",
+			existingHeader:  `[["name","email"]]`,
+			opts:            []google.SaveOption{google.WithRewriteHeader()},
+			wantHeaderWrite: []string{"name", "age"},
+			wantAppended:    [][]interface{}{{"Anthony", 30.0}, {"Dardano", 25.0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headerWrite *google.ValueRange
+			var appended *writtenValues
+			var insertOption string
+
+			sc := setupSheetsClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == "GET" && r.URL.Path == "/v4/spreadsheets/sheet-id":
+					w.Write([]byte(`{"spreadsheetId":"sheet-id","sheets":[{"properties":{"sheetId":1,"title":"Logs"}}]}`))
+				case r.Method == "GET" && r.URL.Path == "/v4/spreadsheets/sheet-id/values/Logs!1:1":
+					w.Write([]byte(`{"range":"Logs!A1:B1","majorDimension":"ROWS","values":` + tt.existingHeader + `}`))
+				case r.Method == "PUT" && r.URL.Path == "/v4/spreadsheets/sheet-id/values/Logs!1:1":
+					body, _ := io.ReadAll(r.Body)
+					headerWrite = &google.ValueRange{}
+					json.Unmarshal(body, headerWrite)
+					w.Write([]byte(`{}`))
+				case r.Method == "POST" && r.URL.Path == "/v4/spreadsheets/sheet-id/values/Logs!A:ZZ:append":
+					insertOption = r.URL.Query().Get("insertDataOption")
+					body, _ := io.ReadAll(r.Body)
+					appended = &writtenValues{}
+					json.Unmarshal(body, appended)
+					w.Write([]byte(`{}`))
+				case r.Method == "POST" && r.URL.Path == "/v4/spreadsheets/sheet-id:batchUpdate":
+					w.Write([]byte(`{}`))
+				default:
+					t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			data := []sheetRow{{Name: "Anthony", Age: 30}, {Name: "Dardano", Age: 25}}
+			opts := append([]google.SaveOption{google.WithAppend()}, tt.opts...)
+			err := sc.SaveToSheet(data, "sheet-id", "Logs", nil, opts...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SaveToSheet() error = %v, want %v", err, tt.wantErr)
+				}
+				if appended != nil {
+					t.Errorf("SaveToSheet() appended rows despite error: %v", appended.Values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SaveToSheet() error = %v", err)
+			}
+
+			if tt.wantHeaderWrite != nil {
+				if headerWrite == nil || !reflect.DeepEqual(headerWrite.Values, [][]string{tt.wantHeaderWrite}) {
+					t.Errorf("Header rewrite = %v, want %v", headerWrite, tt.wantHeaderWrite)
+				}
+			} else if headerWrite != nil {
+				t.Errorf("Unexpected header rewrite: %v", headerWrite.Values)
+			}
+
+			if appended == nil {
+				t.Fatal("SaveToSheet() did not append any rows")
+			}
+			if insertOption != "INSERT_ROWS" {
+				t.Errorf("insertDataOption = %q, want %q", insertOption, "INSERT_ROWS")
+			}
+			if !reflect.DeepEqual(appended.Values, tt.wantAppended) {
+				t.Errorf("Appended values = %v, want %v", appended.Values, tt.wantAppended)
+			}
+		})
+	}
+}
+
+func TestUpsertToSheet(t *testing.T) {
+	tests := []struct {
+		name         string
+		existing     string
+		wantUpdates  map[string][][]interface{}
+		wantBatches  int
+		wantAppended [][]interface{}
+	}{
+		{
+			name:         "Update And Append",
+			existing:     `[["name","age"],["Bob","40"],["Anthony","20"]]`,
+			wantUpdates:  map[string][][]interface{}{"Logs!A3:ZZ3": {{"Anthony", 30.0}}},
+			wantBatches:  1,
+			wantAppended: [][]interface{}{{"Dardano", 25.0}},
+		},
+		{
+			name:     "Update Only",
+			existing: `[["name","age"],["Dardano","40"],["Anthony","20"]]`,
+			wantUpdates: map[string][][]interface{}{
+				"Logs!A2:ZZ2": {{"Dardano", 25.0}},
+				"Logs!A3:ZZ3": {{"Anthony", 30.0}},
+			},
+			wantBatches: 1,
+		},
+		{
+			name:        "Empty Sheet",
+			existing:    `[]`,
+			wantUpdates: map[string][][]interface{}{"Logs!A:ZZ": {{"name", "age"}, {"Anthony", 30.0}, {"Dardano", 25.0}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := map[string][][]interface{}{}
+			var appended *writtenValues
+			batches := 0
+
+			sc := setupSheetsClient(t, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				switch {
+				case r.Method == "GET" && r.URL.Path == "/v4/spreadsheets/sheet-id/values/Logs!A:ZZ":
+					w.Write([]byte(`{"range":"Logs!A1:B3","majorDimension":"ROWS","values":` + tt.existing + `}`))
+				case r.Method == "PUT":
+					written := &writtenValues{}
+					json.Unmarshal(body, written)
+					updates[written.Range] = written.Values
+					w.Write([]byte(`{}`))
+				case r.Method == "POST" && r.URL.Path == "/v4/spreadsheets/sheet-id/values:batchUpdate":
+					batch := struct {
+						Data []writtenValues `json:"data"`
+					}{}
+					json.Unmarshal(body, &batch)
+					for _, written := range batch.Data {
+						updates[written.Range] = written.Values
+					}
+					batches++
+					w.Write([]byte(`{}`))
+				case r.Method == "POST" && r.URL.Path == "/v4/spreadsheets/sheet-id/values/Logs!A:ZZ:append":
+					appended = &writtenValues{}
+					json.Unmarshal(body, appended)
+					w.Write([]byte(`{}`))
+				default:
+					t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			data := []sheetRow{{Name: "Anthony", Age: 30}, {Name: "Dardano", Age: 25}}
+			if err := sc.UpsertToSheet(data, "sheet-id", "Logs", "name", nil); err != nil {
+				t.Fatalf("UpsertToSheet() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(updates, tt.wantUpdates) {
+				t.Errorf("Updated ranges = %v, want %v", updates, tt.wantUpdates)
+			}
+			if batches != tt.wantBatches {
+				t.Errorf("Sent %d batch updates, want %d", batches, tt.wantBatches)
+			}
+			switch {
+			case tt.wantAppended == nil && appended != nil:
+				t.Errorf("Unexpected append: %v", appended.Values)
+			case tt.wantAppended != nil && (appended == nil || !reflect.DeepEqual(appended.Values, tt.wantAppended)):
+				t.Errorf("Appended values = %v, want %v", appended, tt.wantAppended)
+			}
+		})
+	}
+
+	t.Run("Missing Key Column", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(nil)
+		err := sc.UpsertToSheet([]sheetRow{{Name: "Anthony", Age: 30}}, "sheet-id", "Logs", "email", nil)
+		if err == nil {
+			t.Fatal("UpsertToSheet() error = nil, want missing key column error")
+		}
+		if len(mock.calls) != 0 {
+			t.Errorf("UpsertToSheet() sent %d requests, want none", len(mock.calls))
+		}
+	})
+}
+
+func TestGetCell(t *testing.T) {
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + google.Sheets + "/sheet-id/values/Logs!B2": `{"range":"Logs!B2","majorDimension":"ROWS","values":[[42.5]]}`,
+		"GET " + google.Sheets + "/sheet-id/values/Logs!C9": `{"range":"Logs!C9","majorDimension":"ROWS"}`,
+	})
+
+	got, err := sc.GetCell("sheet-id", "Logs!B2")
+	if err != nil {
+		t.Fatalf("GetCell() error = %v", err)
+	}
+	if got != 42.5 {
+		t.Errorf("GetCell() = %#v, want 42.5", got)
+	}
+	if q, ok := mock.calls[0].Query.(google.SheetValueQuery); !ok || q.ValueRenderOption != "UNFORMATTED_VALUE" {
+		t.Errorf("GetCell() query = %+v, want UNFORMATTED_VALUE", mock.calls[0].Query)
+	}
+
+	got, err = sc.GetCell("sheet-id", "Logs!C9")
+	if err != nil {
+		t.Fatalf("GetCell() empty cell error = %v", err)
+	}
+	if got != nil {
+		t.Errorf("GetCell() empty cell = %#v, want nil", got)
+	}
+
+	for _, a1 := range []string{"Logs!B2:C3", "Logs!B", "Logs!2", "B0"} {
+		if _, err := sc.GetCell("sheet-id", a1); !errors.Is(err, google.ErrNotSingleCell) {
+			t.Errorf("GetCell(%q) error = %v, want %v", a1, err, google.ErrNotSingleCell)
+		}
+	}
+	if len(mock.calls) != 2 {
+		t.Errorf("GetCell() sent %d requests, want 2", len(mock.calls))
+	}
+}
+
+func TestSetCell(t *testing.T) {
+	url := google.Sheets + "/sheet-id/values/Status!B2"
+	tests := []struct {
+		name       string
+		value      interface{}
+		opts       []google.WriteOption
+		wantValue  interface{}
+		wantOption string
+	}{
+		{name: "Bool", value: true, wantValue: true, wantOption: "RAW"},
+		{name: "Number", value: 3, wantValue: 3.0, wantOption: "RAW"},
+		{name: "Formula", value: "=NOW()", opts: []google.WriteOption{google.WithValueInputOption("USER_ENTERED")}, wantValue: "=NOW()", wantOption: "USER_ENTERED"},
+		{name: "Clear", value: nil, wantValue: "", wantOption: "RAW"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, mock := setupMockSheetsClient(map[string]string{"PUT " + url: `{}`})
+			if err := sc.SetCell("sheet-id", "Status!B2", tt.value, tt.opts...); err != nil {
+				t.Fatalf("SetCell() error = %v", err)
+			}
+
+			if len(mock.calls) != 1 || mock.calls[0].URL != url {
+				t.Fatalf("SetCell() calls = %+v, want one PUT to %s", mock.calls, url)
+			}
+			if q := mock.calls[0].Query.(google.SheetValueQuery); q.ValueInputOption != tt.wantOption {
+				t.Errorf("valueInputOption = %q, want %q", q.ValueInputOption, tt.wantOption)
+			}
+			written := decodeWrittenValues(t, mock.calls[0].Data)
+			if written.Range != "Status!B2" {
+				t.Errorf("Written range = %q, want %q", written.Range, "Status!B2")
+			}
+			if want := [][]interface{}{{tt.wantValue}}; !reflect.DeepEqual(written.Values, want) {
+				t.Errorf("Written values = %v, want %v", written.Values, want)
+			}
+		})
+	}
+
+	sc, mock := setupMockSheetsClient(nil)
+	if err := sc.SetCell("sheet-id", "Status!B2:B3", "done"); !errors.Is(err, google.ErrNotSingleCell) {
+		t.Errorf("SetCell() error = %v, want %v", err, google.ErrNotSingleCell)
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("SetCell() sent %d requests for a multi-cell range, want none", len(mock.calls))
+	}
+}
+
+func TestSaveChannelToSheet(t *testing.T) {
+	url := fmt.Sprintf(google.SheetValuesAppend, "sheet-id", "Logs!A:ZZ")
+	headerURL := "GET " + google.Sheets + "/sheet-id/values/Logs!1:1"
+	emptyHeader := `{"range":"Logs!1:1","majorDimension":"ROWS"}`
+
+	// appends returns the append requests sent, skipping the header row read
+	appends := func(mock *mockDoer) []mockCall {
+		var calls []mockCall
+		for _, call := range mock.calls {
+			if call.Method != "GET" {
+				calls = append(calls, call)
+			}
+		}
+		return calls
+	}
+
+	defer func(size int) { google.DefaultStreamBatchSize = size }(google.DefaultStreamBatchSize)
+	google.DefaultStreamBatchSize = 2
+
+	t.Run("Batches", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			headerURL:     emptyHeader,
+			"POST " + url: `{"spreadsheetId":"sheet-id"}`,
+		})
+
+		ch := make(chan interface{})
+		go func() {
+			defer close(ch)
+			for i := 1; i <= 5; i++ {
+				ch <- sheetRow{Name: fmt.Sprintf("user%d", i), Age: 20 + i}
+			}
+		}()
+
+		if err := sc.SaveChannelToSheet(ch, "sheet-id", "Logs", nil); err != nil {
+			t.Fatalf("SaveChannelToSheet() error = %v", err)
+		}
+
+		want := [][][]interface{}{
+			{{"name", "age"}, {"user1", float64(21)}, {"user2", float64(22)}},
+			{{"user3", float64(23)}, {"user4", float64(24)}},
+			{{"user5", float64(25)}},
+		}
+		calls := appends(mock)
+		if len(calls) != len(want) {
+			t.Fatalf("Expected %d appends, got %d", len(want), len(calls))
+		}
+		for i, call := range calls {
+			if call.Method != "POST" || call.URL != url {
+				t.Errorf("Request %d = %s %s, want POST %s", i, call.Method, call.URL, url)
+			}
+			if got := decodeWrittenValues(t, call.Data).Values; !reflect.DeepEqual(got, want[i]) {
+				t.Errorf("Batch %d = %v, want %v", i, got, want[i])
+			}
+		}
+	})
+
+	t.Run("Fixed Headers", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			headerURL:     emptyHeader,
+			"POST " + url: `{"spreadsheetId":"sheet-id"}`,
+		})
+
+		ch := make(chan interface{}, 3)
+		for i := 1; i <= 3; i++ {
+			ch <- sheetRow{Name: fmt.Sprintf("user%d", i), Age: 20 + i}
+		}
+		close(ch)
+
+		headers := []string{"age", "name"}
+		if err := sc.SaveChannelToSheet(ch, "sheet-id", "Logs", &headers); err != nil {
+			t.Fatalf("SaveChannelToSheet() error = %v", err)
+		}
+		calls := appends(mock)
+		if len(calls) != 2 {
+			t.Fatalf("Expected 2 appends, got %d", len(calls))
+		}
+		if got := decodeWrittenValues(t, calls[0].Data).Values[0]; !reflect.DeepEqual(got, []interface{}{"age", "name"}) {
+			t.Errorf("Header row = %v, want [age name]", got)
+		}
+		want := [][]interface{}{{float64(23), "user3"}}
+		if got := decodeWrittenValues(t, calls[1].Data).Values; !reflect.DeepEqual(got, want) {
+			t.Errorf("Last batch = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Existing Header", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			headerURL:     `{"range":"Logs!1:1","majorDimension":"ROWS","values":[["name","age"]]}`,
+			"POST " + url: `{"spreadsheetId":"sheet-id"}`,
+		})
+
+		ch := make(chan interface{}, 3)
+		for i := 1; i <= 3; i++ {
+			ch <- sheetRow{Name: fmt.Sprintf("user%d", i), Age: 20 + i}
+		}
+		close(ch)
+
+		if err := sc.SaveChannelToSheet(ch, "sheet-id", "Logs", nil); err != nil {
+			t.Fatalf("SaveChannelToSheet() error = %v", err)
+		}
+		want := [][][]interface{}{
+			{{"user1", float64(21)}, {"user2", float64(22)}},
+			{{"user3", float64(23)}},
+		}
+		calls := appends(mock)
+		if len(calls) != len(want) {
+			t.Fatalf("Expected %d appends, got %d", len(want), len(calls))
+		}
+		for i, call := range calls {
+			if got := decodeWrittenValues(t, call.Data).Values; !reflect.DeepEqual(got, want[i]) {
+				t.Errorf("Batch %d = %v, want %v", i, got, want[i])
+			}
+		}
+	})
+
+	t.Run("Mismatched Header", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			headerURL: `{"range":"Logs!1:1","majorDimension":"ROWS","values":[["name","email"]]}`,
+		})
+
+		ch := make(chan interface{}, 1)
+		ch <- sheetRow{Name: "user1", Age: 21}
+		close(ch)
+
+		if err := sc.SaveChannelToSheet(ch, "sheet-id", "Logs", nil); !errors.Is(err, google.ErrHeaderMismatch) {
+			t.Errorf("SaveChannelToSheet() error = %v, want ErrHeaderMismatch", err)
+		}
+		if calls := appends(mock); len(calls) != 0 {
+			t.Errorf("Expected no appends, got %d", len(calls))
+		}
+	})
+
+	t.Run("Empty Channel", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(nil)
+		ch := make(chan interface{})
+		close(ch)
+		if err := sc.SaveChannelToSheet(ch, "sheet-id", "Logs", nil); !errors.Is(err, google.ErrNoData) {
+			t.Errorf("SaveChannelToSheet() error = %v, want ErrNoData", err)
+		}
+		if len(mock.calls) != 0 {
+			t.Errorf("Expected no requests, got %d", len(mock.calls))
+		}
+	})
+}
+
+func TestBatchUpdateValues(t *testing.T) {
+	url := fmt.Sprintf(google.SheetValuesBatchUpdate, "sheet-id")
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"POST " + url: `{"spreadsheetId":"sheet-id","totalUpdatedRows":3,"totalUpdatedCells":5,"totalUpdatedSheets":2,
+			"responses":[{"updatedRange":"Logs!A1:B2","updatedCells":4},{"updatedRange":"Summary!A1","updatedCells":1}]}`,
+	})
+
+	ranges := []*google.ValueRange{
+		{Range: "Logs!A1:B2", Values: [][]string{{"name", "age"}, {"Anthony", "30"}}},
+		{Range: "Summary!A1", Values: [][]string{{"total"}}},
+	}
+	res, err := sc.BatchUpdateValues("sheet-id", ranges, "")
+	if err != nil {
+		t.Fatalf("BatchUpdateValues() error = %v", err)
+	}
+	if res.TotalUpdatedCells != 5 || len(res.Responses) != 2 || res.Responses[1].UpdatedRange != "Summary!A1" {
+		t.Errorf("BatchUpdateValues() = %+v, want the aggregate response", res)
+	}
+
+	if len(mock.calls) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(mock.calls))
+	}
+	call := mock.calls[0]
+	if call.Method != "POST" || call.URL != url {
+		t.Errorf("Request = %s %s, want POST %s", call.Method, call.URL, url)
+	}
+	req, ok := call.Data.(*google.BatchUpdateValuesRequest)
+	if !ok {
+		t.Fatalf("Data = %T, want *google.BatchUpdateValuesRequest", call.Data)
+	}
+	if req.ValueInputOption != "RAW" || len(req.Data) != len(ranges) {
+		t.Fatalf("Request = %+v, want RAW with %d ranges", req, len(ranges))
+	}
+	for i, data := range req.Data {
+		got := decodeWrittenValues(t, data)
+		if got.Range != ranges[i].Range || len(got.Values) != len(ranges[i].Values) {
+			t.Errorf("Data[%d] = %+v, want %+v", i, got, ranges[i])
+		}
+	}
+
+	t.Run("Invalid Range", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(nil)
+		ranges := []*google.ValueRange{
+			{Range: "Logs!A1:B1", Values: [][]string{{"name", "age"}}},
+			{Range: "Logs!D1:E2"},
+		}
+		var vrErr *google.ValueRangeError
+		if _, err := sc.BatchUpdateValues("sheet-id", ranges, "USER_ENTERED"); !errors.As(err, &vrErr) {
+			t.Errorf("BatchUpdateValues() error = %v, want a ValueRangeError", err)
+		}
+		if len(mock.calls) != 0 {
+			t.Errorf("Expected no requests, got %d", len(mock.calls))
+		}
+	})
+}
+
+func TestSaveToSheetDryRun(t *testing.T) {
+	sc, mock := setupMockSheetsClient(nil)
+
+	var plan google.SavePlan
+	data := []sheetRow{{Name: "Anthony", Age: 30}, {Name: "Dardano", Age: 25}}
+	if err := sc.SaveToSheet(data, "sheet-id", "Logs", nil, google.WithDryRun(&plan)); err != nil {
+		t.Fatalf("SaveToSheet() error = %v", err)
+	}
+
+	if len(mock.calls) != 0 {
+		t.Errorf("Dry run issued %d requests, want 0", len(mock.calls))
+	}
+	if plan.SpreadsheetID != "sheet-id" || plan.Append {
+		t.Errorf("Plan = %+v, want SpreadsheetID sheet-id without append", plan)
+	}
+
+	wantValues := [][]string{{"name", "age"}, {"Anthony", "30"}, {"Dardano", "25"}}
+	if plan.ValueRange == nil || plan.ValueRange.Range != "Logs!A:ZZ" || !reflect.DeepEqual(plan.ValueRange.Values, wantValues) {
+		t.Fatalf("Planned ValueRange = %+v, want Logs!A:ZZ with %v", plan.ValueRange, wantValues)
+	}
+
+	if plan.Format == nil || len(plan.Format.Requests) != 3 {
+		t.Fatalf("Planned format = %+v, want 3 requests", plan.Format)
+	}
+	header := plan.Format.Requests[0].RepeatCell
+	if header == nil || header.Range.EndRowIndex != 1 || header.Range.EndColumnIndex != 2 {
+		t.Errorf("Header format range = %+v, want first row across 2 columns", header)
+	}
+	filter := plan.Format.Requests[1].SetBasicFilter
+	if filter == nil || filter.Filter.Range.EndRowIndex != 3 {
+		t.Errorf("Basic filter = %+v, want 3 rows", filter)
+	}
+}
+
+func TestHeadersFromSheet(t *testing.T) {
+	headerURL := google.Sheets + "/sheet-id/values/Logs!1:1"
+	emptyURL := google.Sheets + "/empty-id/values/Logs!1:1"
+	sc, _ := setupMockSheetsClient(map[string]string{
+		"GET " + headerURL: `{"range":"Logs!A1:C1","majorDimension":"ROWS","values":[["id","name","email"]]}`,
+		"GET " + emptyURL:  `{"range":"Logs!A1:Z1","majorDimension":"ROWS"}`,
+	})
+
+	headers, err := sc.HeadersFromSheet("sheet-id", "Logs!1:1")
+	if err != nil {
+		t.Fatalf("HeadersFromSheet() error = %v", err)
+	}
+	if want := []string{"id", "name", "email"}; headers == nil || !reflect.DeepEqual(*headers, want) {
+		t.Errorf("HeadersFromSheet() = %v, want %v", headers, want)
+	}
+
+	headers, err = sc.HeadersFromSheet("empty-id", "Logs!1:1")
+	if err != nil {
+		t.Fatalf("HeadersFromSheet() error = %v", err)
+	}
+	if headers != nil {
+		t.Errorf("HeadersFromSheet() on empty sheet = %v, want nil", *headers)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	sc, mock := setupMockSheetsClient(nil)
+
+	type employee struct {
+		Name  string   `json:"name"`
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	}
+	data := []employee{
+		{Name: "Anthony", Title: "Engineer, Staff", Tags: []string{"DJ"}},
+		{Name: "Dardano", Title: `The "Boss"`, Tags: []string{"a", "b"}},
+	}
+
+	var buf bytes.Buffer
+	if err := sc.ExportCSV(data, &buf, nil); err != nil {
+		t.Fatalf("ExportCSV() error = %v", err)
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("ExportCSV() issued %d requests, want 0", len(mock.calls))
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Reading exported CSV: %v", err)
+	}
+
+	anyData := make([]any, len(data))
+	for i := range data {
+		anyData[i] = data[i]
+	}
+	vr := sc.GenerateValueRange(anyData, "", nil)
+	if !reflect.DeepEqual(records, vr.Values) {
+		t.Errorf("ExportCSV() = %v, want %v", records, vr.Values)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	sc, mock := setupMockSheetsClient(nil)
+
+	type employee struct {
+		Name  string   `json:"name"`
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	}
+	data := []employee{
+		{Name: "Anthony", Title: "Engineer & <Staff>", Tags: []string{"DJ"}},
+		{Name: "Dardano", Title: `The "Boss"`, Tags: []string{"a", "b"}},
+	}
+
+	var buf bytes.Buffer
+	if err := sc.ExportXLSX(data, &buf, nil); err != nil {
+		t.Fatalf("ExportXLSX() error = %v", err)
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("ExportXLSX() issued %d requests, want 0", len(mock.calls))
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Opening exported XLSX: %v", err)
+	}
+	readPart := func(name string) []byte {
+		f, err := zr.Open(name)
+		if err != nil {
+			t.Fatalf("Opening %s: %v", name, err)
+		}
+		defer f.Close()
+		content, _ := io.ReadAll(f)
+		return content
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref   string `xml:"r,attr"`
+				Style string `xml:"s,attr"`
+				Text  string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(readPart("xl/worksheets/sheet1.xml"), &sheet); err != nil {
+		t.Fatalf("Parsing worksheet: %v", err)
+	}
+
+	anyData := make([]any, len(data))
+	for i := range data {
+		anyData[i] = data[i]
+	}
+	vr := sc.GenerateValueRange(anyData, "", nil)
+
+	if len(sheet.Rows) != len(vr.Values) {
+		t.Fatalf("ExportXLSX() wrote %d rows, want %d", len(sheet.Rows), len(vr.Values))
+	}
+	for r, row := range sheet.Rows {
+		var got []string
+		for _, cell := range row.Cells {
+			got = append(got, cell.Text)
+			if bold := cell.Style == "1"; bold != (r == 0) {
+				t.Errorf("Cell %s header style = %v, want %v", cell.Ref, bold, r == 0)
+			}
+		}
+		if !reflect.DeepEqual(got, vr.Values[r]) {
+			t.Errorf("Row %d = %v, want %v", r+1, got, vr.Values[r])
+		}
+	}
+
+	var styles struct {
+		Fonts []struct {
+			Bold *struct{} `xml:"b"`
+			Size struct {
+				Val int `xml:"val,attr"`
+			} `xml:"sz"`
+		} `xml:"fonts>font"`
+	}
+	if err := xml.Unmarshal(readPart("xl/styles.xml"), &styles); err != nil {
+		t.Fatalf("Parsing styles: %v", err)
+	}
+	if len(styles.Fonts) != 2 {
+		t.Fatalf("styles.xml has %d fonts, want 2", len(styles.Fonts))
+	}
+	if styles.Fonts[1].Bold == nil {
+		t.Errorf("Expected a bold header font in styles.xml")
+	}
+	if got, want := styles.Fonts[1].Size.Val, google.DefaultHeaderFormat().FontSize; got != want {
+		t.Errorf("Header font size = %d, want the HeaderFormat's %d", got, want)
+	}
+	if body, header := styles.Fonts[0].Size.Val, styles.Fonts[1].Size.Val; body != header {
+		t.Errorf("Body font size = %d, want it to match the default header's %d", body, header)
+	}
+}
+
+func TestReadSheetAsMap(t *testing.T) {
+	url := google.Sheets + "/sheet-id/values/Users!A:ZZ"
+	dupURL := google.Sheets + "/dup-id/values/Users!A:ZZ"
+	sc, _ := setupMockSheetsClient(map[string]string{
+		"GET " + url:    `{"range":"Users!A1:C3","majorDimension":"ROWS","values":[["id","name","email"],["1","Anthony","anthony@gemini.com"],["2","Dardano"]]}`,
+		"GET " + dupURL: `{"range":"Users!A1:B3","majorDimension":"ROWS","values":[["id","name"],["1","Anthony"],["1","Dardano"]]}`,
+	})
+
+	got, err := sc.ReadSheetAsMap("sheet-id", "Users!A:ZZ", "id")
+	if err != nil {
+		t.Fatalf("ReadSheetAsMap() error = %v", err)
+	}
+	want := map[string]map[string]string{
+		"1": {"id": "1", "name": "Anthony", "email": "anthony@gemini.com"},
+		"2": {"id": "2", "name": "Dardano", "email": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadSheetAsMap() = %v, want %v", got, want)
+	}
+
+	if _, err := sc.ReadSheetAsMap("sheet-id", "Users!A:ZZ", "uuid"); err == nil {
+		t.Error("ReadSheetAsMap() expected an error for a missing key column")
+	}
+
+	got, err = sc.ReadSheetAsMap("dup-id", "Users!A:ZZ", "id")
+	if err != nil {
+		t.Fatalf("ReadSheetAsMap() error = %v", err)
+	}
+	if got["1"]["name"] != "Dardano" {
+		t.Errorf("ReadSheetAsMap() duplicate key kept %v, want last row", got["1"])
+	}
+
+	_, err = sc.ReadSheetAsMap("dup-id", "Users!A:ZZ", "id", google.WithDuplicateKeyError())
+	if !errors.Is(err, google.ErrDuplicateKey) {
+		t.Errorf("ReadSheetAsMap() error = %v, want %v", err, google.ErrDuplicateKey)
+	}
+	if err != nil && !strings.Contains(err.Error(), "(data row 2)") {
+		t.Errorf("ReadSheetAsMap() error = %v, want it to name data row 2", err)
+	}
+}
+
+func TestAutoResizeColumns(t *testing.T) {
+	url := google.Sheets + "/sheet-id:batchUpdate"
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"POST " + url: `{"spreadsheetId":"sheet-id","replies":[{}]}`,
+	})
+
+	if err := sc.AutoResizeColumns("sheet-id", 42, 2, 5); err != nil {
+		t.Fatalf("AutoResizeColumns() error = %v", err)
+	}
+
+	if len(mock.calls) != 1 || mock.calls[0].Method != "POST" || mock.calls[0].URL != url {
+		t.Fatalf("AutoResizeColumns() calls = %+v, want a single POST %s", mock.calls, url)
+	}
+	batch, ok := mock.calls[0].Data.(*google.SheetBatchRequest)
+	if !ok || len(batch.Requests) != 1 {
+		t.Fatalf("AutoResizeColumns() payload = %+v, want one request", mock.calls[0].Data)
+	}
+
+	resize, ok := batch.Requests[0].AutoResizeDimensions.(*google.AutoResizeDimensionsRequest)
+	if !ok {
+		t.Fatalf("AutoResizeColumns() request = %+v, want autoResizeDimensions", batch.Requests[0])
+	}
+	want := &google.DimensionRange{SheetID: 42, Dimension: "COLUMNS", StartIndex: 2, EndIndex: 5}
+	if !reflect.DeepEqual(resize.Dimensions, want) {
+		t.Errorf("AutoResizeColumns() dimensions = %+v, want %+v", resize.Dimensions, want)
+	}
+}
+
+func TestFormatHeaderAndAutoSize(t *testing.T) {
+	url := google.Sheets + "/sheet-id:batchUpdate"
+	sheet := &google.Sheet{Properties: &google.SheetProperties{SheetID: 7, Title: "Logs"}}
+
+	custom := &google.HeaderFormat{
+		BackgroundColor: &google.Color{Alpha: 1, Red: 0.1, Green: 0.2, Blue: 0.3},
+		TextColor:       &google.Color{Alpha: 1, Red: 1, Green: 1, Blue: 1},
+		FontSize:        12,
+		Italic:          true,
+	}
+
+	tests := []struct {
+		name   string
+		format []*google.HeaderFormat
+		want   *google.HeaderFormat
+	}{
+		{"Default", nil, google.DefaultHeaderFormat()},
+		{"Custom", []*google.HeaderFormat{custom}, custom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, mock := setupMockSheetsClient(map[string]string{"POST " + url: `{}`})
+
+			if err := sc.FormatHeaderAndAutoSize("sheet-id", sheet, 3, 2, tt.format...); err != nil {
+				t.Fatalf("FormatHeaderAndAutoSize() error = %v", err)
+			}
+			if len(mock.calls) != 1 {
+				t.Fatalf("FormatHeaderAndAutoSize() issued %d requests, want 1", len(mock.calls))
+			}
+
+			batch := mock.calls[0].Data.(*google.SheetBatchRequest)
+			cell := batch.Requests[0].RepeatCell.Cell.UserEnteredFormat
+			want := &google.CellFormat{
+				BackgroundColor: tt.want.BackgroundColor,
+				TextFormat: &google.TextFormat{
+					ForegroundColor: tt.want.TextColor,
+					FontSize:        tt.want.FontSize,
+					Bold:            tt.want.Bold,
+					Italic:          tt.want.Italic,
+				},
+			}
+			if !reflect.DeepEqual(cell, want) {
+				t.Errorf("Header CellFormat = %+v, want %+v", cell, want)
+			}
+		})
+	}
+}
+
+func TestSetGridProperties(t *testing.T) {
+	url := google.Sheets + "/sheet-id:batchUpdate"
+	sc, mock := setupMockSheetsClient(map[string]string{"POST " + url: `{}`})
+
+	if err := sc.SetGridProperties("sheet-id", 7, 1, 2, true); err != nil {
+		t.Fatalf("SetGridProperties() error = %v", err)
+	}
+	if len(mock.calls) != 1 {
+		t.Fatalf("SetGridProperties() issued %d requests, want 1", len(mock.calls))
+	}
+
+	batch := mock.calls[0].Data.(*google.SheetBatchRequest)
+	update := batch.Requests[0].UpdateSheetProperties
+	if update == nil {
+		t.Fatalf("SetGridProperties() request = %+v, want updateSheetProperties", batch.Requests[0])
+	}
+	if want := "gridProperties(frozenRowCount,frozenColumnCount,hideGridlines)"; update.Fields != want {
+		t.Errorf("Fields = %q, want %q", update.Fields, want)
+	}
+	want := &google.SheetProperties{
+		SheetID:        7,
+		GridProperties: &google.GridProperties{FrozenRowCount: 1, FrozenColumnCount: 2, HideGridlines: true},
+	}
+	if !reflect.DeepEqual(update.Properties, want) {
+		t.Errorf("Properties = %+v, want %+v", update.Properties, want)
+	}
+}
+
+func TestReadSpreadsheetValuesEmpty(t *testing.T) {
+	url := google.Sheets + "/sheet-id/values/Logs!A:ZZ"
+	sc, _ := setupMockSheetsClient(map[string]string{
+		"GET " + url: `{"range":"Logs!A1:ZZ1000","majorDimension":"ROWS"}`,
+	})
+
+	vr, err := sc.ReadSpreadsheetValues("sheet-id", "Logs!A:ZZ")
+	if err != nil {
+		t.Fatalf("ReadSpreadsheetValues() error = %v", err)
+	}
+	if vr.Values == nil || len(vr.Values) != 0 {
+		t.Errorf("ReadSpreadsheetValues() Values = %#v, want an empty non-nil slice", vr.Values)
+	}
+}
+
+func TestSaveToSheetEmpty(t *testing.T) {
+	tests := []struct {
+		name string
+		data any
+	}{
+		{"Empty Slice", []sheetRow{}},
+		{"Empty Map", map[string]sheetRow{}},
+		{"Empty Rows", [][]string{}},
+		{"Nil Pointer", (*[]sheetRow)(nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, mock := setupMockSheetsClient(nil)
+
+			err := sc.SaveToSheet(tt.data, "sheet-id", "Logs", nil)
+			if !errors.Is(err, google.ErrNoData) {
+				t.Errorf("SaveToSheet() error = %v, want %v", err, google.ErrNoData)
+			}
+			if len(mock.calls) != 0 {
+				t.Errorf("SaveToSheet() issued %d requests, want 0", len(mock.calls))
+			}
+		})
+	}
+}
+
+func TestSaveToSheetFormatError(t *testing.T) {
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + google.Sheets + "/sheet-id":                  `{"spreadsheetId":"sheet-id","sheets":[{"properties":{"sheetId":5,"title":"Logs"}}]}`,
+		"PUT " + google.Sheets + "/sheet-id/values/Logs!A:ZZ": `{}`,
+	})
+
+	if err := sc.SaveToSheet([]sheetRow{{Name: "Anthony", Age: 30}}, "sheet-id", "Logs", nil); err == nil {
+		t.Fatal("SaveToSheet() error = nil, want the failed header format's error")
+	}
+	if last := mock.calls[len(mock.calls)-1]; last.Method != "POST" || last.URL != google.Sheets+"/sheet-id:batchUpdate" {
+		t.Errorf("Last call = %s %s, want the header format batchUpdate", last.Method, last.URL)
+	}
+}
+
+func TestDefaultColumnSpan(t *testing.T) {
+	span := google.DefaultColumnSpan
+	google.DefaultColumnSpan = "A:F"
+	t.Cleanup(func() { google.DefaultColumnSpan = span })
+
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + google.Sheets + "/sheet-id/values/Sheet1!A:F": `{"range":"Sheet1!A1:F1","values":[["name"]]}`,
+	})
+
+	vr := &google.ValueRange{Values: [][]string{{"name"}}}
+	if err := sc.VerifySheetValueRange(vr); err != nil {
+		t.Fatalf("VerifySheetValueRange() error = %v", err)
+	}
+	if vr.Range != "A:F" {
+		t.Errorf("VerifySheetValueRange() Range = %q, want %q", vr.Range, "A:F")
+	}
+
+	if got := sc.GenerateValueRange([]any{sheetRow{Name: "Anthony"}}, "Logs", nil).Range; got != "Logs!A:F" {
+		t.Errorf("GenerateValueRange() Range = %q, want %q", got, "Logs!A:F")
+	}
+
+	if _, err := sc.ReadSpreadsheetValues("sheet-id", ""); err != nil {
+		t.Fatalf("ReadSpreadsheetValues() error = %v", err)
+	}
+	if len(mock.calls) != 1 || mock.calls[0].URL != google.Sheets+"/sheet-id/values/Sheet1!A:F" {
+		t.Errorf("ReadSpreadsheetValues() calls = %+v, want default range Sheet1!A:F", mock.calls)
+	}
+
+	var plan google.SavePlan
+	if err := sc.SaveToSheet([][]string{{"name"}, {"Anthony"}}, "sheet-id", "", nil, google.WithDryRun(&plan)); err != nil {
+		t.Fatalf("SaveToSheet() error = %v", err)
+	}
+	if plan.ValueRange.Range != "Sheet1!A:F" {
+		t.Errorf("SaveToSheet() Range = %q, want %q", plan.ValueRange.Range, "Sheet1!A:F")
+	}
+}
+
+func TestRGB(t *testing.T) {
+	const tolerance = 1e-9
+	want := google.Color{Alpha: 1.0, Red: 182.0 / 255.0, Green: 215.0 / 255.0, Blue: 168.0 / 255.0}
+
+	for name, got := range map[string]*google.Color{
+		"RGB":     google.RGB(182, 215, 168),
+		"Palette": google.ColorLightGreen.Color(),
+	} {
+		if math.Abs(got.Alpha-want.Alpha) > tolerance ||
+			math.Abs(got.Red-want.Red) > tolerance ||
+			math.Abs(got.Green-want.Green) > tolerance ||
+			math.Abs(got.Blue-want.Blue) > tolerance {
+			t.Errorf("%s = %+v, want %+v", name, *got, want)
+		}
+	}
+
+	if white := google.ColorWhite.Color(); *white != (google.Color{Alpha: 1, Red: 1, Green: 1, Blue: 1}) {
+		t.Errorf("ColorWhite = %+v, want opaque white", *white)
+	}
+}
+
+func TestAddSheetRetry(t *testing.T) {
+	var sheets []google.Sheet
+	adds := 0
+
+	sc := setupSheetsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/v4/spreadsheets/sheet-id":
+			json.NewEncoder(w).Encode(google.Spreadsheet{SpreadsheetID: "sheet-id", Sheets: sheets})
+		case r.Method == "POST" && r.URL.Path == "/v4/spreadsheets/sheet-id:batchUpdate":
+			var batch google.SheetBatchRequest
+			json.NewDecoder(r.Body).Decode(&batch)
+			props := batch.Requests[0].AddSheet.Properties
+			for _, sheet := range sheets {
+				if sheet.Properties.SheetID == props.SheetID {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error":{"code":400,"message":"A sheet with this ID already exists","status":"INVALID_ARGUMENT"}}`))
+					return
+				}
+			}
+			sheets = append(sheets, google.Sheet{Properties: props})
+			adds++
+			if adds == 1 {
+				// The sheet was added, but the response is lost and the client retries
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	props, err := sc.AddSheet("sheet-id", "Logs")
+	if err != nil {
+		t.Fatalf("AddSheet() error = %v", err)
+	}
+	if len(sheets) != 1 {
+		t.Fatalf("AddSheet() resulted in %d tabs, want 1", len(sheets))
+	}
+	if props.Title != "Logs" || props.SheetID != sheets[0].Properties.SheetID {
+		t.Errorf("AddSheet() = %+v, want the added sheet %+v", props, sheets[0].Properties)
+	}
+
+	// Adding the same title again is skipped entirely
+	if _, err := sc.AddSheet("sheet-id", "Logs"); err != nil {
+		t.Fatalf("AddSheet() error = %v", err)
+	}
+	if len(sheets) != 1 || adds != 1 {
+		t.Errorf("AddSheet() for an existing title resulted in %d tabs, want 1", len(sheets))
+	}
+}
+
+func TestBatchUpdateReplies(t *testing.T) {
+	sc, _ := setupMockSheetsClient(map[string]string{
+		"GET " + google.Sheets + "/sheet-id": `{"spreadsheetId":"sheet-id","sheets":[{"properties":{"sheetId":0,"title":"Sheet1"}}]}`,
+		"POST " + google.Sheets + "/sheet-id:batchUpdate": `{"spreadsheetId":"sheet-id","replies":[
+			{"addSheet":{"properties":{"sheetId":918273,"title":"Logs","index":1,"gridProperties":{"rowCount":1000,"columnCount":26}}}},
+			{},
+			{"addNamedRange":{"namedRange":{"namedRangeId":"nr-1","name":"totals"}}}
+		]}`,
+	})
+
+	res, err := sc.BatchUpdateSpreadsheet("sheet-id", &google.SheetBatchRequest{})
+	if err != nil {
+		t.Fatalf("BatchUpdateSpreadsheet() error = %v", err)
+	}
+	if res.SpreadsheetID != "sheet-id" || len(res.Replies) != 3 {
+		t.Fatalf("BatchUpdateSpreadsheet() = %+v, want 3 replies for sheet-id", res)
+	}
+	if add := res.Replies[0].AddSheet; add == nil || add.Properties.SheetID != 918273 {
+		t.Errorf("Replies[0].AddSheet = %+v, want sheetId 918273", add)
+	}
+	if res.Replies[1].AddSheet != nil {
+		t.Errorf("Replies[1] = %+v, want an empty reply", res.Replies[1])
+	}
+	if named := res.Replies[2].AddNamedRange; named == nil || named.NamedRange.NamedRangeID != "nr-1" {
+		t.Errorf("Replies[2].AddNamedRange = %+v, want namedRangeId nr-1", named)
+	}
+
+	// AddSheet returns the properties from the reply, as filled in by the API
+	props, err := sc.AddSheet("sheet-id", "Logs")
+	if err != nil {
+		t.Fatalf("AddSheet() error = %v", err)
+	}
+	if props.SheetID != 918273 || props.GridProperties == nil || props.GridProperties.RowCount != 1000 {
+		t.Errorf("AddSheet() = %+v, want the replied properties", props)
+	}
+}
+
+func TestA1R1C1RoundTrip(t *testing.T) {
+	tests := []struct {
+		a1   string
+		r1c1 string
+	}{
+		{"A1", "R1C1"},
+		{"B2", "R2C2"},
+		{"Z10", "R10C26"},
+		{"AA1", "R1C27"},
+		{"ZZ100", "R100C702"},
+		{"Sheet1!A1:D10", "Sheet1!R1C1:R10C4"},
+		{"'My Data'!C5:AB7", "'My Data'!R5C3:R7C28"},
+		{"C:D", "C3:C4"},
+		{"2:5", "R2:R5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a1, func(t *testing.T) {
+			r1c1, err := google.A1ToR1C1(tt.a1)
+			if err != nil {
+				t.Fatalf("A1ToR1C1(%q) error = %v", tt.a1, err)
+			}
+			if r1c1 != tt.r1c1 {
+				t.Errorf("A1ToR1C1(%q) = %q, want %q", tt.a1, r1c1, tt.r1c1)
+			}
+			a1, err := google.R1C1ToA1(r1c1, 0, 0)
+			if err != nil {
+				t.Fatalf("R1C1ToA1(%q) error = %v", r1c1, err)
+			}
+			if a1 != tt.a1 {
+				t.Errorf("R1C1ToA1(%q) = %q, want %q", r1c1, a1, tt.a1)
+			}
+		})
+	}
+
+	t.Run("Absolute Anchors", func(t *testing.T) {
+		if got, err := google.A1ToR1C1("$B$3:C$4"); err != nil || got != "R3C2:R4C3" {
+			t.Errorf("A1ToR1C1() = %q, %v, want R3C2:R4C3", got, err)
+		}
+	})
+
+	t.Run("Relative", func(t *testing.T) {
+		relative := []struct {
+			r1c1 string
+			want string
+		}{
+			{"RC", "E5"},
+			{"R[-1]C[2]", "G4"},
+			{"R[1]C2", "B6"},
+			{"R1C[-4]:R[5]C", "A1:E10"},
+			{"C[1]", "F:F"},
+			{"R", "5:5"},
+		}
+		for _, tt := range relative {
+			got, err := google.R1C1ToA1(tt.r1c1, 5, 5)
+			if err != nil {
+				t.Fatalf("R1C1ToA1(%q) error = %v", tt.r1c1, err)
+			}
+			if got != tt.want {
+				t.Errorf("R1C1ToA1(%q) from E5 = %q, want %q", tt.r1c1, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, ref := range []string{"", "R0C1", "R[-5]C1", "R1X", "RC"} {
+			if _, err := google.R1C1ToA1(ref, 0, 0); !errors.Is(err, google.ErrInvalidReference) {
+				t.Errorf("R1C1ToA1(%q) error = %v, want %v", ref, err, google.ErrInvalidReference)
+			}
+		}
+		for _, ref := range []string{"", "A0", "1A", "A1:B2:C3"} {
+			if _, err := google.A1ToR1C1(ref); !errors.Is(err, google.ErrInvalidReference) {
+				t.Errorf("A1ToR1C1(%q) error = %v, want %v", ref, err, google.ErrInvalidReference)
+			}
+		}
+	})
+
+	t.Run("Read And Write Options", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			"GET " + google.Sheets + "/sheet-id/values/Logs!A1:B2": `{"range":"Logs!A1:B2","values":[["a","b"]]}`,
+			"PUT " + google.Sheets + "/sheet-id/values/Logs!C3":    `{}`,
+		})
+		if _, err := sc.ReadSpreadsheetValues("sheet-id", "Logs!R1C1:R2C2", google.WithR1C1Read()); err != nil {
+			t.Fatalf("ReadSpreadsheetValues() error = %v", err)
+		}
+		vr := &google.ValueRange{Range: "Logs!R3C3", Values: [][]string{{"x"}}}
+		if err := sc.UpdateSpreadsheet("sheet-id", vr, google.WithR1C1Write()); err != nil {
+			t.Fatalf("UpdateSpreadsheet() error = %v", err)
+		}
+		if vr.Range != "Logs!R3C3" {
+			t.Errorf("UpdateSpreadsheet() changed the caller's range to %q", vr.Range)
+		}
+		if written := decodeWrittenValues(t, mock.calls[1].Data); written.Range != "Logs!C3" {
+			t.Errorf("Written range = %q, want %q", written.Range, "Logs!C3")
+		}
+	})
+}
+
+func TestListSheets(t *testing.T) {
+	sc, _ := setupMockSheetsClient(map[string]string{
+		"GET " + google.Sheets + "/sheet-id": `{"spreadsheetId":"sheet-id","sheets":[
+			{"properties":{"title":"Sheet1"}},
+			{"properties":{"sheetId":123,"title":"Users"}},
+			{"properties":{"sheetId":456,"title":"Assets"}},
+			{"properties":{"sheetId":789,"title":"Users"}}
+		]}`,
+	})
+
+	got, err := sc.ListSheets("sheet-id")
+	if err != nil {
+		t.Fatalf("ListSheets() error = %v", err)
+	}
+	want := map[string]int64{"Sheet1": 0, "Users": 123, "Assets": 456}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSheets() = %v, want %v", got, want)
+	}
+}
+
+// cancellingDoer cancels its context after the first request is served
+type cancellingDoer struct {
+	*mockDoer
+	cancel context.CancelFunc
+}
+
+func (d *cancellingDoer) DoRequest(ctx context.Context, method string, url string, query interface{}, data interface{}) (*http.Response, []byte, error) {
+	defer d.cancel()
+	return d.mockDoer.DoRequest(ctx, method, url, query, data)
+}
+
+func TestSaveToSheetContextCancel(t *testing.T) {
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + google.Sheets + "/sheet-id":                    `{"spreadsheetId":"sheet-id","sheets":[{"properties":{"sheetId":0,"title":"Sheet1"}}]}`,
+		"PUT " + google.Sheets + "/sheet-id/values/Sheet1!A:ZZ": `{}`,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc.Doer = &cancellingDoer{mockDoer: mock, cancel: cancel}
+
+	rows := []sheetRow{{Name: "Anthony", Age: 30}}
+	err := sc.SaveToSheetContext(ctx, rows, "sheet-id", "", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("SaveToSheetContext() error = %v, want %v", err, context.Canceled)
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("Expected 1 request before cancellation, got %d", len(mock.calls))
+	}
+}
+
+func TestMergeValueRanges(t *testing.T) {
+	header := &google.ValueRange{Range: "Users!A:ZZ", Values: [][]string{{"name", "age"}}}
+	chunk1 := &google.ValueRange{Range: "Users!A:ZZ", MajorDimension: "ROWS", Values: [][]string{{"Anthony", "30"}}}
+	chunk2 := &google.ValueRange{Range: "Users!A3:B3", MajorDimension: "ROWS", Values: [][]string{{"Sarah", "32"}}}
+
+	t.Run("Compatible", func(t *testing.T) {
+		got, err := google.MergeValueRanges(header, nil, chunk1)
+		if err != nil {
+			t.Fatalf("MergeValueRanges() error = %v", err)
+		}
+		want := &google.ValueRange{
+			Range:          "Users!A:ZZ",
+			MajorDimension: "ROWS",
+			Values:         [][]string{{"name", "age"}, {"Anthony", "30"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("MergeValueRanges() = %+v, want %+v", got, want)
+		}
+
+		got, err = google.MergeValueRanges(header, chunk1, chunk2)
+		if err != nil {
+			t.Fatalf("MergeValueRanges() error = %v", err)
+		}
+		if got.Range != "Users" || len(got.Values) != 3 {
+			t.Errorf("MergeValueRanges() = %+v, want 3 rows in range Users", got)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		vrs     []*google.ValueRange
+		wantRow int
+	}{
+		{
+			name:    "Different Sheet",
+			vrs:     []*google.ValueRange{header, {Range: "Assets!A:ZZ", Values: [][]string{{"x", "y"}}}},
+			wantRow: -1,
+		},
+		{
+			name:    "Different Dimension",
+			vrs:     []*google.ValueRange{header, {Range: "Users!A:ZZ", MajorDimension: "COLUMNS", Values: [][]string{{"x", "y"}}}},
+			wantRow: -1,
+		},
+		{
+			name:    "Column Count",
+			vrs:     []*google.ValueRange{header, chunk1, {Range: "Users!A:ZZ", Values: [][]string{{"Sarah", "32", "extra"}}}},
+			wantRow: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := google.MergeValueRanges(tt.vrs...)
+			var vrErr *google.ValueRangeError
+			if !errors.As(err, &vrErr) {
+				t.Fatalf("Expected error of type *google.ValueRangeError, got %v", err)
+			}
+			if vrErr.Row != tt.wantRow {
+				t.Errorf("ValueRangeError.Row = %d, want %d", vrErr.Row, tt.wantRow)
+			}
+		})
+	}
+
+	t.Run("Ragged BatchGet", func(t *testing.T) {
+		// batchGet drops trailing empty cells, so rows come back shorter than the header
+		sc, _ := setupMockSheetsClient(map[string]string{
+			"GET " + google.Sheets + "/sheet-id/values:batchGet": `{"spreadsheetId":"sheet-id","valueRanges":[
+				{"range":"Users!A1:C2","majorDimension":"ROWS","values":[["name","email","team"],["Anthony","anthony@gemini.com"]]},
+				{"range":"Users!A3:C4","majorDimension":"ROWS","values":[["Sarah"],["Dardano","dardano@gemini.com","it"]]}
+			]}`,
+		})
+		vrs, err := sc.BatchGetValues("sheet-id", []string{"Users!A1:C2", "Users!A3:C4"})
+		if err != nil {
+			t.Fatalf("BatchGetValues() error = %v", err)
+		}
+
+		got, err := google.MergeValueRanges(vrs...)
+		if err != nil {
+			t.Fatalf("MergeValueRanges() error = %v", err)
+		}
+		want := [][]string{
+			{"name", "email", "team"},
+			{"Anthony", "anthony@gemini.com", ""},
+			{"Sarah", "", ""},
+			{"Dardano", "dardano@gemini.com", "it"},
+		}
+		if !reflect.DeepEqual(got.Values, want) {
+			t.Errorf("MergeValueRanges() = %q, want %q", got.Values, want)
+		}
+		if len(vrs[1].Values[0]) != 1 {
+			t.Errorf("MergeValueRanges() padded the input row in place: %q", vrs[1].Values[0])
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		if _, err := google.MergeValueRanges(nil); !errors.Is(err, google.ErrNoData) {
+			t.Errorf("MergeValueRanges() error = %v, want %v", err, google.ErrNoData)
+		}
+	})
+}
+
+func TestGenerateValueRangePointers(t *testing.T) {
+	sc, _ := setupMockSheetsClient(nil)
+
+	users := []*sheetRow{{Name: "Anthony", Age: 30}, nil, {Name: "Sarah", Age: 32}}
+	data := make([]any, len(users))
+	for i := range users {
+		data[i] = users[i]
+	}
+
+	vr := sc.GenerateValueRange(data, "Users", nil)
+	want := [][]string{
+		{"name", "age"},
+		{"Anthony", "30"},
+		{"Sarah", "32"},
+	}
+	if !reflect.DeepEqual(vr.Values, want) {
+		t.Errorf("GenerateValueRange() = %q, want %q (nil rows skipped)", vr.Values, want)
+	}
+}
+
+func TestGenerateValueRangeHeaders(t *testing.T) {
+	sc, _ := setupMockSheetsClient(nil)
+
+	data := []any{
+		sheetRow{Name: "Anthony", Age: 30},
+		&sheetRow{Name: "Sarah", Age: 32},
+	}
+	headers := &[]string{"age", "email", "name"}
+
+	vr := sc.GenerateValueRange(data, "Users", headers)
+	want := [][]string{
+		{"age", "email", "name"},
+		{"30", "", "Anthony"},
+		{"32", "", "Sarah"},
+	}
+	if !reflect.DeepEqual(vr.Values, want) {
+		t.Errorf("GenerateValueRange() = %q, want %q", vr.Values, want)
+	}
+}
+
+func TestGenerateValueRangeWithoutHeaderRow(t *testing.T) {
+	sc, _ := setupMockSheetsClient(nil)
+
+	data := []any{
+		sheetRow{Name: "Anthony", Age: 30},
+		&sheetRow{Name: "Sarah", Age: 32},
+	}
+
+	vr := sc.GenerateValueRange(data, "Users", nil, google.WithoutHeaderRow())
+	want := [][]string{
+		{"Anthony", "30"},
+		{"Sarah", "32"},
+	}
+	if !reflect.DeepEqual(vr.Values, want) {
+		t.Errorf("GenerateValueRange() = %q, want %q", vr.Values, want)
+	}
+
+	// Provided headers still order the columns
+	vr = sc.GenerateValueRange(data, "Users", &[]string{"age", "name"}, google.WithoutHeaderRow())
+	want = [][]string{
+		{"30", "Anthony"},
+		{"32", "Sarah"},
+	}
+	if !reflect.DeepEqual(vr.Values, want) {
+		t.Errorf("GenerateValueRange() with headers = %q, want %q", vr.Values, want)
+	}
+
+	// Cells keep their native types without the header row
+	url := fmt.Sprintf(google.SheetValuesAppend, "sheet-id", vr.Range)
+	sc, mock := setupMockSheetsClient(map[string]string{"POST " + url: `{}`})
+	if err := sc.AppendSpreadsheet("sheet-id", vr); err != nil {
+		t.Fatalf("AppendSpreadsheet() error = %v", err)
+	}
+	if got := decodeWrittenValues(t, mock.calls[0].Data).Values; !reflect.DeepEqual(got, [][]interface{}{{float64(30), "Anthony"}, {float64(32), "Sarah"}}) {
+		t.Errorf("Payload = %v, want typed data rows", got)
+	}
+}
+
+func TestGenerateValueRangeTypes(t *testing.T) {
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"PUT " + google.Sheets + "/sheet-id/values/Users!A:ZZ": `{}`,
+	})
+
+	type account struct {
+		Name   string  `json:"name"`
+		Age    int     `json:"age"`
+		Active bool    `json:"active"`
+		Score  float64 `json:"score"`
+		Zip    string  `json:"zip"`
+		ID     int64   `json:"id"`
+		Serial uint64  `json:"serial"`
+		Limit  int64   `json:"limit"`
+	}
+	data := []any{account{
+		Name: "Anthony", Age: 30, Active: true, Score: 9.5, Zip: "02134",
+		ID: 9007199254740993, Serial: 9007199254740993, Limit: -9007199254740992,
+	}}
+
+	vr := sc.GenerateValueRange(data, "Users", nil)
+	if err := sc.UpdateSpreadsheet("sheet-id", vr); err != nil {
+		t.Fatalf("UpdateSpreadsheet() error = %v", err)
+	}
+
+	body, err := json.Marshal(mock.calls[0].Data)
+	if err != nil {
+		t.Fatalf("Marshaling payload: %v", err)
+	}
+	// Integers beyond 2^53 would lose precision as JSON numbers, so they are sent as strings
+	want := `"values":[["name","age","active","score","zip","id","serial","limit"],` +
+		`["Anthony",30,true,9.5,"02134","9007199254740993","9007199254740993",-9007199254740992]]`
+	if !bytes.Contains(body, []byte(want)) {
+		t.Errorf("Payload = %s, want it to contain %s", body, want)
+	}
+
+	// The ValueRange itself still holds the flattened strings
+	if got := vr.Values[1][1]; got != "30" {
+		t.Errorf("Values[1][1] = %q, want %q", got, "30")
+	}
+}
+
+func TestCreateSpreadsheetWithData(t *testing.T) {
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"POST " + google.Sheets: `{"spreadsheetId":"new-id","sheets":[
+			{"properties":{"sheetId":11,"title":"Assets","index":0}},
+			{"properties":{"sheetId":12,"title":"Empty","index":1}},
+			{"properties":{"sheetId":13,"title":"Users","index":2}}
+		]}`,
+		"PUT " + google.Sheets + "/new-id/values/Assets!A:ZZ": `{}`,
+		"PUT " + google.Sheets + "/new-id/values/Users!A:ZZ":  `{}`,
+		"POST " + google.Sheets + "/new-id:batchUpdate":       `{}`,
+	})
+
+	type asset struct {
+		Serial string `json:"serial"`
+	}
+	spreadsheet, err := sc.CreateSpreadsheetWithData("Inventory", map[string]interface{}{
+		"Users":  []sheetRow{{Name: "Anthony", Age: 30}},
+		"Assets": []*asset{{Serial: "C02"}},
+		"Empty":  []sheetRow{},
+	})
+	if err != nil {
+		t.Fatalf("CreateSpreadsheetWithData() error = %v", err)
+	}
+	if spreadsheet.SpreadsheetID != "new-id" {
+		t.Errorf("SpreadsheetID = %q, want %q", spreadsheet.SpreadsheetID, "new-id")
+	}
+
+	if len(mock.calls) != 5 {
+		t.Fatalf("Expected 5 requests (create, 2 writes, 2 formats), got %d", len(mock.calls))
+	}
+	created := mock.calls[0].Data.(*google.Spreadsheet)
+	if created.Properties.Title != "Inventory" {
+		t.Errorf("Created title = %q, want %q", created.Properties.Title, "Inventory")
+	}
+	var tabs []string
+	for _, sheet := range created.Sheets {
+		tabs = append(tabs, sheet.Properties.Title)
+	}
+	if !reflect.DeepEqual(tabs, []string{"Assets", "Empty", "Users"}) {
+		t.Errorf("Created tabs = %v, want sorted by title", tabs)
+	}
+
+	writes := map[string][][]interface{}{}
+	var formatted []int
+	for _, call := range mock.calls[1:] {
+		switch data := call.Data.(type) {
+		case *google.SheetBatchRequest:
+			repeat := data.Requests[0].RepeatCell
+			formatted = append(formatted, repeat.Range.SheetID)
+		default:
+			written := decodeWrittenValues(t, data)
+			writes[written.Range] = written.Values
+		}
+	}
+	wantWrites := map[string][][]interface{}{
+		"Assets!A:ZZ": {{"serial"}, {"C02"}},
+		"Users!A:ZZ":  {{"name", "age"}, {"Anthony", 30.0}},
+	}
+	if !reflect.DeepEqual(writes, wantWrites) {
+		t.Errorf("Writes = %v, want %v", writes, wantWrites)
+	}
+	if !reflect.DeepEqual(formatted, []int{11, 13}) {
+		t.Errorf("Formatted sheet IDs = %v, want [11 13]", formatted)
+	}
+}
+
+func TestFormatHeaderNotes(t *testing.T) {
+	url := google.Sheets + "/sheet-id:batchUpdate"
+	headerURL := google.Sheets + "/sheet-id/values/Logs!1:1"
+	sheet := &google.Sheet{Properties: &google.SheetProperties{SheetID: 7, Title: "Logs"}}
+
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + headerURL: `{"range":"Logs!A1:C1","values":[["name","age","email"]]}`,
+		"POST " + url:      `{}`,
+	})
+
+	hf := google.DefaultHeaderFormat()
+	hf.Notes = map[string]string{
+		"email":   "Primary work email",
+		"name":    "Full legal name",
+		"missing": "Not a header",
+	}
+	if err := sc.FormatHeaderAndAutoSize("sheet-id", sheet, 3, 3, hf); err != nil {
+		t.Fatalf("FormatHeaderAndAutoSize() error = %v", err)
+	}
+	if len(mock.calls) != 2 {
+		t.Fatalf("FormatHeaderAndAutoSize() issued %d requests, want 2", len(mock.calls))
+	}
+
+	batch := mock.calls[1].Data.(*google.SheetBatchRequest)
+	notes := map[int]string{}
+	for _, req := range batch.Requests {
+		if req.UpdateCells == nil {
+			continue
+		}
+		if req.UpdateCells.Fields != "note" || req.UpdateCells.Range.SheetID != 7 || req.UpdateCells.Range.EndRowIndex != 1 {
+			t.Errorf("UpdateCells = %+v, want a note on header row of sheet 7", req.UpdateCells)
+		}
+		notes[req.UpdateCells.Range.StartColumnIndex] = req.UpdateCells.Rows[0].Values[0].Note
+	}
+	want := map[int]string{0: "Full legal name", 2: "Primary work email"}
+	if !reflect.DeepEqual(notes, want) {
+		t.Errorf("Header notes by column = %v, want %v", notes, want)
+	}
+
+	t.Run("SaveToSheet", func(t *testing.T) {
+		sc, _ := setupMockSheetsClient(nil)
+		var plan google.SavePlan
+		hf := google.DefaultHeaderFormat()
+		hf.Notes = map[string]string{"age": "Age in years"}
+
+		err := sc.SaveToSheet([]sheetRow{{Name: "Anthony", Age: 30}}, "sheet-id", "Logs", nil, google.WithDryRun(&plan), google.WithHeaderFormat(hf))
+		if err != nil {
+			t.Fatalf("SaveToSheet() error = %v", err)
+		}
+		found := false
+		for _, req := range plan.Format.Requests {
+			if req.UpdateCells != nil && req.UpdateCells.Range.StartColumnIndex == 1 && req.UpdateCells.Rows[0].Values[0].Note == "Age in years" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Planned format requests do not include the age note")
+		}
+	})
+}
+
+func TestSaveToSheetCreated(t *testing.T) {
+	rows := []sheetRow{{Name: "Anthony", Age: 30}}
+
+	t.Run("New Spreadsheet", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			"POST " + google.Sheets: `{"spreadsheetId":"new-id","spreadsheetUrl":"https://docs.google.com/spreadsheets/d/new-id/edit",
+				"sheets":[{"properties":{"sheetId":0,"title":"Users"}}]}`,
+			"PUT " + google.Sheets + "/new-id/values/Users!A:ZZ": `{}`,
+			"POST " + google.Sheets + "/new-id:batchUpdate":      `{}`,
+		})
+
+		var saved google.Spreadsheet
+		if err := sc.SaveToSheet(rows, "", "Users", nil, google.WithSavedSpreadsheet(&saved)); err != nil {
+			t.Fatalf("SaveToSheet() error = %v", err)
+		}
+		if saved.SpreadsheetID != "new-id" || saved.SpreadsheetURL != "https://docs.google.com/spreadsheets/d/new-id/edit" {
+			t.Errorf("Saved spreadsheet = %q %q, want the created ID and URL", saved.SpreadsheetID, saved.SpreadsheetURL)
+		}
+		if call := mock.calls[0]; call.Method != "POST" || call.URL != google.Sheets {
+			t.Errorf("First request = %s %s, want POST %s", call.Method, call.URL, google.Sheets)
+		}
+	})
+
+	t.Run("Failed Write", func(t *testing.T) {
+		// The created spreadsheet is reported even though writing to it fails
+		sc, _ := setupMockSheetsClient(map[string]string{
+			"POST " + google.Sheets: `{"spreadsheetId":"new-id","spreadsheetUrl":"https://docs.google.com/spreadsheets/d/new-id/edit"}`,
+		})
+
+		var saved google.Spreadsheet
+		if err := sc.SaveToSheet(rows, "", "Users", nil, google.WithSavedSpreadsheet(&saved)); err == nil {
+			t.Fatal("SaveToSheet() error = nil, want the failed write")
+		}
+		if saved.SpreadsheetID != "new-id" {
+			t.Errorf("Saved spreadsheet ID = %q, want new-id", saved.SpreadsheetID)
+		}
+	})
+
+	t.Run("Existing Spreadsheet", func(t *testing.T) {
+		sc, _ := setupMockSheetsClient(map[string]string{
+			"GET " + google.Sheets + "/sheet-id": `{"spreadsheetId":"sheet-id","spreadsheetUrl":"https://docs.google.com/spreadsheets/d/sheet-id/edit",
+				"sheets":[{"properties":{"sheetId":5,"title":"Users"}}]}`,
+			"PUT " + google.Sheets + "/sheet-id/values/Users!A:ZZ": `{}`,
+			"POST " + google.Sheets + "/sheet-id:batchUpdate":      `{}`,
+		})
+
+		var saved google.Spreadsheet
+		if err := sc.SaveToSheet(rows, "sheet-id", "Users", nil, google.WithSavedSpreadsheet(&saved)); err != nil {
+			t.Fatalf("SaveToSheet() error = %v", err)
+		}
+		if saved.SpreadsheetURL != "https://docs.google.com/spreadsheets/d/sheet-id/edit" {
+			t.Errorf("Saved spreadsheet URL = %q", saved.SpreadsheetURL)
+		}
+	})
+}
+
+func TestSaveToSheetNumberFormats(t *testing.T) {
+	type invoice struct {
+		Customer string  `json:"customer"`
+		Amount   float64 `json:"amount" rego:"format=currency"`
+		Due      string  `json:"due" rego:"format=date"`
+	}
+	rows := []invoice{{Customer: "Anthony", Amount: 12.5, Due: "2026-10-16"}, {Customer: "Sarah", Amount: 3, Due: "2026-11-01"}}
+
+	// wantFormats checks that only the tagged columns get a number format, over the data rows beneath the header
+	wantFormats := func(t *testing.T, req *google.SheetBatchRequest, sheetID int) {
+		t.Helper()
+		if req == nil || len(req.Requests) != 2 {
+			t.Fatalf("Number format requests = %+v, want 2", req)
+		}
+		for i, want := range []struct {
+			column int
+			typ    string
+		}{{1, "CURRENCY"}, {2, "DATE"}} {
+			rc := req.Requests[i].RepeatCell
+			if rc == nil {
+				t.Fatalf("Request %d = %+v, want a repeatCell", i, req.Requests[i])
+			}
+			r := rc.Range
+			if r.SheetID != sheetID || r.StartColumnIndex != want.column || r.EndColumnIndex != want.column+1 || r.StartRowIndex != 1 || r.EndRowIndex != 3 {
+				t.Errorf("Request %d range = %+v, want column %d, rows [1, 3)", i, r, want.column)
+			}
+			if nf := rc.Cell.UserEnteredFormat.NumberFormat; nf == nil || nf.Type != want.typ {
+				t.Errorf("Request %d numberFormat = %+v, want %s", i, nf, want.typ)
+			}
+			if rc.Fields != "userEnteredFormat(numberFormat)" {
+				t.Errorf("Request %d fields = %q, want userEnteredFormat(numberFormat)", i, rc.Fields)
+			}
+		}
+	}
+
+	t.Run("Save", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			"GET " + google.Sheets + "/sheet-id":                      `{"spreadsheetId":"sheet-id","sheets":[{"properties":{"sheetId":5,"title":"Invoices"}}]}`,
+			"PUT " + google.Sheets + "/sheet-id/values/Invoices!A:ZZ": `{}`,
+			"POST " + google.Sheets + "/sheet-id:batchUpdate":         `{}`,
+		})
+
+		if err := sc.SaveToSheet(rows, "sheet-id", "Invoices", nil); err != nil {
+			t.Fatalf("SaveToSheet() error = %v", err)
+		}
+
+		var batches []*google.SheetBatchRequest
+		for _, call := range mock.calls {
+			if call.Method == "POST" {
+				batches = append(batches, call.Data.(*google.SheetBatchRequest))
+			}
+		}
+		if len(batches) != 2 {
+			t.Fatalf("Expected a header format and a number format batchUpdate, got %d", len(batches))
+		}
+		wantFormats(t, batches[1], 5)
+	})
+
+	t.Run("Dry Run", func(t *testing.T) {
+		sc, _ := setupMockSheetsClient(nil)
+		var plan google.SavePlan
+		if err := sc.SaveToSheet(rows, "", "Invoices", nil, google.WithDryRun(&plan)); err != nil {
+			t.Fatalf("SaveToSheet() error = %v", err)
+		}
+		wantFormats(t, plan.NumberFormat, 0)
+	})
+
+	t.Run("Untagged", func(t *testing.T) {
+		sc, _ := setupMockSheetsClient(nil)
+		var plan google.SavePlan
+		if err := sc.SaveToSheet([]sheetRow{{Name: "Anthony", Age: 30}}, "", "Users", nil, google.WithDryRun(&plan)); err != nil {
+			t.Fatalf("SaveToSheet() error = %v", err)
+		}
+		if plan.NumberFormat != nil {
+			t.Errorf("NumberFormat = %+v, want nil without format tags", plan.NumberFormat)
+		}
+	})
+}
+
+func TestSaveToSheetMetadata(t *testing.T) {
+	meta := map[string]string{"source": "okta", "exported": "2026-10-16T00:00:00Z", "rows": "2"}
+	rows := []sheetRow{{Name: "Anthony", Age: 30}, {Name: "Sarah", Age: 32}}
+	wantData := [][]interface{}{{"name", "age"}, {"Anthony", 30.0}, {"Sarah", 32.0}}
+
+	t.Run("Above Header", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			"GET " + google.Sheets + "/sheet-id":                    `{"spreadsheetId":"sheet-id","sheets":[{"properties":{"sheetId":5,"title":"Users"}}]}`,
+			"PUT " + google.Sheets + "/sheet-id/values/Users!A2:ZZ": `{}`,
+			"PUT " + google.Sheets + "/sheet-id/values/Users!1:1":   `{}`,
+			"POST " + google.Sheets + "/sheet-id:batchUpdate":       `{}`,
+		})
+
+		if err := sc.SaveToSheet(rows, "sheet-id", "Users", nil, google.WithMetadata(meta)); err != nil {
+			t.Fatalf("SaveToSheet() error = %v", err)
+		}
+
+		writes := map[string][][]interface{}{}
+		var format *google.SheetBatchRequest
+		for _, call := range mock.calls {
+			switch {
+			case call.Method == "PUT":
+				written := decodeWrittenValues(t, call.Data)
+				writes[written.Range] = written.Values
+			case call.Method == "POST":
+				format = call.Data.(*google.SheetBatchRequest)
+			}
+		}
+
+		wantMeta := [][]interface{}{{"exported", "2026-10-16T00:00:00Z", "rows", "2", "source", "okta"}}
+		if got := writes["Users!1:1"]; !reflect.DeepEqual(got, wantMeta) {
+			t.Errorf("Metadata row = %v, want %v", got, wantMeta)
+		}
+		if got := writes["Users!A2:ZZ"]; !reflect.DeepEqual(got, wantData) {
+			t.Errorf("Data block = %v, want %v", got, wantData)
+		}
+
+		if format == nil {
+			t.Fatal("SaveToSheet() did not format the header")
+		}
+		if r := format.Requests[0].RepeatCell.Range; r.StartRowIndex != 1 || r.EndRowIndex != 2 {
+			t.Errorf("Header format rows = [%d, %d), want [1, 2)", r.StartRowIndex, r.EndRowIndex)
+		}
+		if r := format.Requests[1].SetBasicFilter.Filter.Range; r.StartRowIndex != 1 || r.EndRowIndex != 4 {
+			t.Errorf("Filter rows = [%d, %d), want [1, 4)", r.StartRowIndex, r.EndRowIndex)
+		}
+	})
+
+	t.Run("Separate Tab", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{
+			"GET " + google.Sheets + "/sheet-id":                   `{"spreadsheetId":"sheet-id","sheets":[{"properties":{"sheetId":5,"title":"Users"}}]}`,
+			"PUT " + google.Sheets + "/sheet-id/values/Users!A:ZZ": `{}`,
+			"PUT " + google.Sheets + "/sheet-id/values/About!A:ZZ": `{}`,
+			"POST " + google.Sheets + "/sheet-id:batchUpdate":      `{}`,
+		})
+
+		if err := sc.SaveToSheet(rows, "sheet-id", "Users", nil, google.WithMetadataTab("About", meta)); err != nil {
+			t.Fatalf("SaveToSheet() error = %v", err)
+		}
+
+		writes := map[string][][]interface{}{}
+		var addedTab string
+		for _, call := range mock.calls {
+			switch data := call.Data.(type) {
+			case *google.SheetBatchRequest:
+				if add := data.Requests[0].AddSheet; add != nil {
+					addedTab = add.Properties.Title
+				}
+			case nil:
+			default:
+				written := decodeWrittenValues(t, data)
+				writes[written.Range] = written.Values
+			}
+		}
+
+		if addedTab != "About" {
+			t.Errorf("Added tab = %q, want %q", addedTab, "About")
+		}
+		wantMeta := [][]interface{}{{"exported", "2026-10-16T00:00:00Z"}, {"rows", "2"}, {"source", "okta"}}
+		if got := writes["About!A:ZZ"]; !reflect.DeepEqual(got, wantMeta) {
+			t.Errorf("Metadata tab = %v, want %v", got, wantMeta)
+		}
+		if got := writes["Users!A:ZZ"]; !reflect.DeepEqual(got, wantData) {
+			t.Errorf("Data block = %v, want %v", got, wantData)
+		}
+	})
+}
+
+func TestSaveToSheetHyperlinks(t *testing.T) {
+	type profile struct {
+		Name string `json:"name"`
+		URL  string `json:"url" rego:"link"`
+		Docs string `json:"docs"`
+	}
+	rows := []profile{
+		{Name: "=1+1", URL: `https://example.com/?q="rego"`, Docs: "https://example.com/docs"},
+		{Name: "Sarah"},
+	}
+
+	url := google.Sheets + "/sheet-id/values/Sheet1!A:ZZ"
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + google.Sheets + "/sheet-id":              `{"spreadsheetId":"sheet-id","sheets":[{"properties":{"title":"Sheet1"}}]}`,
+		"PUT " + url:                                      `{}`,
+		"POST " + google.Sheets + "/sheet-id:batchUpdate": `{}`,
+	})
+
+	if err := sc.SaveToSheet(rows, "sheet-id", "", nil, google.WithLinkColumns("docs")); err != nil {
+		t.Fatalf("SaveToSheet() error = %v", err)
+	}
+
+	var write *mockCall
+	for i := range mock.calls {
+		if mock.calls[i].Method == "PUT" {
+			write = &mock.calls[i]
+		}
+	}
+	if write == nil {
+		t.Fatal("SaveToSheet() did not write any values")
+	}
+	if q, ok := write.Query.(google.SheetValueQuery); !ok || q.ValueInputOption != "USER_ENTERED" {
+		t.Errorf("Query = %+v, want ValueInputOption USER_ENTERED", write.Query)
+	}
+	want := [][]string{
+		{"'name", "'url", "'docs"},
+		{"'=1+1", `=HYPERLINK("https://example.com/?q=""rego""")`, `=HYPERLINK("https://example.com/docs")`},
+		{"'Sarah", "", ""},
+	}
+	if got := write.Data.(*google.ValueRange).Values; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values = %q, want %q", got, want)
+	}
+}
+
+func TestSaveToSheetHyperlinksTyped(t *testing.T) {
+	type profile struct {
+		Name   string `json:"name"`
+		URL    string `json:"url" rego:"link"`
+		Age    int    `json:"age"`
+		Active bool   `json:"active"`
+	}
+	rows := []profile{{Name: "Anthony", URL: "https://example.com/anthony", Age: 35, Active: true}}
+
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + google.Sheets + "/sheet-id":                    `{"spreadsheetId":"sheet-id","sheets":[{"properties":{"title":"Sheet1"}}]}`,
+		"PUT " + google.Sheets + "/sheet-id/values/Sheet1!A:ZZ": `{}`,
+		"POST " + google.Sheets + "/sheet-id:batchUpdate":       `{}`,
+	})
+
+	if err := sc.SaveToSheet(rows, "sheet-id", "", nil); err != nil {
+		t.Fatalf("SaveToSheet() error = %v", err)
+	}
+
+	var write *mockCall
+	for i := range mock.calls {
+		if mock.calls[i].Method == "PUT" {
+			write = &mock.calls[i]
+		}
+	}
+	if write == nil {
+		t.Fatal("SaveToSheet() did not write any values")
+	}
+	want := [][]interface{}{
+		{"'name", "'url", "'age", "'active"},
+		{"'Anthony", `=HYPERLINK("https://example.com/anthony")`, float64(35), true},
+	}
+	if got := decodeWrittenValues(t, write.Data).Values; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values = %#v, want %#v", got, want)
+	}
+}
+
+func TestBatchUpdateBuilder(t *testing.T) {
+	r := &google.GridRange{EndRowIndex: 1, EndColumnIndex: 3}
+
+	t.Run("RepeatCellFormat", func(t *testing.T) {
+		format := &google.CellFormat{BackgroundColor: google.ColorYellow.Color(), WrapStrategy: "WRAP"}
+		req := google.NewBatchUpdate(5).RepeatCellFormat(r, format).Request().Requests[0].RepeatCell
+		if req.Fields != "userEnteredFormat(backgroundColor,wrapStrategy)" {
+			t.Errorf("Fields = %q, want %q", req.Fields, "userEnteredFormat(backgroundColor,wrapStrategy)")
+		}
+		if req.Range.SheetID != 5 || req.Cell.UserEnteredFormat != format {
+			t.Errorf("RepeatCell = %+v, want sheet 5 with the given format", req)
+		}
+		if r.SheetID != 0 {
+			t.Errorf("RepeatCellFormat() modified the caller's range: %+v", r)
+		}
+	})
+
+	t.Run("AutoResize", func(t *testing.T) {
+		req := google.NewBatchUpdate(5).AutoResize(&google.DimensionRange{Dimension: "COLUMNS", EndIndex: 3}).Request().Requests[0]
+		want := &google.DimensionRange{SheetID: 5, Dimension: "COLUMNS", EndIndex: 3}
+		if got := req.AutoResizeDimensions.(*google.AutoResizeDimensionsRequest).Dimensions.(*google.DimensionRange); !reflect.DeepEqual(got, want) {
+			t.Errorf("Dimensions = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("Freeze", func(t *testing.T) {
+		reqs := google.NewBatchUpdate(5).FreezeRows(1).FreezeColumns(2).Request().Requests
+		if len(reqs) != 2 {
+			t.Fatalf("Requests = %d, want 2", len(reqs))
+		}
+		rows, cols := reqs[0].UpdateSheetProperties, reqs[1].UpdateSheetProperties
+		if rows.Fields != "gridProperties.frozenRowCount" || rows.Properties.GridProperties.FrozenRowCount != 1 || rows.Properties.SheetID != 5 {
+			t.Errorf("FreezeRows() = %+v, want 1 frozen row on sheet 5", rows)
+		}
+		if cols.Fields != "gridProperties.frozenColumnCount" || cols.Properties.GridProperties.FrozenColumnCount != 2 {
+			t.Errorf("FreezeColumns() = %+v, want 2 frozen columns", cols)
+		}
+	})
+
+	t.Run("Note", func(t *testing.T) {
+		req := google.NewBatchUpdate(5).Note(0, 2, "Work email").Request().Requests[0].UpdateCells
+		want := &google.GridRange{SheetID: 5, StartRowIndex: 0, EndRowIndex: 1, StartColumnIndex: 2, EndColumnIndex: 3}
+		if req.Fields != "note" || !reflect.DeepEqual(req.Range, want) || req.Rows[0].Values[0].Note != "Work email" {
+			t.Errorf("Note() = %+v, want note on %+v", req, want)
+		}
+	})
+
+	t.Run("Execute", func(t *testing.T) {
+		url := google.Sheets + "/sheet-id:batchUpdate"
+		sc, mock := setupMockSheetsClient(map[string]string{"POST " + url: `{}`})
+
+		err := google.NewBatchUpdate(5).BasicFilter(r).FreezeRows(1).Execute(sc, "sheet-id")
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if len(mock.calls) != 1 || mock.calls[0].URL != url {
+			t.Fatalf("Execute() issued %v, want one POST to %s", mock.calls, url)
+		}
+		if got := len(mock.calls[0].Data.(*google.SheetBatchRequest).Requests); got != 2 {
+			t.Errorf("Execute() sent %d requests, want 2", got)
+		}
+	})
+}
+
+func TestGridRangeValidate(t *testing.T) {
+	grid := &google.GridProperties{RowCount: 100, ColumnCount: 26}
+
+	tests := []struct {
+		name    string
+		r       google.GridRange
+		grid    *google.GridProperties
+		wantErr bool
+	}{
+		{"Valid", google.GridRange{EndRowIndex: 1, EndColumnIndex: 3}, grid, false},
+		{"Zero Width", google.GridRange{EndRowIndex: 1, EndColumnIndex: 0}, nil, true},
+		{"Zero Height", google.GridRange{StartRowIndex: 2, EndRowIndex: 2, EndColumnIndex: 3}, nil, true},
+		{"Inverted Columns", google.GridRange{EndRowIndex: 1, StartColumnIndex: 4, EndColumnIndex: 2}, nil, true},
+		{"Negative Start", google.GridRange{StartRowIndex: -1, EndRowIndex: 1, EndColumnIndex: 1}, nil, true},
+		{"Beyond Sheet", google.GridRange{EndRowIndex: 1, EndColumnIndex: 27}, grid, true},
+		{"Unknown Sheet Size", google.GridRange{EndRowIndex: 1, EndColumnIndex: 27}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.r.Validate(tt.grid)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, google.ErrInvalidRange) {
+				t.Errorf("Validate() error = %v, want %v", err, google.ErrInvalidRange)
+			}
+		})
+	}
+
+	t.Run("Zero Column Header", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{"POST " + google.Sheets + "/sheet-id:batchUpdate": `{}`})
+		sheet := &google.Sheet{Properties: &google.SheetProperties{SheetID: 7, Title: "Logs"}}
+
+		err := sc.FormatHeaderAndAutoSize("sheet-id", sheet, 1, 0)
+		if !errors.Is(err, google.ErrInvalidRange) {
+			t.Errorf("FormatHeaderAndAutoSize() error = %v, want %v", err, google.ErrInvalidRange)
+		}
+		if len(mock.calls) != 0 {
+			t.Errorf("FormatHeaderAndAutoSize() issued %d requests for an invalid range, want 0", len(mock.calls))
+		}
+	})
+
+	t.Run("Within Grid", func(t *testing.T) {
+		b := google.NewBatchUpdate(7).WithinGrid(grid).
+			RepeatCellFormat(&google.GridRange{EndRowIndex: 1, EndColumnIndex: 26}, &google.CellFormat{})
+		if err := b.Err(); err != nil {
+			t.Fatalf("Err() = %v, want nil for a range inside the grid", err)
+		}
+
+		b.AutoResize(&google.DimensionRange{Dimension: "COLUMNS", EndIndex: 27})
+		if err := b.Err(); !errors.Is(err, google.ErrInvalidRange) {
+			t.Errorf("Err() = %v, want %v for columns beyond the grid", err, google.ErrInvalidRange)
+		}
+	})
+
+	t.Run("Header Beyond Sheet", func(t *testing.T) {
+		sc, mock := setupMockSheetsClient(map[string]string{"POST " + google.Sheets + "/sheet-id:batchUpdate": `{}`})
+		sheet := &google.Sheet{Properties: &google.SheetProperties{SheetID: 7, Title: "Logs", GridProperties: grid}}
+
+		err := sc.FormatHeaderAndAutoSize("sheet-id", sheet, 10, 30)
+		if !errors.Is(err, google.ErrInvalidRange) {
+			t.Errorf("FormatHeaderAndAutoSize() error = %v, want %v", err, google.ErrInvalidRange)
+		}
+		if len(mock.calls) != 0 {
+			t.Errorf("FormatHeaderAndAutoSize() issued %d requests beyond the sheet, want 0", len(mock.calls))
+		}
+	})
+}
+
+func TestReadSheetsAsStructs(t *testing.T) {
+	url := google.Sheets + "/sheet-id/values:batchGet"
+	sc, mock := setupMockSheetsClient(map[string]string{
+		"GET " + url: `{"spreadsheetId":"sheet-id","valueRanges":[
+			{"range":"2024!A1:B3","majorDimension":"ROWS","values":[["name","age"],["Anthony","30"],["Sarah"]]},
+			{"range":"2025!A1:B2","majorDimension":"ROWS"},
+			{"range":"2026!A1:B2","majorDimension":"ROWS","values":[["name","age"],["Dardano","31"]]}
+		]}`,
+	})
+
+	ranges := []string{"2024!A:B", "2025!A:B", "2026!A:B"}
+	got, err := sc.ReadSheetsAsStructs("sheet-id", ranges)
+	if err != nil {
+		t.Fatalf("ReadSheetsAsStructs() error = %v", err)
+	}
+	if q, ok := mock.calls[0].Query.(google.SheetValueQuery); !ok || !reflect.DeepEqual(q.Ranges, ranges) {
+		t.Errorf("Query = %+v, want Ranges %v", mock.calls[0].Query, ranges)
+	}
+
+	raw, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshalling structs: %v", err)
+	}
+	want := `[{"name":"Anthony","age":"30"},{"name":"Sarah","age":""},{"name":"Dardano","age":"31"}]`
+	if string(raw) != want {
+		t.Errorf("ReadSheetsAsStructs() = %s, want %s", raw, want)
+	}
+
+	t.Run("Header Mismatch", func(t *testing.T) {
+		sc, _ := setupMockSheetsClient(map[string]string{
+			"GET " + url: `{"valueRanges":[
+				{"range":"2024!A1:B2","values":[["name","age"],["Anthony","30"]]},
+				{"range":"2025!A1:B2","values":[["name","email"],["Sarah","sarah@gemini.com"]]}
+			]}`,
+		})
+		if _, err := sc.ReadSheetsAsStructs("sheet-id", []string{"2024!A:B", "2025!A:B"}); !errors.Is(err, google.ErrHeaderMismatch) {
+			t.Errorf("ReadSheetsAsStructs() error = %v, want %v", err, google.ErrHeaderMismatch)
+		}
+	})
+}
+
+func TestReadRangesConcurrently(t *testing.T) {
+	ranges := []string{"A!A:B", "B!A:B", "C!A:B", "D!A:B", "E!A:B"}
+
+	var (
+		mu   sync.Mutex
+		hits []string
+	)
+	sc := setupSheetsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		rangeNotation := strings.TrimPrefix(r.URL.Path, "/v4/spreadsheets/sheet-id/values/")
+		mu.Lock()
+		hits = append(hits, rangeNotation)
+		mu.Unlock()
+		if strings.HasPrefix(rangeNotation, "Missing") {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"code":400,"message":"Unable to parse range"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"range":%q,"values":[[%q]]}`, rangeNotation, rangeNotation)
+	})
+
+	// Open the limiter's window first, so every later request is counted against it
+	if _, err := sc.ReadSpreadsheetValues("sheet-id", "Warmup!A:B"); err != nil {
+		t.Fatalf("ReadSpreadsheetValues() error = %v", err)
+	}
+	before := sc.HTTP.RateLimiter.State().Requests
+
+	got, err := sc.ReadRangesConcurrently("sheet-id", ranges, 2)
+	if err != nil {
+		t.Fatalf("ReadRangesConcurrently() error = %v", err)
+	}
+	for i, vr := range got {
+		if vr.Range != ranges[i] || vr.Values[0][0] != ranges[i] {
+			t.Errorf("Result %d = %+v, want range %s", i, vr, ranges[i])
+		}
+	}
+	if len(hits) != len(ranges)+1 {
+		t.Errorf("Issued %d requests, want %d", len(hits)-1, len(ranges))
+	}
+	// Each read takes exactly one slot of the shared quota
+	if used := sc.HTTP.RateLimiter.State().Requests - before; used != len(ranges) {
+		t.Errorf("Rate limiter counted %d requests, want %d", used, len(ranges))
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		mu.Lock()
+		hits = nil
+		mu.Unlock()
+
+		_, err := sc.ReadRangesConcurrently("sheet-id", append([]string{"Missing!A:B"}, ranges...), 1)
+		if err == nil || !strings.Contains(err.Error(), "Missing!A:B") {
+			t.Fatalf("ReadRangesConcurrently() error = %v, want the missing range's error", err)
+		}
+		// No read is started after the first one fails
+		if len(hits) != 1 {
+			t.Errorf("Issued %d requests after a failure, want 1: %v", len(hits), hits)
+		}
+	})
+}